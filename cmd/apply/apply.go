@@ -0,0 +1,40 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/load"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+func NewCmd(config *config.Config, crd map[string]crd.CRD) *cobra.Command {
+	var from string
+	applyCmd := &cobra.Command{
+		Use:     "apply -f <path/to/manifest.yaml>/<manifest URL>",
+		Short:   "Reconcile running components with TriggerMesh manifest",
+		Example: "tmctl apply -f manifest.yaml",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return load.Apply(from, config, crd)
+		},
+	}
+	applyCmd.Flags().StringVarP(&from, "from", "f", "", "Apply manifest from")
+	cobra.CheckErr(applyCmd.MarkFlagRequired("from"))
+	return applyCmd
+}
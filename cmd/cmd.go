@@ -19,18 +19,26 @@ package cmd
 import (
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/cobra/doc"
 
+	"github.com/triggermesh/tmctl/cmd/apply"
 	"github.com/triggermesh/tmctl/cmd/brokers"
 	"github.com/triggermesh/tmctl/cmd/config"
+	"github.com/triggermesh/tmctl/cmd/crd"
 	"github.com/triggermesh/tmctl/cmd/create"
 	"github.com/triggermesh/tmctl/cmd/delete"
 	"github.com/triggermesh/tmctl/cmd/describe"
+	"github.com/triggermesh/tmctl/cmd/diff"
 	"github.com/triggermesh/tmctl/cmd/dump"
+	"github.com/triggermesh/tmctl/cmd/filter"
+	"github.com/triggermesh/tmctl/cmd/graph"
 	import_ "github.com/triggermesh/tmctl/cmd/import"
 	"github.com/triggermesh/tmctl/cmd/logs"
+	"github.com/triggermesh/tmctl/cmd/replay"
+	"github.com/triggermesh/tmctl/cmd/schema"
 	"github.com/triggermesh/tmctl/cmd/sendevent"
 	"github.com/triggermesh/tmctl/cmd/start"
 	"github.com/triggermesh/tmctl/cmd/stop"
@@ -41,7 +49,8 @@ import (
 	"github.com/triggermesh/tmctl/pkg/log"
 	"github.com/triggermesh/tmctl/pkg/manifest"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
-	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter/custom"
+	tmcrd "github.com/triggermesh/tmctl/pkg/triggermesh/crd"
 )
 
 func NewRootCommand(ver, commit string) *cobra.Command {
@@ -56,8 +65,12 @@ Find more information at: https://docs.triggermesh.io`,
 
 	c, err := cliconfig.New()
 	cobra.CheckErr(err)
-	crds, err := crd.Fetch(c.ConfigHome, c.Triggermesh.ComponentsVersion)
-	cobra.CheckErr(err)
+	custom.Register(c.Triggermesh.Kinds)
+	crds, err := tmcrd.Load(c)
+	if !importsCRD(os.Args[1:]) {
+		// missing CRD can be imported without the network access
+		cobra.CheckErr(err)
+	}
 
 	manifest := manifest.New(filepath.Join(
 		c.ConfigHome,
@@ -65,17 +78,24 @@ Find more information at: https://docs.triggermesh.io`,
 		triggermesh.ManifestFile))
 	_ = manifest.Read()
 
+	rootCmd.AddCommand(apply.NewCmd(c, crds))
 	rootCmd.AddCommand(brokers.NewCmd(c))
 	rootCmd.AddCommand(create.NewCmd(c, manifest, crds))
+	rootCmd.AddCommand(crd.NewCmd(c))
 	rootCmd.AddCommand(config.NewCmd())
 	rootCmd.AddCommand(delete.NewCmd(c, manifest, crds))
 	rootCmd.AddCommand(describe.NewCmd(c, manifest, crds))
+	rootCmd.AddCommand(diff.NewCmd(c, manifest, crds))
 	rootCmd.AddCommand(dump.NewCmd(c, manifest, crds))
+	rootCmd.AddCommand(filter.NewCmd(c))
+	rootCmd.AddCommand(graph.NewCmd(c, manifest, crds))
 	rootCmd.AddCommand(import_.NewCmd(c, crds))
 	rootCmd.AddCommand(logs.NewCmd(c, manifest, crds))
+	rootCmd.AddCommand(replay.NewCmd(c, manifest, crds))
+	rootCmd.AddCommand(schema.NewCmd(c, manifest))
 	rootCmd.AddCommand(sendevent.NewCmd(c, manifest, crds))
 	rootCmd.AddCommand(start.NewCmd(c, manifest, crds))
-	rootCmd.AddCommand(stop.NewCmd(c, manifest))
+	rootCmd.AddCommand(stop.NewCmd(c, manifest, crds))
 	rootCmd.AddCommand(watch.NewCmd(c))
 	rootCmd.AddCommand(version.NewCmd(ver, commit, c))
 
@@ -91,3 +111,13 @@ Find more information at: https://docs.triggermesh.io`,
 	}
 	return rootCmd
 }
+
+// importsCRD checks if the command line arguments invoke the CRD import.
+func importsCRD(args []string) bool {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return arg == "crd"
+		}
+	}
+	return false
+}
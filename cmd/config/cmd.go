@@ -21,6 +21,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/triggermesh/tmctl/cmd/brokers"
 	cliconfig "github.com/triggermesh/tmctl/pkg/config"
 )
 
@@ -38,16 +39,25 @@ func NewCmd() *cobra.Command {
 }
 
 func getCmd() *cobra.Command {
-	return &cobra.Command{
+	var broker string
+	getCmd := &cobra.Command{
 		Use:   "get [key]",
 		Short: "Read config value",
-		Args:  cobra.RangeArgs(0, 1),
+		Example: `tmctl config get triggermesh.broker.version
+tmctl config get --broker foo`,
+		Args: cobra.RangeArgs(0, 1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			key := ""
 			if len(args) == 1 {
 				key = args[0]
 			}
-			value, err := cliconfig.Get(key)
+			get := cliconfig.Get
+			if broker != "" {
+				get = func(key string) (string, error) {
+					return cliconfig.GetBroker(broker, key)
+				}
+			}
+			value, err := get(key)
 			if err != nil {
 				return err
 			}
@@ -55,15 +65,44 @@ func getCmd() *cobra.Command {
 			return nil
 		},
 	}
+	getCmd.Flags().StringVar(&broker, "broker", "", "Read the broker settings instead of the global config")
+	cobra.CheckErr(getCmd.RegisterFlagCompletionFunc("broker", brokersCompletion))
+	return getCmd
 }
 
 func setCmd() *cobra.Command {
-	return &cobra.Command{
+	var broker string
+	setCmd := &cobra.Command{
 		Use:   "set <key> <value>",
 		Short: "Write config value",
-		Args:  cobra.ExactArgs(2),
+		Long: `Write config value. Broker settings override the global broker configuration
+for a single broker. Available broker settings keys: backend, version,
+memory.buffer-size, memory.produce-timeout, redis.address, redis.username,
+redis.password. Broker must be restarted to apply the new settings.`,
+		Example: `tmctl config set triggermesh.broker.version v1.1.0
+tmctl config set --broker foo backend redis
+tmctl config set --broker foo redis.address localhost:6379
+tmctl config set docker.runtime podman
+tmctl config set offline true
+tmctl config set images.mirror registry.example.com/mirror
+tmctl config set images.overrides.AWSS3Source registry.example.com/awssqssource-adapter:dev`,
+		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if broker != "" {
+				return cliconfig.SetBroker(broker, args[0], args[1])
+			}
 			return cliconfig.Set(args[0], args[1])
 		},
 	}
+	setCmd.Flags().StringVar(&broker, "broker", "", "Write the broker settings instead of the global config")
+	cobra.CheckErr(setCmd.RegisterFlagCompletionFunc("broker", brokersCompletion))
+	return setCmd
+}
+
+func brokersCompletion(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	list, err := brokers.List(cliconfig.HomeAbsPath(), "")
+	if err != nil {
+		return []string{}, cobra.ShellCompDirectiveNoFileComp
+	}
+	return list, cobra.ShellCompDirectiveNoFileComp
 }
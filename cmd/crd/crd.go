@@ -0,0 +1,76 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/log"
+	tmcrd "github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+type CliOptions struct {
+	Config *config.Config
+}
+
+func NewCmd(config *config.Config) *cobra.Command {
+	o := &CliOptions{
+		Config: config,
+	}
+	crdCmd := &cobra.Command{
+		Use:   "crd [import]",
+		Short: "Manage TriggerMesh CRDs cache",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.HelpFunc()(cmd, args)
+		},
+	}
+	crdCmd.AddCommand(o.newImportCmd())
+	return crdCmd
+}
+
+func (o *CliOptions) newImportCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import TriggerMesh CRDs to the local cache",
+		Long: `Import TriggerMesh CRDs to the local cache. CRDs are stored as the components
+version set with the --version flag or in the config. Cached CRDs are used
+instead of the release download, e.g. on the machines without the internet access.`,
+		Example: `tmctl crd import triggermesh-crds.yaml --version v1.24.0`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.importCRD(args[0])
+		},
+	}
+}
+
+func (o *CliOptions) importCRD(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("CRD file: %w", err)
+	}
+	defer f.Close()
+	version := o.Config.Triggermesh.ComponentsVersion
+	if err := tmcrd.Import(o.Config.ConfigHome, version, f); err != nil {
+		return fmt.Errorf("importing CRD: %w", err)
+	}
+	log.Printf("%s CRD is imported", version)
+	return nil
+}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/log"
 	"github.com/triggermesh/tmctl/pkg/output"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
@@ -31,23 +32,38 @@ import (
 )
 
 func (o *CliOptions) newBrokerCmd() *cobra.Command {
-	var version string
+	var version, backend, port string
 	brokerCmd := &cobra.Command{
-		Use:               "broker <name>",
-		Short:             "Create TriggerMesh Broker. More information at https://docs.triggermesh.io/brokers/",
-		Example:           "tmctl create broker foo",
+		Use:   "broker <name>",
+		Short: "Create TriggerMesh Broker. More information at https://docs.triggermesh.io/brokers/",
+		Example: `tmctl create broker foo
+tmctl create broker foo --backend redis
+tmctl create broker foo --backend memory --version v1.1.0
+tmctl create broker foo --port 8080`,
 		Args:              cobra.MinimumNArgs(1),
 		ValidArgsFunction: cobra.NoFileCompletions,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return o.broker(args[0], version)
+			if !cmd.Flags().Changed("version") {
+				// keep following the global broker version
+				version = ""
+			}
+			return o.broker(args[0], version, backend, port)
 		},
 	}
 	brokerCmd.Flags().StringVar(&version, "version", o.Config.Triggermesh.Broker.Version, "TriggerMesh broker version.")
+	brokerCmd.Flags().StringVar(&port, "port", "", "Host port of the broker container. Random port is assigned if not set.")
+	brokerCmd.Flags().StringVar(&backend, "backend", "", "Broker backend, \"memory\" or \"redis\". Redis backend starts the local Redis container with persistent storage. Global broker configuration is used if not set.")
+	cobra.CheckErr(brokerCmd.RegisterFlagCompletionFunc("backend", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{config.BackendMemory, config.BackendRedis}, cobra.ShellCompDirectiveNoFileComp
+	}))
 	return brokerCmd
 }
 
-func (o *CliOptions) broker(name, version string) error {
+func (o *CliOptions) broker(name, version, backend, port string) error {
 	ctx := context.Background()
+	if backend != "" && backend != config.BackendMemory && backend != config.BackendRedis {
+		return fmt.Errorf("unsupported broker backend %q", backend)
+	}
 	o.Manifest.Path = filepath.Join(o.Config.ConfigHome, name, triggermesh.ManifestFile)
 	if _, err := os.Stat(o.Manifest.Path); !os.IsNotExist(err) {
 		return fmt.Errorf("broker %q already exists", name)
@@ -56,11 +72,27 @@ func (o *CliOptions) broker(name, version string) error {
 	if _, err := tmbroker.CreateBrokerConfig(o.Config.ConfigHome, name); err != nil {
 		return fmt.Errorf("creating broker config: %w", err)
 	}
+	reservation, err := o.reservePort(name, tmbroker.ContainerName(name), port)
+	if err != nil {
+		return err
+	}
+	defer reservation.rollback()
+	if backend == config.BackendRedis {
+		if _, err := tmbroker.NewManagedRedis(o.Config.ConfigHome, name); err != nil {
+			return fmt.Errorf("managed redis: %w", err)
+		}
+	}
+	if backend != "" || version != "" {
+		settings := config.LocalBrokerConfig{
+			Backend: backend,
+			Version: version,
+		}
+		if err := settings.Save(o.Config.ConfigHome, name); err != nil {
+			return fmt.Errorf("broker settings: %w", err)
+		}
+	}
 
-	brokerConfig := o.Config.Triggermesh.Broker
-	brokerConfig.Version = version
-
-	broker, err := tmbroker.New(name, brokerConfig)
+	broker, err := tmbroker.New(o.Config.ConfigHome, name, o.Config.Triggermesh.Broker, o.Config.Images)
 	if err != nil {
 		return fmt.Errorf("broker: %w", err)
 	}
@@ -84,6 +116,7 @@ func (o *CliOptions) broker(name, version string) error {
 	if _, err := broker.(triggermesh.Runnable).Start(ctx, nil, restart); err != nil {
 		return err
 	}
+	reservation.commit()
 
 	output.PrintStatus("broker", broker, []string{}, []string{})
 	return nil
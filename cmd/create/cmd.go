@@ -24,9 +24,11 @@ import (
 
 	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/log"
 	"github.com/triggermesh/tmctl/pkg/manifest"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
 )
 
@@ -88,6 +90,52 @@ func isFlag(s string) bool {
 	return len(strings.TrimLeft(s, "-")) == len(s)-2
 }
 
+// portReservation is the component host port assignment
+// that is rolled back unless the component is started.
+type portReservation struct {
+	configHome string
+	broker     string
+	component  string
+	previous   string
+	done       bool
+}
+
+// commit keeps the reserved port assigned to the component.
+func (r *portReservation) commit() {
+	r.done = true
+}
+
+// rollback restores the previous host port assignment if the reservation is not committed.
+func (r *portReservation) rollback() {
+	if r.done {
+		return
+	}
+	r.done = true
+	if err := tmbroker.RestoreHostPort(r.configHome, r.broker, r.component, r.previous); err != nil {
+		log.Printf("WARNING: host port is not released: %v", err)
+	}
+}
+
+// reservePort assigns the host port to the component container if it is set.
+func (o *CliOptions) reservePort(broker, component, port string) (*portReservation, error) {
+	if port == "" {
+		return &portReservation{done: true}, nil
+	}
+	ports, err := tmbroker.LoadHostPorts(o.Config.ConfigHome, broker)
+	if err != nil {
+		return nil, fmt.Errorf("host port: %w", err)
+	}
+	if err := tmbroker.ReserveHostPort(o.Config.ConfigHome, broker, component, port); err != nil {
+		return nil, fmt.Errorf("host port: %w", err)
+	}
+	return &portReservation{
+		configHome: o.Config.ConfigHome,
+		broker:     broker,
+		component:  component,
+		previous:   ports[component],
+	}, nil
+}
+
 func (o *CliOptions) translateEventSource(eventSourcesFilter []string) ([]string, error) {
 	var result []string
 	for _, source := range eventSourcesFilter {
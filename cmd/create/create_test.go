@@ -0,0 +1,290 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package create
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/docker/fake"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter/custom"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/test"
+)
+
+func freePort(t *testing.T) string {
+	listener, err := net.Listen("tcp", ":0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+}
+
+func TestCreateBroker(t *testing.T) {
+	runtime, c := fake.Setup(t)
+	o := &CliOptions{
+		Config:   c,
+		Manifest: manifest.New(""),
+	}
+	port := freePort(t)
+	assert.NoError(t, o.broker("foo", "", config.BackendRedis, port))
+
+	assert.Equal(t, "foo", c.Context)
+	assert.Equal(t, []string{docker.NetworkName("foo")}, runtime.CallsOf("network"))
+	assert.Equal(t, []string{"foo-redis", "foo-broker"}, runtime.CallsOf("create"))
+
+	redis := runtime.Container("foo-redis")
+	assert.Equal(t, config.ManagedRedisImage, redis.Config.Image)
+	assert.Equal(t, []string{"foo-redis-data:/data"}, redis.HostConfig.Binds)
+
+	broker := runtime.Container("foo-broker")
+	assert.True(t, broker.Running)
+	assert.Equal(t, "gcr.io/triggermesh/redis-broker:v1.1.0", broker.Config.Image)
+	assert.Equal(t, port, broker.HostPort("8080/tcp"))
+	assert.Contains(t, broker.Config.Entrypoint, "foo-redis:6379")
+	managedRedis, err := tmbroker.LoadManagedRedis(c.ConfigHome, "foo")
+	assert.NoError(t, err)
+	assert.NotContains(t, broker.Config.Entrypoint, managedRedis.Password)
+	assert.NotContains(t, broker.Config.Cmd, managedRedis.Password)
+	assert.Equal(t, managedRedis.Password, broker.Env()["REDIS_PASSWORD"])
+	assert.Contains(t, broker.HostConfig.Binds, filepath.Join(c.ConfigHome, "foo")+":/etc/triggermesh:ro")
+	assert.Contains(t, broker.Config.Entrypoint, "/etc/triggermesh/broker.conf")
+
+	m := manifest.New(filepath.Join(c.ConfigHome, "foo", "manifest.yaml"))
+	assert.NoError(t, m.Read())
+	assert.Len(t, m.Objects, 1)
+	assert.Equal(t, tmbroker.BrokerKind, m.Objects[0].Kind)
+
+	assert.EqualError(t, o.broker("foo", "", "", ""), `broker "foo" already exists`)
+	assert.EqualError(t, o.broker("bar", "", "kafka", ""), `unsupported broker backend "kafka"`)
+}
+
+func TestCreateBrokerPortConflict(t *testing.T) {
+	runtime, c := fake.Setup(t)
+	runtime.StartErrors["foo-broker"] = errors.New("Bind for 0.0.0.0:8080 failed: port is already allocated")
+	o := &CliOptions{
+		Config:   c,
+		Manifest: manifest.New(""),
+	}
+	err := o.broker("foo", "", "", "")
+	assert.ErrorContains(t, err, "port is already in use by another process")
+	// failed container is removed to not block the next start
+	assert.Nil(t, runtime.Container("foo-broker"))
+}
+
+func TestCreateTransformation(t *testing.T) {
+	runtime, c := fake.Setup(t)
+	o := &CliOptions{
+		Config:   c,
+		Manifest: manifest.New(""),
+		CRD:      test.CRD(),
+	}
+	assert.NoError(t, o.broker("foo", "", "", ""))
+
+	spec := filepath.Join(t.TempDir(), "spec.yaml")
+	assert.NoError(t, os.WriteFile(spec, []byte(`data:
+- operation: add
+  paths:
+  - key: foo
+    value: bar
+`), 0644))
+	port := freePort(t)
+	assert.NoError(t, o.transformation("", "", spec, port, nil, []string{"foo.type"}))
+
+	transformation := runtime.Container("foo-transformation")
+	assert.True(t, transformation.Running)
+	assert.Equal(t, docker.NetworkName("foo"), string(transformation.HostConfig.NetworkMode))
+	assert.Equal(t, port, transformation.HostPort("8080/tcp"))
+
+	metricsPort := transformation.HostPort("9092/tcp")
+	assert.NotEmpty(t, metricsPort)
+
+	triggers, err := tmbroker.GetTargetTriggers("foo-transformation", "foo", c.ConfigHome)
+	assert.NoError(t, err)
+	assert.Len(t, triggers, 1)
+
+	// recreated container keeps its host ports
+	assert.NoError(t, os.WriteFile(spec, []byte(`data:
+- operation: delete
+  paths:
+  - key: foo
+`), 0644))
+	assert.NoError(t, o.transformation("", "", spec, "", nil, nil))
+	transformation = runtime.Container("foo-transformation")
+	assert.Equal(t, port, transformation.HostPort("8080/tcp"))
+	assert.Equal(t, metricsPort, transformation.HostPort("9092/tcp"))
+}
+
+func TestCreatePortReleasedOnFailure(t *testing.T) {
+	runtime, c := fake.Setup(t)
+	o := &CliOptions{
+		Config:   c,
+		Manifest: manifest.New(""),
+		CRD:      test.CRD(),
+	}
+	assert.NoError(t, o.broker("foo", "", "", ""))
+
+	spec := filepath.Join(t.TempDir(), "spec.yaml")
+	assert.NoError(t, os.WriteFile(spec, []byte("data: []\n"), 0644))
+	port := freePort(t)
+	runtime.StartErrors["foo-transformation"] = errors.New("image not found")
+	assert.Error(t, o.transformation("", "", spec, port, nil, nil))
+
+	ports, err := tmbroker.LoadHostPorts(c.ConfigHome, "foo")
+	assert.NoError(t, err)
+	assert.NotContains(t, ports, "foo-transformation")
+
+	// released port can be used by the other component
+	_, err = o.reservePort("foo", "sockeye", port)
+	assert.NoError(t, err)
+}
+
+func TestCreateCustomSource(t *testing.T) {
+	runtime, c := fake.Setup(t)
+	custom.Register(map[string]config.CustomKind{
+		"InventorySource": {
+			Image: "registry.example.com/inventory-adapter:v1",
+			Env:   map[string]string{"INVENTORY_URL": "endpoint", "INVENTORY_TOKEN": "token"},
+		},
+	})
+	defer custom.Register(nil)
+
+	crds := test.CRD()
+	reader, err := os.Open(test.CustomCRD())
+	assert.NoError(t, err)
+	customCRD, err := crd.Parse(reader)
+	assert.NoError(t, err)
+	crds["inventorysource"] = customCRD["inventorysource"]
+
+	o := &CliOptions{
+		Config:   c,
+		Manifest: manifest.New(""),
+		CRD:      crds,
+	}
+	assert.NoError(t, o.broker("foo", "", "", ""))
+	assert.NoError(t, o.source("", "inventory", "", map[string]string{
+		"endpoint": "https://inventory.example.com",
+		"token":    "s3cr3t",
+	}))
+
+	source := runtime.Container("foo-inventorysource")
+	assert.True(t, source.Running)
+	assert.Equal(t, "registry.example.com/inventory-adapter:v1", source.Config.Image)
+	env := source.Env()
+	assert.Equal(t, "https://inventory.example.com", env["INVENTORY_URL"])
+	assert.Equal(t, "s3cr3t", env["INVENTORY_TOKEN"])
+	assert.Equal(t, tmbroker.LocalURL("foo"), env["K_SINK"])
+
+	m := manifest.New(filepath.Join(c.ConfigHome, "foo", "manifest.yaml"))
+	assert.NoError(t, m.Read())
+	kinds := []string{}
+	for _, object := range m.Objects {
+		kinds = append(kinds, object.Kind)
+	}
+	assert.Contains(t, kinds, "InventorySource")
+	assert.Contains(t, kinds, "Secret")
+}
+
+func TestCreateCustomSourceRole(t *testing.T) {
+	runtime, c := fake.Setup(t)
+	// API group does not tell the kind role
+	custom.Register(map[string]config.CustomKind{
+		"InventorySource": {
+			Image: "registry.example.com/inventory-adapter:v1",
+			Role:  config.SourceRole,
+		},
+	})
+	defer custom.Register(nil)
+
+	crds := test.CRD()
+	reader, err := os.Open(test.CustomCRD())
+	assert.NoError(t, err)
+	customCRD, err := crd.Parse(reader)
+	assert.NoError(t, err)
+	inventory := customCRD["inventorysource"]
+	inventory.Spec.Group = "inventory.example.com"
+	crds["inventorysource"] = inventory
+
+	o := &CliOptions{
+		Config:   c,
+		Manifest: manifest.New(""),
+		CRD:      crds,
+	}
+	assert.NoError(t, o.broker("foo", "", "", ""))
+	assert.NoError(t, o.source("", "inventory", "", map[string]string{"endpoint": "https://inventory.example.com"}))
+	assert.True(t, runtime.Container("foo-inventorysource").Running)
+
+	m := manifest.New(filepath.Join(c.ConfigHome, "foo", "manifest.yaml"))
+	assert.NoError(t, m.Read())
+	assert.Contains(t, completion.ListSources(m), "foo-inventorysource")
+	assert.NotContains(t, completion.ListTargets(m), "foo-inventorysource")
+}
+
+func TestCreateBrokerImages(t *testing.T) {
+	runtime, c := fake.Setup(t)
+	c.Images = config.Images{
+		Mirror:    "registry.example.com/mirror",
+		Overrides: map[string]string{"RedisBroker": "registry.example.com/redis-broker:dev"},
+	}
+	o := &CliOptions{
+		Config:   c,
+		Manifest: manifest.New(""),
+	}
+	assert.NoError(t, o.broker("foo", "", config.BackendRedis, ""))
+	assert.Equal(t, []string{
+		"registry.example.com/mirror/library/redis:7.0-alpine",
+		"registry.example.com/redis-broker:dev",
+	}, runtime.CallsOf("pull"))
+}
+
+func TestCreateTriggerCESQL(t *testing.T) {
+	_, c := fake.Setup(t)
+	o := &CliOptions{
+		Config:   c,
+		Manifest: manifest.New(""),
+		CRD:      test.CRD(),
+	}
+	assert.NoError(t, o.broker("foo", "", "", ""))
+	assert.NoError(t, o.targetFromImage("sockeye", "docker.io/n3wscott/sockeye:v0.7.0", "", map[string]string{}, nil, nil))
+
+	// default broker version does not evaluate CESQL, the trigger is created anyway
+	assert.Equal(t, "v1.1.0", c.Triggermesh.Broker.Version)
+	filter := filterOptions{match: tmbroker.MatchAll, cesql: "source LIKE '%/orders' AND EXISTS subject"}
+	assert.NoError(t, o.trigger("orders", filter, nil, nil, "sockeye", deliveryOptions{retry: -1}))
+
+	triggers, err := tmbroker.LocalTriggers("foo", c.ConfigHome)
+	assert.NoError(t, err)
+	assert.Len(t, triggers, 1)
+	for _, trigger := range triggers {
+		_, cesql := tmbroker.SplitFilters(trigger.Filters)
+		assert.Equal(t, filter.cesql, cesql)
+		assert.Equal(t, "sockeye", trigger.Target.Component)
+	}
+
+	filter.cesql = "source LIKE"
+	assert.ErrorContains(t, o.trigger("orders", filter, nil, nil, "sockeye", deliveryOptions{retry: -1}), "invalid CESQL expression")
+}
@@ -36,7 +36,7 @@ import (
 
 func (o *CliOptions) newSourceCmd() *cobra.Command {
 	return &cobra.Command{
-		Use:   "source [kind]/[--from-image <image>][--name <name>]",
+		Use:   "source [kind]/[--from-image <image>][--name <name>][--port <port>]",
 		Short: "Create TriggerMesh source. More information at https://docs.triggermesh.io",
 		Example: `tmctl create source httppoller \
 	--endpoint https://www.example.com \
@@ -68,7 +68,12 @@ func (o *CliOptions) newSourceCmd() *cobra.Command {
 				o.Config.Triggermesh.ComponentsVersion = v
 				delete(params, "version")
 			}
-			crd, err := crd.Fetch(o.Config.ConfigHome, o.Config.Triggermesh.ComponentsVersion)
+			var port string
+			if p, exists := params["port"]; exists {
+				port = p
+				delete(params, "port")
+			}
+			crd, err := crd.Load(o.Config)
 			if err != nil {
 				return err
 			}
@@ -88,30 +93,34 @@ func (o *CliOptions) newSourceCmd() *cobra.Command {
 			}
 			if image, exists := params["from-image"]; exists {
 				delete(params, "from-image")
-				return o.sourceFromImage(name, image, params)
+				return o.sourceFromImage(name, image, port, params)
 			}
-			return o.source(name, args[0], params)
+			return o.source(name, args[0], port, params)
 		},
 	}
 }
 
-func (o *CliOptions) source(name, kind string, params map[string]string) error {
+func (o *CliOptions) source(name, kind, port string, params map[string]string) error {
 	ctx := context.Background()
-	broker, err := tmbroker.New(o.Config.Context, o.Config.Triggermesh.Broker)
+	broker, err := tmbroker.New(o.Config.ConfigHome, o.Config.Context, o.Config.Triggermesh.Broker, o.Config.Images)
 	if err != nil {
 		return fmt.Errorf("broker object: %v", err)
 	}
-	port, err := broker.(triggermesh.Consumer).GetPort(ctx)
-	if err != nil {
+	if _, err := broker.(triggermesh.Consumer).GetPort(ctx); err != nil {
 		return fmt.Errorf("broker offline: %v", err)
 	}
-	params["sink.uri"] = "http://host.docker.internal:" + port
+	params["sink.uri"] = tmbroker.LocalURL(o.Config.Context)
 
 	crd, exists := o.CRD[kind+"source"]
 	if !exists {
 		return fmt.Errorf("CRD for kind %q not found", kind)
 	}
-	s := source.New(name, kind, o.Config.Context, o.Config.Triggermesh.ComponentsVersion, crd, params, nil)
+	s := source.New(name, kind, o.Config.Context, o.Config.Triggermesh.ComponentsVersion, o.Config.Images, crd, params, nil)
+	reservation, err := o.reservePort(o.Config.Context, s.GetName(), port)
+	if err != nil {
+		return err
+	}
+	defer reservation.rollback()
 
 	secrets, secretsEnv, err := components.ProcessSecrets(s.(triggermesh.Parent), o.Manifest)
 	if err != nil {
@@ -144,23 +153,28 @@ func (o *CliOptions) source(name, kind string, params map[string]string) error {
 	if _, err := s.(triggermesh.Runnable).Start(ctx, secretsEnv, (restart || secretsChanged)); err != nil {
 		return err
 	}
+	reservation.commit()
 	output.PrintStatus("producer", s, []string{}, []string{})
 	return nil
 }
 
-func (o *CliOptions) sourceFromImage(name, image string, params map[string]string) error {
+func (o *CliOptions) sourceFromImage(name, image, port string, params map[string]string) error {
 	ctx := context.Background()
-	broker, err := tmbroker.New(o.Config.Context, o.Config.Triggermesh.Broker)
+	broker, err := tmbroker.New(o.Config.ConfigHome, o.Config.Context, o.Config.Triggermesh.Broker, o.Config.Images)
 	if err != nil {
 		return fmt.Errorf("broker object: %v", err)
 	}
-	port, err := broker.(triggermesh.Consumer).GetPort(ctx)
-	if err != nil {
+	if _, err := broker.(triggermesh.Consumer).GetPort(ctx); err != nil {
 		return fmt.Errorf("broker offline: %v", err)
 	}
-	params["K_SINK"] = "http://host.docker.internal:" + port
+	params["K_SINK"] = tmbroker.LocalURL(o.Config.Context)
 
 	s := service.New(name, image, o.Config.Context, service.Producer, params)
+	reservation, err := o.reservePort(o.Config.Context, s.GetName(), port)
+	if err != nil {
+		return err
+	}
+	defer reservation.rollback()
 
 	log.Println("Updating manifest")
 	restart, err := o.Manifest.Add(s)
@@ -171,6 +185,7 @@ func (o *CliOptions) sourceFromImage(name, image string, params map[string]strin
 	if _, err := s.(triggermesh.Runnable).Start(ctx, nil, restart); err != nil {
 		return err
 	}
+	reservation.commit()
 	output.PrintStatus("producer", s, []string{}, []string{})
 	return nil
 }
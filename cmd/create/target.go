@@ -38,7 +38,7 @@ import (
 
 func (o *CliOptions) newTargetCmd() *cobra.Command {
 	return &cobra.Command{
-		Use:   "target [kind]/[--from-image <image>][--name <name>][--source <name>...][--eventTypes <type>...]",
+		Use:   "target [kind]/[--from-image <image>][--name <name>][--port <port>][--source <name>...][--eventTypes <type>...]",
 		Short: "Create TriggerMesh target. More information at https://docs.triggermesh.io",
 		Example: `tmctl create target http \
 	--endpoint https://image-charts.com \
@@ -68,7 +68,12 @@ func (o *CliOptions) newTargetCmd() *cobra.Command {
 				o.Config.Triggermesh.ComponentsVersion = v
 				delete(params, "version")
 			}
-			crd, err := crd.Fetch(o.Config.ConfigHome, o.Config.Triggermesh.ComponentsVersion)
+			var port string
+			if p, exists := params["port"]; exists {
+				port = p
+				delete(params, "port")
+			}
+			crd, err := crd.Load(o.Config)
 			if err != nil {
 				return err
 			}
@@ -102,14 +107,14 @@ func (o *CliOptions) newTargetCmd() *cobra.Command {
 			}
 			if image, exists := params["from-image"]; exists {
 				delete(params, "from-image")
-				return o.targetFromImage(name, image, params, eventSourcesFilter, eventTypesFilter)
+				return o.targetFromImage(name, image, port, params, eventSourcesFilter, eventTypesFilter)
 			}
-			return o.target(name, args[0], params, eventSourcesFilter, eventTypesFilter)
+			return o.target(name, args[0], port, params, eventSourcesFilter, eventTypesFilter)
 		},
 	}
 }
 
-func (o *CliOptions) target(name, kind string, args map[string]string, eventSourcesFilter, eventTypesFilter []string) error {
+func (o *CliOptions) target(name, kind, port string, args map[string]string, eventSourcesFilter, eventTypesFilter []string) error {
 	ctx := context.Background()
 
 	et, err := o.translateEventSource(eventSourcesFilter)
@@ -122,7 +127,12 @@ func (o *CliOptions) target(name, kind string, args map[string]string, eventSour
 	if !exists {
 		return fmt.Errorf("CRD for kind %q not found", kind)
 	}
-	t := target.New(name, kind, o.Config.Context, o.Config.Triggermesh.ComponentsVersion, crd, args)
+	t := target.New(name, kind, o.Config.Context, o.Config.Triggermesh.ComponentsVersion, o.Config.Images, crd, args)
+	reservation, err := o.reservePort(o.Config.Context, t.GetName(), port)
+	if err != nil {
+		return err
+	}
+	defer reservation.rollback()
 
 	secrets, secretsEnv, err := components.ProcessSecrets(t.(triggermesh.Parent), o.Manifest)
 	if err != nil {
@@ -149,6 +159,7 @@ func (o *CliOptions) target(name, kind string, args map[string]string, eventSour
 	if _, err := t.(triggermesh.Runnable).Start(ctx, secretsEnv, (restart || secretsChanged)); err != nil {
 		return err
 	}
+	reservation.commit()
 
 	// update our triggers in case of target container restart
 	if restart || secretsChanged {
@@ -158,7 +169,7 @@ func (o *CliOptions) target(name, kind string, args map[string]string, eventSour
 	}
 
 	for _, et := range eventTypesFilter {
-		if _, err := o.createTrigger("", t, tmbroker.FilterAttribute("type", et)); err != nil {
+		if _, err := o.createTrigger("", t, typeFilter(et)); err != nil {
 			return fmt.Errorf("creating trigger: %w", err)
 		}
 	}
@@ -167,8 +178,8 @@ func (o *CliOptions) target(name, kind string, args map[string]string, eventSour
 	return nil
 }
 
-func (o *CliOptions) createTrigger(name string, target triggermesh.Component, filter *eventingbroker.Filter) (triggermesh.Component, error) {
-	trigger, err := tmbroker.NewTrigger(name, o.Config.Context, o.Config.ConfigHome, target, filter)
+func (o *CliOptions) createTrigger(name string, target triggermesh.Component, filters []eventingbroker.Filter, options ...func(*tmbroker.Trigger)) (triggermesh.Component, error) {
+	trigger, err := tmbroker.NewTrigger(name, o.Config.Context, o.Config.ConfigHome, target, filters, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -181,21 +192,19 @@ func (o *CliOptions) createTrigger(name string, target triggermesh.Component, fi
 	return trigger, nil
 }
 
+// typeFilter returns the trigger filters that pass the event type.
+func typeFilter(eventType string) []eventingbroker.Filter {
+	return []eventingbroker.Filter{*tmbroker.FilterAttribute("type", eventType)}
+}
+
 func (o *CliOptions) updateTriggers(target triggermesh.Component) error {
-	triggers, err := tmbroker.GetTargetTriggers(target.GetName(), o.Config.Context, o.Config.ConfigHome)
-	if err != nil {
-		return fmt.Errorf("target triggers: %w", err)
-	}
-	for _, trigger := range triggers {
-		trigger.(*tmbroker.Trigger).SetTarget(target)
-		if err := trigger.(*tmbroker.Trigger).WriteLocalConfig(); err != nil {
-			return fmt.Errorf("broker config update: %w", err)
-		}
+	if err := tmbroker.UpdateComponentTriggers(o.Config.Context, o.Config.ConfigHome, target); err != nil {
+		return fmt.Errorf("broker config update: %w", err)
 	}
 	return nil
 }
 
-func (o *CliOptions) targetFromImage(name, image string, params map[string]string, eventSourcesFilter, eventTypesFilter []string) error {
+func (o *CliOptions) targetFromImage(name, image, port string, params map[string]string, eventSourcesFilter, eventTypesFilter []string) error {
 	ctx := context.Background()
 
 	et, err := o.translateEventSource(eventSourcesFilter)
@@ -205,6 +214,11 @@ func (o *CliOptions) targetFromImage(name, image string, params map[string]strin
 	eventTypesFilter = append(eventTypesFilter, et...)
 
 	s := service.New(name, image, o.Config.Context, service.Consumer, params)
+	reservation, err := o.reservePort(o.Config.Context, s.GetName(), port)
+	if err != nil {
+		return err
+	}
+	defer reservation.rollback()
 
 	log.Println("Updating manifest")
 	restart, err := o.Manifest.Add(s)
@@ -215,6 +229,7 @@ func (o *CliOptions) targetFromImage(name, image string, params map[string]strin
 	if _, err := s.(triggermesh.Runnable).Start(ctx, nil, restart); err != nil {
 		return err
 	}
+	reservation.commit()
 	// update our triggers in case of target container restart
 	if restart {
 		if err := o.updateTriggers(s); err != nil {
@@ -222,7 +237,7 @@ func (o *CliOptions) targetFromImage(name, image string, params map[string]strin
 		}
 	}
 	for _, et := range eventTypesFilter {
-		if _, err := o.createTrigger("", s, tmbroker.FilterAttribute("type", et)); err != nil {
+		if _, err := o.createTrigger("", s, typeFilter(et)); err != nil {
 			return fmt.Errorf("creating trigger: %w", err)
 		}
 	}
@@ -33,7 +33,6 @@ import (
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
 	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components/transformation"
-	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
 )
 
 const (
@@ -63,10 +62,10 @@ https://github.com/triggermesh/triggermesh/tree/main/config/samples/bumblebee`
 )
 
 func (o *CliOptions) newTransformationCmd() *cobra.Command {
-	var name, target, file string
+	var name, target, file, port string
 	var eventSourcesFilter, eventTypesFilter []string
 	transformationCmd := &cobra.Command{
-		Use:   "transformation [--target <name>][--source <name>...][--eventTypes <type>...][--from <path>]",
+		Use:   "transformation [--target <name>][--source <name>...][--eventTypes <type>...][--from <path>][--port <port>]",
 		Short: "Create TriggerMesh transformation. More information at https://docs.triggermesh.io/transformation/jsontransformation/",
 		Example: `tmctl create transformation <<EOF
   data:
@@ -75,19 +74,16 @@ func (o *CliOptions) newTransformationCmd() *cobra.Command {
     - key: new-field
       value: hello from Transformation!
 EOF`,
-		ValidArgs: []string{"--name", "--target", "--source", "--eventTypes", "--from"},
+		ValidArgs: []string{"--name", "--target", "--source", "--eventTypes", "--from", "--port"},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return o.transformation(name, target, file, eventSourcesFilter, eventTypesFilter)
+			return o.transformation(name, target, file, port, eventSourcesFilter, eventTypesFilter)
 		},
 	}
 
-	crd, err := crd.Fetch(o.Config.ConfigHome, o.Config.Triggermesh.ComponentsVersion)
-	cobra.CheckErr(err)
-	o.CRD = crd
-
 	transformationCmd.Flags().StringVar(&name, "name", "", "Transformation name")
 	transformationCmd.Flags().StringVarP(&file, "from", "f", "", "Transformation specification file")
 	transformationCmd.Flags().StringVar(&target, "target", "", "Target name")
+	transformationCmd.Flags().StringVar(&port, "port", "", "Host port of the transformation container. Random port is assigned if not set")
 	transformationCmd.Flags().StringSliceVar(&eventSourcesFilter, "source", []string{}, "Sources component names")
 	transformationCmd.Flags().StringSliceVar(&eventTypesFilter, "eventTypes", []string{}, "Event types filter")
 
@@ -104,7 +100,7 @@ EOF`,
 	return transformationCmd
 }
 
-func (o *CliOptions) transformation(name, target, file string, eventSourcesFilter, eventTypesFilter []string) error {
+func (o *CliOptions) transformation(name, target, file, port string, eventSourcesFilter, eventTypesFilter []string) error {
 	ctx := context.Background()
 	var targetComponent triggermesh.Component
 	if target != "" {
@@ -148,7 +144,12 @@ func (o *CliOptions) transformation(name, target, file string, eventSourcesFilte
 		return fmt.Errorf("CRD for kind \"transformation\" not found")
 	}
 
-	t := transformation.New(name, "transformation", o.Config.Context, o.Config.Triggermesh.ComponentsVersion, crd, spec)
+	t := transformation.New(name, "transformation", o.Config.Context, o.Config.Triggermesh.ComponentsVersion, o.Config.Images, crd, spec)
+	reservation, err := o.reservePort(o.Config.Context, t.GetName(), port)
+	if err != nil {
+		return err
+	}
+	defer reservation.rollback()
 
 	transformationEventType := fmt.Sprintf("%s.output", t.GetName())
 	if et, _ := t.(triggermesh.Producer).GetEventTypes(); len(et) == 0 {
@@ -170,6 +171,7 @@ func (o *CliOptions) transformation(name, target, file string, eventSourcesFilte
 	if _, err := t.(triggermesh.Runnable).Start(ctx, nil, restart); err != nil {
 		return err
 	}
+	reservation.commit()
 
 	// update our triggers in case of target container restart
 	if restart {
@@ -184,20 +186,20 @@ func (o *CliOptions) transformation(name, target, file string, eventSourcesFilte
 		if targetTriggers, err = tmbroker.GetTargetTriggers(targetComponent.GetName(), o.Config.Context, o.Config.ConfigHome); err != nil {
 			return fmt.Errorf("target triggers: %w", err)
 		}
-		if _, err := o.createTrigger("", targetComponent, tmbroker.FilterAttribute("type", transformationEventType)); err != nil {
+		if _, err := o.createTrigger("", targetComponent, typeFilter(transformationEventType)); err != nil {
 			return fmt.Errorf("create trigger: %w", err)
 		}
 	}
 
 	// updating existing triggers from sources to target
 	for _, et := range eventTypesFilter {
-		filter := tmbroker.FilterAttribute("type", et)
-		if _, err := o.createTrigger("", t, filter); err != nil {
+		if _, err := o.createTrigger("", t, typeFilter(et)); err != nil {
 			return err
 		}
 		for _, component := range targetTriggers {
 			trigger := component.(*tmbroker.Trigger)
-			if trigger.Filters[0].Exact == nil ||
+			if len(trigger.Filters) == 0 ||
+				trigger.Filters[0].Exact == nil ||
 				trigger.Filters[0].Exact["type"] != et {
 				continue
 			}
@@ -17,13 +17,15 @@ limitations under the License.
 package create
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/spf13/cobra"
 
 	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
 
 	"github.com/triggermesh/tmctl/pkg/completion"
 	"github.com/triggermesh/tmctl/pkg/log"
@@ -32,26 +34,60 @@ import (
 	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
 )
 
+// filterOptions are the trigger filter expressions.
+type filterOptions struct {
+	raw        []string
+	attributes []string
+	exclude    []string
+	match      string
+	cesql      string
+}
+
+// deliveryOptions are the trigger event delivery parameters.
+type deliveryOptions struct {
+	retry         int32
+	backoffPolicy string
+	backoffDelay  string
+	deadLetter    string
+}
+
 func (o *CliOptions) newTriggerCmd() *cobra.Command {
-	var name, target, rawFilter string
+	var name, target string
 	var eventSourcesFilter, eventTypesFilter []string
+	var filter filterOptions
+	var delivery deliveryOptions
 	triggerCmd := &cobra.Command{
-		Use:       "trigger --target <name> [--source <name>...][--eventTypes <type>...]",
-		Short:     "Create TriggerMesh trigger. More information at https://docs.triggermesh.io/brokers/triggers/",
-		Example:   "tmctl create trigger --target sockeye --source foo-httppollersource",
-		ValidArgs: []string{"--target", "--name", "--source", "--eventTypes"},
+		Use:   "trigger --target <name> [--source <name>...][--eventTypes <type>...][--filter-attr <key=value>...][--cesql <expression>][--retry <n>][--dead-letter <name>]",
+		Short: "Create TriggerMesh trigger. More information at https://docs.triggermesh.io/brokers/triggers/",
+		Example: `tmctl create trigger --target sockeye --source foo-httppollersource
+tmctl create trigger --target sockeye --filter-attr type=com.example.* --filter-attr subject=*.json --exclude source=test
+tmctl create trigger --target sockeye --cesql "source LIKE '%/orders' AND EXISTS subject"
+tmctl create trigger --target sockeye --retry 3 --backoff-policy exponential --backoff-delay PT0.5S --dead-letter dls`,
+		ValidArgs: []string{"--target", "--name", "--source", "--eventTypes", "--filter-attr", "--exclude", "--match", "--filter", "--cesql", "--retry", "--backoff-policy", "--backoff-delay", "--dead-letter"},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return o.trigger(name, rawFilter, eventSourcesFilter, eventTypesFilter, target)
+			if !cmd.Flags().Changed("retry") {
+				delivery.retry = -1
+			}
+			return o.trigger(name, filter, eventSourcesFilter, eventTypesFilter, target, delivery)
 		},
 	}
 	triggerCmd.Flags().StringVar(&name, "name", "", "Trigger name")
 	triggerCmd.Flags().StringVar(&target, "target", "", "Target name")
-	triggerCmd.Flags().StringVar(&rawFilter, "filter", "", "Raw filter JSON")
+	triggerCmd.Flags().StringArrayVar(&filter.raw, "filter", []string{}, "Raw filter JSON. Can be repeated")
+	triggerCmd.Flags().StringArrayVar(&filter.attributes, "filter-attr", []string{}, "Attribute filter, e.g. \"source=foo-*\". Can be repeated")
+	triggerCmd.Flags().StringArrayVar(&filter.exclude, "exclude", []string{}, "Attribute filter that events must not match. Can be repeated")
+	triggerCmd.Flags().StringVar(&filter.match, "match", tmbroker.MatchAll, "Pass events matching all or any of the --filter-attr expressions")
+	triggerCmd.Flags().StringVar(&filter.cesql, "cesql", "", "CloudEvents SQL filter expression. Brokers v1.1.0 and older do not evaluate it")
 	triggerCmd.Flags().StringSliceVar(&eventSourcesFilter, "source", []string{}, "Event sources filter")
 	triggerCmd.Flags().StringSliceVar(&eventTypesFilter, "eventTypes", []string{}, "Event types filter")
+	triggerCmd.Flags().Int32Var(&delivery.retry, "retry", 0, "Number of delivery retries")
+	triggerCmd.Flags().StringVar(&delivery.backoffPolicy, "backoff-policy", "", "Retry backoff policy. One of linear, exponential")
+	triggerCmd.Flags().StringVar(&delivery.backoffDelay, "backoff-delay", "", "Retry backoff delay in ISO 8601 duration format, e.g. PT0.5S")
+	triggerCmd.Flags().StringVar(&delivery.deadLetter, "dead-letter", "", "Component to send undelivered events to")
 	cobra.CheckErr(triggerCmd.MarkFlagRequired("target"))
 
 	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("name", cobra.NoFileCompletions))
+	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("cesql", cobra.NoFileCompletions))
 	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("source", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
 		return completion.ListSources(o.Manifest), cobra.ShellCompDirectiveNoFileComp
 	}))
@@ -61,25 +97,40 @@ func (o *CliOptions) newTriggerCmd() *cobra.Command {
 	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("target", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
 		return completion.ListTargets(o.Manifest), cobra.ShellCompDirectiveNoFileComp
 	}))
+	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("dead-letter", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return completion.ListTargets(o.Manifest), cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("match", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{tmbroker.MatchAll, tmbroker.MatchAny}, cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("backoff-policy", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{string(eventingduckv1.BackoffPolicyLinear), string(eventingduckv1.BackoffPolicyExponential)}, cobra.ShellCompDirectiveNoFileComp
+	}))
 	return triggerCmd
 }
 
-func (o *CliOptions) trigger(name string, rawFilter string, eventSourcesFilter, eventTypesFilter []string, target string) error {
-	var filters []*eventingbroker.Filter
-	if rawFilter != "" {
-		var filter eventingbroker.Filter
-		if err := json.Unmarshal([]byte(rawFilter), &filter); err != nil {
-			return fmt.Errorf("cannot decode filter JSON %q: %w", rawFilter, err)
-		}
-		filters = []*eventingbroker.Filter{&filter}
-	} else {
-		et, err := o.translateEventSource(eventSourcesFilter)
-		if err != nil {
+// trigger creates a trigger for every event type with the filter expressions
+// appended to the type filter, or a single trigger if types are not set.
+func (o *CliOptions) trigger(name string, filter filterOptions, eventSourcesFilter, eventTypesFilter []string, target string, delivery deliveryOptions) error {
+	expressions, err := tmbroker.ComposeFilters(filter.attributes, filter.match, filter.exclude, filter.raw)
+	if err != nil {
+		return err
+	}
+	if filter.cesql != "" {
+		if err := tmbroker.ValidateCESQL(filter.cesql); err != nil {
 			return err
 		}
-		for _, eventTypes := range append(eventTypesFilter, et...) {
-			filters = append(filters, tmbroker.FilterAttribute("type", eventTypes))
-		}
+	}
+	et, err := o.translateEventSource(eventSourcesFilter)
+	if err != nil {
+		return err
+	}
+	var filters [][]eventingbroker.Filter
+	for _, eventType := range append(eventTypesFilter, et...) {
+		filters = append(filters, append(typeFilter(eventType), expressions...))
+	}
+	if len(filters) == 0 && (len(expressions) != 0 || filter.cesql != "") {
+		filters = append(filters, expressions)
 	}
 
 	component, err := components.GetObject(target, o.Config, o.Manifest, o.CRD)
@@ -90,20 +141,28 @@ func (o *CliOptions) trigger(name string, rawFilter string, eventSourcesFilter,
 		return fmt.Errorf("%q is not an event target", target)
 	}
 
+	setDelivery, err := o.triggerDelivery(delivery)
+	if err != nil {
+		return err
+	}
+	setCESQL := func(t *tmbroker.Trigger) {
+		t.CESQL = filter.cesql
+	}
+
 	log.Println("Creating trigger")
 	if len(filters) == 0 {
-		if _, err = o.createTrigger(name, component, nil); err != nil {
+		if _, err = o.createTrigger(name, component, nil, setDelivery, setCESQL); err != nil {
 			return err
 		}
 	}
 
 	oldTriggers := o.listTriggers(name + "-")
-	for i, filter := range filters {
+	for i, triggerFilters := range filters {
 		newTrigger := name
 		if name != "" {
 			newTrigger = fmt.Sprintf("%s-%d", name, i+1)
 		}
-		if _, err = o.createTrigger(newTrigger, component, filter); err != nil {
+		if _, err = o.createTrigger(newTrigger, component, triggerFilters, setDelivery, setCESQL); err != nil {
 			return err
 		}
 		delete(oldTriggers, newTrigger)
@@ -120,6 +179,51 @@ func (o *CliOptions) trigger(name string, rawFilter string, eventSourcesFilter,
 	return nil
 }
 
+// triggerDelivery validates the delivery options and returns the function that sets them on the trigger.
+func (o *CliOptions) triggerDelivery(options deliveryOptions) (func(*tmbroker.Trigger), error) {
+	if options.retry < 0 && options.backoffPolicy == "" && options.backoffDelay == "" && options.deadLetter == "" {
+		return func(*tmbroker.Trigger) {}, nil
+	}
+	delivery := &eventingduckv1.DeliverySpec{}
+	if options.retry >= 0 {
+		delivery.Retry = &options.retry
+	}
+	if options.backoffPolicy != "" {
+		policy := eventingduckv1.BackoffPolicyType(options.backoffPolicy)
+		delivery.BackoffPolicy = &policy
+	}
+	if options.backoffDelay != "" {
+		delivery.BackoffDelay = &options.backoffDelay
+	}
+	var deadLetter triggermesh.Component
+	if options.deadLetter != "" {
+		component, err := components.GetObject(options.deadLetter, o.Config, o.Manifest, o.CRD)
+		if err != nil || component == nil {
+			return nil, fmt.Errorf("dead letter sink %q not found", options.deadLetter)
+		}
+		if _, ok := component.(triggermesh.Consumer); !ok {
+			return nil, fmt.Errorf("%q is not an event target", options.deadLetter)
+		}
+		deadLetter = component
+		delivery.DeadLetterSink = &duckv1.Destination{
+			Ref: &duckv1.KReference{
+				Kind:       component.GetKind(),
+				Name:       component.GetName(),
+				APIVersion: component.GetAPIVersion(),
+			},
+		}
+	}
+	if err := delivery.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("delivery options: %w", err)
+	}
+	return func(t *tmbroker.Trigger) {
+		t.Delivery = delivery
+		if deadLetter != nil {
+			t.SetDeadLetter(deadLetter)
+		}
+	}, nil
+}
+
 func (o *CliOptions) listTriggers(prefix string) map[string]*tmbroker.Trigger {
 	result := make(map[string]*tmbroker.Trigger, 0)
 	for _, v := range o.Manifest.Objects {
@@ -23,7 +23,6 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/docker/docker/client"
 	"github.com/spf13/cobra"
 
 	"github.com/triggermesh/tmctl/cmd/brokers"
@@ -89,6 +88,12 @@ func (o *CliOptions) deleteBroker(broker string) error {
 	if err := oo.deleteComponents([]string{}, true); err != nil {
 		return fmt.Errorf("deleting component: %w", err)
 	}
+	if err := oo.deleteManagedRedis(broker); err != nil {
+		return fmt.Errorf("deleting redis: %w", err)
+	}
+	if err := oo.deleteNetwork(broker); err != nil {
+		return fmt.Errorf("deleting network: %w", err)
+	}
 	if err := os.RemoveAll(filepath.Join(oo.Config.ConfigHome, broker)); err != nil {
 		return fmt.Errorf("delete broker %q: %v", broker, err)
 	}
@@ -98,11 +103,17 @@ func (o *CliOptions) deleteBroker(broker string) error {
 	return nil
 }
 
+// DeleteComponents removes the components along with their containers,
+// external resources, triggers and secrets.
+func (o *CliOptions) DeleteComponents(names []string) error {
+	return o.deleteComponents(names, false)
+}
+
 func (o *CliOptions) deleteComponents(names []string, deleteBroker bool) error {
 	ctx := context.Background()
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return fmt.Errorf("docker client: %w", err)
+		return fmt.Errorf("container runtime: %w", err)
 	}
 	for _, object := range o.Manifest.Objects {
 		if object.Kind == "Secret" {
@@ -111,7 +122,7 @@ func (o *CliOptions) deleteComponents(names []string, deleteBroker bool) error {
 			continue
 		}
 		if deleteBroker {
-			o.deleteEverything(ctx, object, client)
+			o.deleteEverything(ctx, object, runtime)
 			continue
 		}
 		skip := true
@@ -128,12 +139,12 @@ func (o *CliOptions) deleteComponents(names []string, deleteBroker bool) error {
 			log.Printf("use \"tmctl delete --broker %s\" to delete the broker. Skipping", object.Metadata.Name)
 			continue
 		}
-		o.deleteEverything(ctx, object, client)
+		o.deleteEverything(ctx, object, runtime)
 	}
 	return nil
 }
 
-func (o *CliOptions) deleteEverything(ctx context.Context, object kubernetes.Object, client *client.Client) {
+func (o *CliOptions) deleteEverything(ctx context.Context, object kubernetes.Object, runtime docker.Runtime) {
 	log.Printf("Deleting %q %s", object.Metadata.Name, strings.ToLower(object.Kind))
 	if object.Kind == tmbroker.BrokerKind {
 		object.Metadata.Name = object.Metadata.Name + "-broker"
@@ -142,7 +153,10 @@ func (o *CliOptions) deleteEverything(ctx context.Context, object kubernetes.Obj
 		log.Printf("WARNING: external services are not deleted: %v", err)
 	}
 	// not all components are runnable, but removeContainer should try to stop it anyway
-	_ = o.removeContainer(ctx, object.Metadata.Name, client)
+	_ = o.removeContainer(ctx, object.Metadata.Name, runtime)
+	if err := tmbroker.ReleaseHostPort(o.Config.ConfigHome, o.Config.Context, object.Metadata.Name); err != nil {
+		log.Printf("WARNING: host port is not released: %v", err)
+	}
 	o.removeObject(object.Metadata.Name)
 	o.cleanupTriggers(object.Metadata.Name)
 	o.cleanupSecrets(object.Metadata.Name)
@@ -169,8 +183,32 @@ func (o *CliOptions) removeObject(component string) {
 	}
 }
 
-func (o *CliOptions) removeContainer(ctx context.Context, name string, client *client.Client) error {
-	return docker.ForceStop(ctx, name, client)
+// deleteManagedRedis removes the Redis container and the data volume
+// of the broker with the managed Redis backend.
+func (o *CliOptions) deleteManagedRedis(broker string) error {
+	redis, err := tmbroker.LoadManagedRedis(o.Config.ConfigHome, broker)
+	if err != nil || redis == nil {
+		return err
+	}
+	runtime, err := docker.NewRuntime()
+	if err != nil {
+		return fmt.Errorf("container runtime: %w", err)
+	}
+	log.Printf("Deleting %q redis and its data", broker)
+	return redis.Remove(context.Background(), runtime)
+}
+
+// deleteNetwork removes the Docker network of the broker components.
+func (o *CliOptions) deleteNetwork(broker string) error {
+	runtime, err := docker.NewRuntime()
+	if err != nil {
+		return fmt.Errorf("container runtime: %w", err)
+	}
+	return runtime.RemoveNetwork(context.Background(), docker.NetworkName(broker))
+}
+
+func (o *CliOptions) removeContainer(ctx context.Context, name string, runtime docker.Runtime) error {
+	return docker.ForceStop(ctx, name, runtime)
 }
 
 func (o *CliOptions) cleanupTriggers(target string) {
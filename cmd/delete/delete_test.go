@@ -0,0 +1,123 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delete
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/docker/fake"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/test"
+)
+
+var containers = []string{"foo-broker", "sockeye", "foo-transformation"}
+
+// setup copies the fixture broker without the AWS source to the "foo" broker
+// directory and creates the containers of its components in the runtime.
+func setup(t *testing.T) (*fake.Runtime, *CliOptions) {
+	runtime, c := fake.Setup(t)
+	c.Context = "foo"
+	assert.NoError(t, c.Save())
+	brokerConfig, err := tmbroker.CreateBrokerConfig(c.ConfigHome, c.Context)
+	assert.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(test.ConfigBase(), triggermesh.BrokerConfigFile))
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(brokerConfig, data, 0644))
+
+	data, err = os.ReadFile(test.Manifest())
+	assert.NoError(t, err)
+	m := manifest.New(filepath.Join(c.ConfigHome, c.Context, triggermesh.ManifestFile))
+	assert.NoError(t, os.WriteFile(m.Path, data, 0644))
+	assert.NoError(t, m.Read())
+	assert.NoError(t, m.Remove("foo-awss3source", "AWSS3Source"))
+	assert.NoError(t, m.Remove("foo-awss3source-secret", "Secret"))
+
+	ctx := context.Background()
+	network := docker.NetworkName(c.Context)
+	assert.NoError(t, runtime.EnsureNetwork(ctx, network))
+	for _, name := range containers {
+		_, err := runtime.CreateContainer(ctx, name, &container.Config{}, &container.HostConfig{NetworkMode: container.NetworkMode(network)})
+		assert.NoError(t, err)
+		_, err = tmbroker.HostPort(c.ConfigHome, c.Context, name)
+		assert.NoError(t, err)
+	}
+	return runtime, &CliOptions{
+		Config:   c,
+		Manifest: m,
+		CRD:      test.CRD(),
+	}
+}
+
+func TestDeleteComponents(t *testing.T) {
+	runtime, o := setup(t)
+	assert.NoError(t, o.DeleteComponents([]string{"sockeye", "foo"}))
+
+	// broker is deleted with the --broker flag only
+	assert.Equal(t, []string{"sockeye"}, runtime.CallsOf("remove"))
+	assert.Nil(t, runtime.Container("sockeye"))
+	assert.NotNil(t, runtime.Container("foo-broker"))
+
+	ports, err := tmbroker.LoadHostPorts(o.Config.ConfigHome, "foo")
+	assert.NoError(t, err)
+	assert.NotContains(t, ports, "sockeye")
+	assert.Contains(t, ports, "foo-transformation")
+
+	m := manifest.New(o.Manifest.Path)
+	assert.NoError(t, m.Read())
+	var names []string
+	for _, object := range m.Objects {
+		names = append(names, object.Metadata.Name)
+	}
+	// trigger targeting the deleted service is removed too
+	assert.ElementsMatch(t, []string{"foo", "foo-transformation", "foo-trigger-6ada801c"}, names)
+
+	triggers, err := tmbroker.GetTargetTriggers("sockeye", "foo", o.Config.ConfigHome)
+	assert.NoError(t, err)
+	assert.Empty(t, triggers)
+}
+
+func TestDeleteBroker(t *testing.T) {
+	runtime, o := setup(t)
+	redis, err := tmbroker.NewManagedRedis(o.Config.ConfigHome, "foo")
+	assert.NoError(t, err)
+	_, err = runtime.CreateContainer(context.Background(), redis.ContainerName(), &container.Config{}, &container.HostConfig{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, o.deleteBroker("foo"))
+
+	assert.Empty(t, runtime.Containers)
+	assert.Empty(t, runtime.Networks)
+	assert.Equal(t, []string{redis.Volume()}, runtime.CallsOf("remove-volume"))
+	assert.Equal(t, []string{docker.NetworkName("foo")}, runtime.CallsOf("remove-network"))
+
+	_, err = os.Stat(filepath.Join(o.Config.ConfigHome, "foo"))
+	assert.True(t, os.IsNotExist(err))
+
+	context, err := config.Get("context")
+	assert.NoError(t, err)
+	assert.Empty(t, context)
+}
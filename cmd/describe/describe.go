@@ -21,17 +21,21 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"text/tabwriter"
+	"time"
 
-	kyaml "sigs.k8s.io/yaml"
-
+	"github.com/docker/docker/api/types"
+	"github.com/docker/go-connections/nat"
 	"github.com/spf13/cobra"
 
-	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 
 	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/metrics"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
 	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
@@ -44,12 +48,24 @@ const (
 	successColorCode = "\033[92m"
 	defaultColorCode = "\033[39m"
 	offlineColorCode = "\033[31m"
+	warningColorCode = "\033[33m"
+)
+
+const (
+	// watchInterval is the period of the describe output refresh.
+	watchInterval = 2 * time.Second
+
+	mainPort    = nat.Port("8080/tcp")
+	metricsPort = nat.Port(metrics.Port + "/tcp")
 )
 
 type CliOptions struct {
 	Config   *config.Config
 	Manifest *manifest.Manifest
 	CRD      map[string]crd.CRD
+
+	Stats bool
+	Watch bool
 }
 
 func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
@@ -58,11 +74,12 @@ func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD)
 		Config:   config,
 		Manifest: m,
 	}
-	return &cobra.Command{
-		Use:     "describe [broker]",
-		Short:   "List broker components and their statuses",
-		Example: "tmctl describe",
-		Args:    cobra.RangeArgs(0, 1),
+	describeCmd := &cobra.Command{
+		Use:   "describe [broker]",
+		Short: "List broker components and their statuses",
+		Example: `tmctl describe
+tmctl describe --stats --watch`,
+		Args: cobra.RangeArgs(0, 1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
 			return []string{}, cobra.ShellCompDirectiveNoFileComp
 		},
@@ -75,9 +92,30 @@ func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD)
 					triggermesh.ManifestFile))
 			}
 			cobra.CheckErr(o.Manifest.Read())
+			if o.Watch {
+				return o.watch()
+			}
 			return o.Describe()
 		},
 	}
+	describeCmd.Flags().BoolVar(&o.Stats, "stats", false, "Show the events counters collected from the broker and the adapters metrics, queue depth is not reported")
+	describeCmd.Flags().BoolVar(&o.Watch, "watch", false, "Refresh the output every "+watchInterval.String())
+	return describeCmd
+}
+
+func (o *CliOptions) watch() error {
+	for {
+		// clear the terminal and move the cursor to the top
+		fmt.Print("\033[H\033[2J")
+		fmt.Printf("Every %s: tmctl describe %s\n\n", watchInterval, o.Config.Context)
+		if err := o.Describe(); err != nil {
+			return err
+		}
+		time.Sleep(watchInterval)
+		if err := o.Manifest.Read(); err != nil {
+			return err
+		}
+	}
 }
 
 func (o *CliOptions) Describe() error {
@@ -86,37 +124,50 @@ func (o *CliOptions) Describe() error {
 	producers := tabwriter.NewWriter(os.Stdout, 10, 5, 5, ' ', 0)
 	consumers := tabwriter.NewWriter(os.Stdout, 10, 5, 5, ' ', 0)
 	transformations := tabwriter.NewWriter(os.Stdout, 10, 5, 5, ' ', 0)
-	fmt.Fprintln(broker, "Broker\tStatus")
-	fmt.Fprintln(triggers, "Trigger\tTarget\tFilter")
-	fmt.Fprintln(transformations, "Transformation\tEventTypes\tStatus")
-	fmt.Fprintln(producers, "Source\tKind\tEventTypes\tStatus")
-	fmt.Fprintln(consumers, "Target\tKind\tExpected Events\tStatus")
+	brokerStats, adapterStats, triggerStats := "", "", ""
+	if o.Stats {
+		brokerStats, adapterStats, triggerStats = "\tIngested\tRejected", "\tSent\tFailed", "\tDelivered\tFailed"
+	}
+	fmt.Fprintln(broker, "Broker\tStatus"+brokerStats)
+	fmt.Fprintln(triggers, "Trigger\tTarget\tFilter\tDelivery"+triggerStats)
+	fmt.Fprintln(transformations, "Transformation\tEventTypes\tStatus"+adapterStats)
+	fmt.Fprintln(producers, "Source\tKind\tEventTypes\tStatus"+adapterStats)
+	fmt.Fprintln(consumers, "Target\tKind\tExpected Events\tStatus"+adapterStats)
+	var brokerMetrics *metrics.BrokerStats
+	if o.Stats {
+		brokerMetrics = o.brokerMetrics()
+	}
 	brokersPrint := false
 	triggersPrint := false
 	transformationsPrint := false
 	producersPrint := false
 	consumersPrint := false
 
+	var objects []triggermesh.Component
 	for _, object := range o.Manifest.Objects {
 		c, err := components.GetObject(object.Metadata.Name, o.Config, o.Manifest, o.CRD)
 		if err != nil {
 			return fmt.Errorf("creating component interface: %w", err)
 		}
-		if c == nil {
-			continue
+		if c != nil {
+			objects = append(objects, c)
 		}
+	}
+	states := o.probe(objects)
+	status := func(c triggermesh.Component) string {
+		return states[c.GetName()].status
+	}
+
+	for _, c := range objects {
 		if c.GetAPIVersion() == tmbroker.APIVersion {
 			switch c.GetKind() {
 			case tmbroker.BrokerKind:
 				brokersPrint = true
-				fmt.Fprintf(broker, "%s\t%s\n", c.GetName(), status(c))
+				fmt.Fprintf(broker, "%s\t%s%s\n", c.GetName(), status(c), o.brokerStats(brokerMetrics))
 			case tmbroker.TriggerKind:
-				filterString := "*"
-				if len(c.(*tmbroker.Trigger).Filters) != 0 {
-					filterString = triggerFilterToString(c.(*tmbroker.Trigger).Filters)
-				}
+				filterString := tmbroker.TriggerFiltersToString(c.(*tmbroker.Trigger).Filters, c.(*tmbroker.Trigger).CESQL)
 				triggersPrint = true
-				fmt.Fprintf(triggers, "%s\t%s\t%s\n", c.GetName(), c.(*tmbroker.Trigger).Target.Ref.Name, filterString)
+				fmt.Fprintf(triggers, "%s\t%s\t%s\t%s%s\n", c.GetName(), c.(*tmbroker.Trigger).Target.Ref.Name, filterString, deliveryToString(c.(*tmbroker.Trigger).Delivery), o.triggerStats(brokerMetrics, c.GetName()))
 			}
 			continue
 		}
@@ -133,7 +184,7 @@ func (o *CliOptions) Describe() error {
 						et = []string{"*"}
 					}
 					producersPrint = true
-					fmt.Fprintf(producers, "%s\tservice (%s)\t%s\t%s\n", c.GetName(), service.Image, strings.Join(et, ", "), status(c))
+					fmt.Fprintf(producers, "%s\tservice (%s)\t%s\t%s%s\n", c.GetName(), service.Image, strings.Join(et, ", "), status(c), states[c.GetName()].stats)
 				}
 				if service.IsTarget() {
 					et, _ := c.(triggermesh.Consumer).ConsumedEventTypes()
@@ -141,7 +192,7 @@ func (o *CliOptions) Describe() error {
 						et = []string{"*"}
 					}
 					consumersPrint = true
-					fmt.Fprintf(consumers, "%s\tservice (%s)\t%s\t%s\n", c.GetName(), service.Image, strings.Join(et, ", "), status(c))
+					fmt.Fprintf(consumers, "%s\tservice (%s)\t%s\t%s%s\n", c.GetName(), service.Image, strings.Join(et, ", "), status(c), states[c.GetName()].stats)
 				}
 			}
 			// transformation
@@ -151,7 +202,7 @@ func (o *CliOptions) Describe() error {
 					et = []string{"*"}
 				}
 				transformationsPrint = true
-				fmt.Fprintf(transformations, "%s\t%s\t%s\n", c.GetName(), strings.Join(et, ", "), status(c))
+				fmt.Fprintf(transformations, "%s\t%s\t%s%s\n", c.GetName(), strings.Join(et, ", "), status(c), states[c.GetName()].stats)
 			}
 		case pOk:
 			// source
@@ -160,7 +211,7 @@ func (o *CliOptions) Describe() error {
 				et = []string{"*"}
 			}
 			producersPrint = true
-			fmt.Fprintf(producers, "%s\t%s\t%s\t%s\n", c.GetName(), c.GetKind(), strings.Join(et, ", "), status(c))
+			fmt.Fprintf(producers, "%s\t%s\t%s\t%s%s\n", c.GetName(), c.GetKind(), strings.Join(et, ", "), status(c), states[c.GetName()].stats)
 		case cOk:
 			// target
 			et, _ := consumer.ConsumedEventTypes()
@@ -168,7 +219,7 @@ func (o *CliOptions) Describe() error {
 				et = []string{"*"}
 			}
 			consumersPrint = true
-			fmt.Fprintf(consumers, "%s\t%s\t%s\t%s\n", c.GetName(), c.GetKind(), strings.Join(et, ", "), status(c))
+			fmt.Fprintf(consumers, "%s\t%s\t%s\t%s%s\n", c.GetName(), c.GetKind(), strings.Join(et, ", "), status(c), states[c.GetName()].stats)
 		}
 	}
 	if brokersPrint {
@@ -189,47 +240,163 @@ func (o *CliOptions) Describe() error {
 	return nil
 }
 
-func status(component triggermesh.Component) string {
-	offlineStatus := fmt.Sprintf("%soffline%s", offlineColorCode, defaultColorCode)
-	if container, ok := component.(triggermesh.Runnable); ok {
-		c, err := container.Info(context.Background())
-		if err != nil || !c.Online {
-			return offlineStatus
+// componentState is the status and the events counters of the component.
+type componentState struct {
+	status string
+	stats  string
+}
+
+// probe collects the states of the runnable components concurrently.
+func (o *CliOptions) probe(objects []triggermesh.Component) map[string]componentState {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	result := make(map[string]componentState, len(objects))
+	for _, c := range objects {
+		if _, ok := c.(triggermesh.Runnable); !ok {
+			continue
 		}
-		return fmt.Sprintf("%sonline(http://localhost:%s)%s", successColorCode, c.HostPort(), defaultColorCode)
+		wg.Add(1)
+		go func(c triggermesh.Component) {
+			defer wg.Done()
+			state := componentState{status: componentStatus(c)}
+			if c.GetKind() != tmbroker.BrokerKind {
+				state.stats = o.adapterStats(c)
+			}
+			mu.Lock()
+			result[c.GetName()] = state
+			mu.Unlock()
+		}(c)
 	}
-	return offlineStatus
+	wg.Wait()
+	return result
 }
 
-func triggerFilterToString(filters []eventingbroker.Filter) string {
-	var result []string
-	for _, filter := range filters {
-		output, err := kyaml.Marshal(filter)
-		if err != nil {
+func componentStatus(component triggermesh.Component) string {
+	offlineStatus := fmt.Sprintf("%soffline%s", offlineColorCode, defaultColorCode)
+	runnable, ok := component.(triggermesh.Runnable)
+	if !ok {
+		return offlineStatus
+	}
+	ctx := context.Background()
+	c, err := runnable.Info(ctx)
+	switch {
+	case err != nil:
+		return offlineStatus
+	case c.Restarting:
+		return fmt.Sprintf("%srestarting(%d restarts)%s", warningColorCode, c.RestartCount, defaultColorCode)
+	case !c.Online:
+		return offlineStatus
+	}
+	// components are probed on the port they receive events on, sources
+	// that do not serve HTTP are probed on the metrics endpoint
+	health := c.HealthStatus(ctx, mainPort, "/")
+	if _, consumer := component.(triggermesh.Consumer); !consumer && health == types.Unhealthy && c.BoundPort(metricsPort) != "" {
+		health = c.HealthStatus(ctx, metricsPort, "/metrics")
+	}
+	color := successColorCode
+	switch health {
+	case types.Unhealthy:
+		color = offlineColorCode
+	case types.Starting:
+		color = warningColorCode
+	}
+	return fmt.Sprintf("%s%s(http://localhost:%s)%s", color, health, c.HostPort(), defaultColorCode)
+}
+
+// brokerMetrics returns the current broker counters
+// or nil if the broker metrics are not available.
+func (o *CliOptions) brokerMetrics() *metrics.BrokerStats {
+	for _, object := range o.Manifest.Objects {
+		if object.Kind != tmbroker.BrokerKind {
 			continue
 		}
-		components := strings.Split(string(output), ":")
-		prefixCondition := ""
-		if len(components) > 3 {
-			prefixCondition = strings.TrimRight(strings.TrimSpace(components[0]), ":")
-			components = components[1:]
-		}
-		if len(components) != 3 {
-			continue
+		c, err := components.GetObject(object.Metadata.Name, o.Config, o.Manifest, o.CRD)
+		if err != nil || c == nil {
+			return nil
 		}
-		condition := strings.TrimPrefix(components[0], ":\n")
-		attribute := strings.TrimRight(strings.TrimSpace(components[1]), ":")
-		value := strings.TrimRight(strings.TrimSpace(components[2]), ":")
-		switch condition {
-		case "exact":
-			result = append(result, fmt.Sprintf("%s is %s", attribute, value))
-		case "prefix":
-			result = append(result, fmt.Sprintf("%s is %s*", attribute, value))
-		case "suffix":
-			result = append(result, fmt.Sprintf("%s is *%s", attribute, value))
-		default:
-			result = append(result, fmt.Sprintf("%s is %s %s", attribute, prefixCondition, value))
+		samples, err := scrape(c)
+		if err != nil {
+			return nil
 		}
+		stats := metrics.Broker(samples)
+		return &stats
+	}
+	return nil
+}
+
+func (o *CliOptions) brokerStats(stats *metrics.BrokerStats) string {
+	if !o.Stats {
+		return ""
+	}
+	if stats == nil {
+		return "\t-\t-"
+	}
+	return fmt.Sprintf("\t%d\t%d", stats.Ingested, stats.Rejected)
+}
+
+func (o *CliOptions) triggerStats(stats *metrics.BrokerStats, trigger string) string {
+	if !o.Stats {
+		return ""
+	}
+	if stats == nil {
+		return "\t-\t-"
+	}
+	t := stats.Triggers[trigger]
+	failed := strconv.Itoa(t.Failed)
+	if t.Failed != 0 {
+		failed = fmt.Sprintf("%s%d%s", offlineColorCode, t.Failed, defaultColorCode)
+	}
+	return fmt.Sprintf("\t%d\t%s", t.Delivered, failed)
+}
+
+func (o *CliOptions) adapterStats(component triggermesh.Component) string {
+	if !o.Stats {
+		return ""
+	}
+	samples, err := scrape(component)
+	if err != nil {
+		return "\t-\t-"
+	}
+	stats := metrics.Adapter(samples)
+	return fmt.Sprintf("\t%d\t%d", stats.Sent, stats.Failed)
+}
+
+// scrape reads the metrics of the running component container.
+func scrape(component triggermesh.Component) ([]metrics.Sample, error) {
+	runnable, ok := component.(triggermesh.Runnable)
+	if !ok {
+		return nil, fmt.Errorf("component is not runnable")
+	}
+	c, err := runnable.Info(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	port := c.BoundPort(metricsPort)
+	if !c.Online || port == "" {
+		return nil, fmt.Errorf("metrics are not available")
+	}
+	return metrics.Scrape(context.Background(), port)
+}
+
+func deliveryToString(delivery *eventingduckv1.DeliverySpec) string {
+	if delivery == nil {
+		return "-"
+	}
+	var result []string
+	if delivery.Retry != nil {
+		result = append(result, fmt.Sprintf("retry: %d", *delivery.Retry))
+	}
+	if delivery.BackoffPolicy != nil {
+		result = append(result, fmt.Sprintf("backoff: %s", *delivery.BackoffPolicy))
+	}
+	if delivery.BackoffDelay != nil {
+		result = append(result, fmt.Sprintf("delay: %s", *delivery.BackoffDelay))
+	}
+	if delivery.DeadLetterSink != nil && delivery.DeadLetterSink.Ref != nil {
+		result = append(result, fmt.Sprintf("dead letter: %s", delivery.DeadLetterSink.Ref.Name))
+	}
+	if len(result) == 0 {
+		return "-"
 	}
 	return strings.Join(result, ", ")
 }
@@ -0,0 +1,129 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package describe
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/docker/fake"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	"github.com/triggermesh/tmctl/test"
+)
+
+// barrier responds OK only if the expected number of requests
+// are in flight at the same time, otherwise it fails on timeout.
+func barrier(t *testing.T, requests int) *httptest.Server {
+	var mu sync.Mutex
+	arrived := 0
+	ready := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		if arrived++; arrived == requests {
+			close(ready)
+		}
+		mu.Unlock()
+		select {
+		case <-ready:
+		case <-time.After(time.Second):
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func port(t *testing.T, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	assert.NoError(t, err)
+	return u.Port()
+}
+
+// closedPort returns the local port that does not accept connections.
+func closedPort(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	assert.NoError(t, l.Close())
+	return port(t, "http://"+l.Addr().String())
+}
+
+func TestProbe(t *testing.T) {
+	runtime, c := fake.Setup(t)
+	// containers ports are served by the test servers
+	runtime.Serve = false
+	m := manifest.New(test.Manifest())
+	assert.NoError(t, m.Read())
+	o := &CliOptions{Config: c, Manifest: m, CRD: test.CRD()}
+
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ok.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+	// consumers must be probed concurrently to pass the barrier
+	consumers := barrier(t, 2)
+
+	run := func(name string, main, metrics string) {
+		_, err := runtime.CreateContainer(context.Background(), name, &container.Config{}, &container.HostConfig{
+			PortBindings: nat.PortMap{
+				mainPort:    []nat.PortBinding{{HostPort: main}},
+				metricsPort: []nat.PortBinding{{HostPort: metrics}},
+			},
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, runtime.StartContainer(context.Background(), name))
+	}
+	run("sockeye", port(t, consumers.URL), closedPort(t))
+	run("foo-transformation", port(t, consumers.URL), closedPort(t))
+	// source does not serve HTTP on the main port
+	run("foo-awss3source", closedPort(t), port(t, ok.URL))
+
+	var objects []triggermesh.Component
+	for _, name := range []string{"foo", "foo-awss3source", "sockeye", "foo-transformation", "foo-trigger-9dad7875"} {
+		component, err := components.GetObject(name, c, m, o.CRD)
+		assert.NoError(t, err)
+		objects = append(objects, component)
+	}
+	states := o.probe(objects)
+	assert.Len(t, states, 4)
+	assert.True(t, strings.HasPrefix(states["foo"].status, offlineColorCode+"offline"), states["foo"].status)
+	for _, name := range []string{"foo-awss3source", "sockeye", "foo-transformation"} {
+		assert.True(t, strings.HasPrefix(states[name].status, successColorCode+"healthy"), "%s: %s", name, states[name].status)
+	}
+
+	// consumers are not healthy if they do not accept events on the main port
+	runtime.Container("foo-transformation").HostConfig.PortBindings = nat.PortMap{
+		mainPort:    []nat.PortBinding{{HostPort: port(t, broken.URL)}},
+		metricsPort: []nat.PortBinding{{HostPort: port(t, ok.URL)}},
+	}
+	status := componentStatus(objects[3])
+	assert.True(t, strings.HasPrefix(status, offlineColorCode+"unhealthy"), status)
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	kyaml "sigs.k8s.io/yaml"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/cmd/dump"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/load"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+	CRD      map[string]crd.CRD
+
+	From     string
+	Platform string
+	Format   string
+}
+
+type objectDiff struct {
+	Object    string               `json:"object"`
+	Operation manifest.Operation   `json:"operation"`
+	Fields    []manifest.FieldDiff `json:"fields,omitempty"`
+}
+
+func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
+	o := &CliOptions{
+		CRD:      crd,
+		Config:   config,
+		Manifest: m,
+	}
+	diffCmd := &cobra.Command{
+		Use:   "diff [broker] <-f <path/to/manifest.yaml>/<manifest URL> | -p <kubernetes|knative>> [-o json]",
+		Short: "Show differences between the local manifest and another manifest",
+		Example: `tmctl diff -f manifest.yaml
+tmctl diff -p knative`,
+		Args: cobra.RangeArgs(0, 1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			return []string{"--from", "--platform", "--output"}, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				o.Config.Context = args[0]
+				o.Manifest = manifest.New(filepath.Join(
+					o.Config.ConfigHome,
+					o.Config.Context,
+					triggermesh.ManifestFile))
+			}
+			if (o.From == "") == (o.Platform == "") {
+				return fmt.Errorf("either manifest or platform must be specified")
+			}
+			cobra.CheckErr(o.Manifest.Read())
+			return o.diff()
+		},
+	}
+	diffCmd.Flags().StringVarP(&o.From, "from", "f", "", "Manifest file or URL to compare with")
+	diffCmd.Flags().StringVarP(&o.Platform, "platform", "p", "", "Compare with the dump output for the platform. One of kubernetes, knative")
+	diffCmd.Flags().StringVarP(&o.Format, "output", "o", "text", "Output format")
+	cobra.CheckErr(diffCmd.RegisterFlagCompletionFunc("platform", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{"kubernetes", "knative"}, cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(diffCmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{"text", "json", "yaml"}, cobra.ShellCompDirectiveNoFileComp
+	}))
+	return diffCmd
+}
+
+func (o *CliOptions) diff() error {
+	var target []kubernetes.Object
+	if o.From != "" {
+		m, err := load.Manifest(o.From)
+		if err != nil {
+			return fmt.Errorf("manifest %q: %w", o.From, err)
+		}
+		target = m.Objects
+	} else {
+		objects, err := (&dump.CliOptions{
+			Config:   o.Config,
+			Manifest: o.Manifest,
+			CRD:      o.CRD,
+			Platform: o.Platform,
+		}).Objects()
+		if err != nil {
+			return fmt.Errorf("%s objects: %w", o.Platform, err)
+		}
+		target = objects
+	}
+
+	result := manifest.Diff(withoutNamespace(o.Manifest.Objects), withoutNamespace(target))
+
+	switch o.Format {
+	case "text":
+		if len(result) == 0 {
+			fmt.Println("No differences")
+		}
+		for _, d := range result {
+			fmt.Println(format(d))
+		}
+		return nil
+	case "json", "yaml":
+		output := make([]objectDiff, 0, len(result))
+		for _, d := range result {
+			output = append(output, objectDiff{
+				Object:    d.Key(),
+				Operation: d.Operation,
+				Fields:    d.Fields,
+			})
+		}
+		var res []byte
+		var err error
+		if o.Format == "json" {
+			res, err = json.MarshalIndent(output, "", "  ")
+		} else {
+			res, err = kyaml.Marshal(output)
+		}
+		if err != nil {
+			return fmt.Errorf("output format error: %w", err)
+		}
+		fmt.Println(string(res))
+		return nil
+	}
+	return fmt.Errorf("format %q is not supported", o.Format)
+}
+
+func format(d manifest.ObjectDiff) string {
+	var sign string
+	switch d.Operation {
+	case manifest.Create:
+		sign = "+"
+	case manifest.Update:
+		sign = "~"
+	case manifest.Delete:
+		sign = "-"
+	}
+	result := fmt.Sprintf("%s %s", sign, d.Key())
+	for _, f := range d.Fields {
+		result += fmt.Sprintf("\n    %s: %s -> %s", f.Path, value(f.Old), value(f.New))
+	}
+	return result
+}
+
+func value(v interface{}) string {
+	if v == nil {
+		return "<none>"
+	}
+	res, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(res)
+}
+
+// withoutNamespace resets objects namespace which is not set in the local manifest.
+func withoutNamespace(objects []kubernetes.Object) []kubernetes.Object {
+	result := make([]kubernetes.Object, len(objects))
+	for i, object := range objects {
+		object.Metadata.Namespace = ""
+		result[i] = object
+	}
+	return result
+}
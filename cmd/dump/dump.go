@@ -29,6 +29,8 @@ import (
 	"github.com/digitalocean/godo"
 	"github.com/spf13/cobra"
 
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
 	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/kubernetes"
 	"github.com/triggermesh/tmctl/pkg/manifest"
@@ -110,6 +112,42 @@ func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD)
 }
 
 func (o *CliOptions) dump(do *doOptions) error {
+	output, externalReconcilable, err := o.export(do)
+	if err != nil {
+		return err
+	}
+	res, err := o.format(output)
+	if err != nil {
+		return fmt.Errorf("output format error: %w", err)
+	}
+	fmt.Println(string(res))
+
+	if len(externalReconcilable) != 0 {
+		fmt.Fprintf(os.Stderr, "\nWARNING: manifest contains running components that use external shared resources to produce events.\n"+
+			"It is strongly recommended to stop the broker before deploying integration in the cluster to avoid events read race conditions.\n"+
+			"External resources: %s\n", strings.Join(externalReconcilable, ", "))
+	}
+	return nil
+}
+
+// Objects returns the Kubernetes objects that the dump produces for the platform.
+func (o *CliOptions) Objects() ([]kubernetes.Object, error) {
+	if o.Platform != platformKubernetes && o.Platform != platformKnative {
+		return nil, fmt.Errorf("platform %q does not produce Kubernetes objects", o.Platform)
+	}
+	output, _, err := o.export(&doOptions{})
+	if err != nil {
+		return nil, err
+	}
+	items, _ := output.([]interface{})
+	objects := make([]kubernetes.Object, 0, len(items))
+	for _, item := range items {
+		objects = append(objects, item.(kubernetes.Object))
+	}
+	return objects, nil
+}
+
+func (o *CliOptions) export(do *doOptions) (interface{}, []string, error) {
 	var externalReconcilable []string
 	var output interface{}
 	for _, object := range o.Manifest.Objects {
@@ -141,7 +179,7 @@ func (o *CliOptions) dump(do *doOptions) error {
 		}
 		if parent, ok := component.(triggermesh.Parent); ok {
 			if _, additionalEnv, err = components.ProcessSecrets(parent, o.Manifest); err != nil {
-				return fmt.Errorf("processing secrets: %v", err)
+				return nil, nil, fmt.Errorf("processing secrets: %v", err)
 			}
 		}
 
@@ -154,7 +192,7 @@ func (o *CliOptions) dump(do *doOptions) error {
 			if component.GetKind() == tmbroker.BrokerKind {
 				config, err := o.getStaticBrokerConfig()
 				if err != nil {
-					return fmt.Errorf("broker static config: %w", err)
+					return nil, nil, fmt.Errorf("broker static config: %w", err)
 				}
 				additionalEnv["BROKER_CONFIG"] = string(config)
 			}
@@ -168,10 +206,10 @@ func (o *CliOptions) dump(do *doOptions) error {
 			}
 			platformObject, err := exportable.AsDigitalOceanObject(additionalEnv)
 			if err != nil {
-				return fmt.Errorf("unable to export component %q to %q: %v", component.GetName(), o.Platform, err)
+				return nil, nil, fmt.Errorf("unable to export component %q to %q: %v", component.GetName(), o.Platform, err)
 			}
 			platformObject = injectDOInstanceSize(platformObject, do.InstanceSize)
-			if component.GetAPIVersion() == "sources.triggermesh.io/v1alpha1" {
+			if crd.IsSource(component.GetAPIVersion(), component.GetKind()) {
 				output.(map[string]interface{})["workers"] = append(output.(map[string]interface{})["workers"].([]interface{}), platformObject)
 			} else {
 				output.(map[string]interface{})["services"] = append(output.(map[string]interface{})["services"].([]interface{}), platformObject)
@@ -184,7 +222,7 @@ func (o *CliOptions) dump(do *doOptions) error {
 			if component.GetKind() == tmbroker.BrokerKind {
 				config, err := o.getStaticBrokerConfig()
 				if err != nil {
-					return fmt.Errorf("broker static config: %w", err)
+					return nil, nil, fmt.Errorf("broker static config: %w", err)
 				}
 				additionalEnv["BROKER_CONFIG"] = string(config)
 			}
@@ -195,9 +233,16 @@ func (o *CliOptions) dump(do *doOptions) error {
 			}
 			platformObject, err := exportable.AsDockerComposeObject(additionalEnv)
 			if err != nil {
-				return fmt.Errorf("unable to export component %q to %q: %v", component.GetName(), o.Platform, err)
+				return nil, nil, fmt.Errorf("unable to export component %q to %q: %v", component.GetName(), o.Platform, err)
 			}
 			output.(map[string]interface{})["services"].(map[string]interface{})[component.GetName()] = platformObject
+			if b, ok := component.(*tmbroker.Broker); ok && b.ManagedRedis() != nil {
+				redis := b.ManagedRedis()
+				output.(map[string]interface{})["services"].(map[string]interface{})[redis.ContainerName()] = redis.AsDockerComposeObject()
+				output.(map[string]interface{})["volumes"] = map[string]interface{}{
+					redis.Volume(): map[string]interface{}{},
+				}
+			}
 		case platformKnative:
 			object.Metadata.Namespace = ""
 			if output == nil {
@@ -213,21 +258,10 @@ func (o *CliOptions) dump(do *doOptions) error {
 			}
 			output = append(output.([]interface{}), object)
 		default:
-			return fmt.Errorf("platform %q is not supported", o.Platform)
+			return nil, nil, fmt.Errorf("platform %q is not supported", o.Platform)
 		}
 	}
-	res, err := o.format(output)
-	if err != nil {
-		return fmt.Errorf("output format error: %w", err)
-	}
-	fmt.Println(string(res))
-
-	if len(externalReconcilable) != 0 {
-		fmt.Fprintf(os.Stderr, "\nWARNING: manifest contains running components that use external shared resources to produce events.\n"+
-			"It is strongly recommended to stop the broker before deploying integration in the cluster to avoid events read race conditions.\n"+
-			"External resources: %s\n", strings.Join(externalReconcilable, ", "))
-	}
-	return nil
+	return output, externalReconcilable, nil
 }
 
 func (o *CliOptions) getStaticBrokerConfig() ([]byte, error) {
@@ -245,27 +279,45 @@ func (o *CliOptions) getStaticBrokerConfig() ([]byte, error) {
 		if staticBrokerConfig.Triggers == nil {
 			staticBrokerConfig.Triggers = make(map[string]tmbroker.LocalTriggerSpec, 1)
 		}
+		target := tmbroker.LocalTarget{
+			URL: o.staticURL(trigger.Target.Ref.Name),
+		}
+		if trigger.Delivery != nil {
+			target.DeliveryOptions = &eventingbroker.DeliveryOptions{
+				Retry:        trigger.Delivery.Retry,
+				BackoffDelay: trigger.Delivery.BackoffDelay,
+			}
+			if trigger.Delivery.BackoffPolicy != nil {
+				policy := eventingbroker.BackoffPolicyType(*trigger.Delivery.BackoffPolicy)
+				target.DeliveryOptions.BackoffPolicy = &policy
+			}
+			if sink := trigger.Delivery.DeadLetterSink; sink != nil && sink.Ref != nil {
+				url := o.staticURL(sink.Ref.Name)
+				target.DeliveryOptions.DeadLetterURL = &url
+			}
+		}
 		staticBrokerConfig.Triggers[trigger.Name] = tmbroker.LocalTriggerSpec{
-			Filters: trigger.Filters,
-			Target: tmbroker.LocalTarget{
-				URL: func() string {
-					switch o.Platform {
-					case platformDigitalOcean:
-						return fmt.Sprintf("${%s.PRIVATE_URL}", trigger.Target.Ref.Name)
-					case platformDockerCompose:
-						return fmt.Sprintf("http://%s:8080", trigger.Target.Ref.Name)
-					}
-					return ""
-				}(),
-			},
+			Filters: tmbroker.JoinFilters(trigger.Filters, trigger.CESQL),
+			Target:  target,
 		}
 	}
 	return json.Marshal(staticBrokerConfig)
 }
 
+// staticURL returns the component address on the platform.
+func (o *CliOptions) staticURL(component string) string {
+	switch o.Platform {
+	case platformDigitalOcean:
+		return fmt.Sprintf("${%s.PRIVATE_URL}", component)
+	case platformDockerCompose:
+		return fmt.Sprintf("http://%s:8080", component)
+	}
+	return ""
+}
+
 func (o *CliOptions) knativeEventingTransformation(object kubernetes.Object) kubernetes.Object {
-	switch object.APIVersion {
-	case tmbroker.APIVersion:
+	switch {
+	case object.APIVersion == tmbroker.APIVersion:
 		switch object.Kind {
 		case tmbroker.BrokerKind:
 			object.APIVersion = "eventing.knative.dev/v1"
@@ -278,10 +330,19 @@ func (o *CliOptions) knativeEventingTransformation(object kubernetes.Object) kub
 			if filter, set := object.Spec["filters"]; set {
 				newSpec["filters"] = filter
 			}
+			if delivery, set := object.Spec["delivery"]; set {
+				newSpec["delivery"] = delivery
+			}
 			object.APIVersion = "eventing.knative.dev/v1"
 			object.Spec = newSpec
 		}
-	case "sources.triggermesh.io/v1alpha1":
+	case crd.IsSource(object.APIVersion, object.Kind):
+		// copy the spec to keep the manifest object intact
+		spec := make(map[string]interface{}, len(object.Spec))
+		for k, v := range object.Spec {
+			spec[k] = v
+		}
+		object.Spec = spec
 		object.Spec["sink"] = map[string]interface{}{
 			"ref": map[string]interface{}{
 				"name":       o.Config.Context,
@@ -0,0 +1,147 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/output"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+)
+
+type CliOptions struct {
+	Config *config.Config
+}
+
+func NewCmd(config *config.Config) *cobra.Command {
+	filterCmd := &cobra.Command{
+		Use:   "filter",
+		Short: "Work with the trigger filters",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.HelpFunc()(cmd, args)
+		},
+	}
+	filterCmd.AddCommand(testCmd(&CliOptions{
+		Config: config,
+	}))
+	return filterCmd
+}
+
+func testCmd(o *CliOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "test [broker] <event.json>",
+		Short: "Evaluate the broker triggers against the CloudEvent",
+		Long: `Evaluate the broker triggers against the CloudEvent in JSON format
+and show the triggers that pass the event and the components that receive it.
+Use "-" to read the event from the standard input.`,
+		Example: `tmctl filter test event.json
+tmctl watch -o json | head -n 1 | tmctl filter test -`,
+		Args: cobra.RangeArgs(1, 2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			return []string{}, cobra.ShellCompDirectiveDefault
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 2 {
+				o.Config.Context = args[0]
+			}
+			return o.test(args[len(args)-1])
+		},
+	}
+}
+
+func (o *CliOptions) test(file string) error {
+	event, err := readEvent(file)
+	if err != nil {
+		return err
+	}
+	triggers, err := tmbroker.LocalTriggers(o.Config.Context, o.Config.ConfigHome)
+	if err != nil {
+		return fmt.Errorf("broker %q triggers: %w", o.Config.Context, err)
+	}
+	if len(triggers) == 0 {
+		return fmt.Errorf("broker %q does not have triggers", o.Config.Context)
+	}
+	names := make([]string, 0, len(triggers))
+	for name := range triggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 10, 5, 5, ' ', 0)
+	fmt.Fprintln(w, "Trigger\tMatch\tTarget\tFilter")
+	var receivers []string
+	for _, name := range names {
+		trigger := triggers[name]
+		filters, cesql := tmbroker.SplitFilters(trigger.Filters)
+		target := trigger.Target.Component
+		if target == "" {
+			target = trigger.Target.URL
+		}
+		match := fmt.Sprintf("%sno%s", output.OfflineColorCode, output.DefaultColorCode)
+		passed, err := tmbroker.MatchFilters(trigger.Filters, event)
+		switch {
+		case err != nil:
+			match = fmt.Sprintf("%sno (%s)%s", output.OfflineColorCode, err, output.DefaultColorCode)
+		case passed:
+			match = fmt.Sprintf("%syes%s", output.SuccessColorCode, output.DefaultColorCode)
+			receivers = append(receivers, target)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, match, target, tmbroker.TriggerFiltersToString(filters, cesql))
+	}
+	fmt.Fprintln(w)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(receivers) == 0 {
+		fmt.Printf("Event %q does not match any trigger\n", event.ID())
+		return nil
+	}
+	fmt.Printf("Event %q is delivered to: %s\n", event.ID(), strings.Join(receivers, ", "))
+	return nil
+}
+
+func readEvent(file string) (cloudevents.Event, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return cloudevents.Event{}, fmt.Errorf("reading event: %w", err)
+	}
+	event := cloudevents.NewEvent()
+	if err := json.Unmarshal(data, &event); err != nil {
+		return cloudevents.Event{}, fmt.Errorf("decoding event: %w", err)
+	}
+	if err := event.Validate(); err != nil {
+		return cloudevents.Event{}, fmt.Errorf("invalid event: %w", err)
+	}
+	return event, nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graph
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	tmgraph "github.com/triggermesh/tmctl/pkg/graph"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/output"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+	CRD      map[string]crd.CRD
+
+	Format string
+}
+
+func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
+	o := &CliOptions{
+		CRD:      crd,
+		Config:   config,
+		Manifest: m,
+	}
+	graphCmd := &cobra.Command{
+		Use:   "graph [broker] [-o tree|dot|mermaid]",
+		Short: "Show the event flow between broker components",
+		Example: `tmctl graph
+tmctl graph -o dot | dot -Tpng > broker.png`,
+		Args: cobra.RangeArgs(0, 1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			return []string{"--output"}, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				o.Config.Context = args[0]
+				o.Manifest = manifest.New(filepath.Join(
+					o.Config.ConfigHome,
+					o.Config.Context,
+					triggermesh.ManifestFile))
+			}
+			cobra.CheckErr(o.Manifest.Read())
+			return o.graph()
+		},
+	}
+	graphCmd.Flags().StringVarP(&o.Format, "output", "o", output.FormatTree, "Output format. One of tree, dot, mermaid")
+	cobra.CheckErr(graphCmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{output.FormatTree, output.FormatDOT, output.FormatMermaid}, cobra.ShellCompDirectiveNoFileComp
+	}))
+	return graphCmd
+}
+
+func (o *CliOptions) graph() error {
+	g, err := tmgraph.New(o.Manifest, o.Config, o.CRD)
+	if err != nil {
+		return fmt.Errorf("components graph: %w", err)
+	}
+	result, err := output.Draw(g, o.Format)
+	if err != nil {
+		return err
+	}
+	fmt.Print(result)
+	return nil
+}
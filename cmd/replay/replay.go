@@ -0,0 +1,135 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replay
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/wiretap"
+)
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+	CRD      map[string]crd.CRD
+
+	Target string
+	Speed  float64
+}
+
+func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
+	o := &CliOptions{
+		CRD:      crd,
+		Config:   config,
+		Manifest: m,
+	}
+	replayCmd := &cobra.Command{
+		Use:   "replay [broker] <file> [--target <name>] [--speed <factor>]",
+		Short: "Send recorded events to the broker or the component",
+		Example: `tmctl replay events.jsonl
+tmctl replay events.jsonl --target sockeye --speed 10`,
+		Args: cobra.RangeArgs(1, 2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			return []string{}, cobra.ShellCompDirectiveDefault
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 2 {
+				o.Config.Context = args[0]
+				o.Manifest = manifest.New(filepath.Join(
+					o.Config.ConfigHome,
+					o.Config.Context,
+					triggermesh.ManifestFile))
+			}
+			if o.Speed < 0 {
+				return fmt.Errorf("speed factor must not be negative")
+			}
+			cobra.CheckErr(o.Manifest.Read())
+			if o.Target == "" {
+				o.Target = o.Config.Context
+			}
+			return o.replay(args[len(args)-1])
+		},
+	}
+	replayCmd.Flags().StringVar(&o.Target, "target", "", "Component to send the events to. Default is the broker")
+	replayCmd.Flags().Float64Var(&o.Speed, "speed", 1, "Replay speed factor relative to the recorded timing, 0 sends events without delays")
+	cobra.CheckErr(replayCmd.RegisterFlagCompletionFunc("target", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return completion.ListTargets(o.Manifest), cobra.ShellCompDirectiveNoFileComp
+	}))
+	return replayCmd
+}
+
+func (o *CliOptions) replay(file string) error {
+	ctx := context.Background()
+	records, err := wiretap.ReadRecords(file)
+	if err != nil {
+		return fmt.Errorf("reading %q: %w", file, err)
+	}
+	if len(records) == 0 {
+		log.Printf("No events in %q", file)
+		return nil
+	}
+
+	component, err := components.GetObject(o.Target, o.Config, o.Manifest, o.CRD)
+	if err != nil {
+		return fmt.Errorf("destination target: %w", err)
+	}
+	consumer, ok := component.(triggermesh.Consumer)
+	if !ok {
+		return fmt.Errorf("%q is not an event consumer", o.Target)
+	}
+	port, err := consumer.GetPort(ctx)
+	if err != nil {
+		return fmt.Errorf("target port: %w", err)
+	}
+	c, err := cloudevents.NewClientHTTP()
+	if err != nil {
+		return fmt.Errorf("cloudevents client, %w", err)
+	}
+	ctx = cloudevents.ContextWithTarget(ctx, fmt.Sprintf("http://localhost:%s", port))
+
+	log.Printf("Sending %d events to %q", len(records), o.Target)
+	start := time.Now()
+	var failed int
+	for i, record := range records {
+		if o.Speed != 0 {
+			offset := record.ReceivedAt.Sub(records[0].ReceivedAt)
+			time.Sleep(time.Until(start.Add(time.Duration(float64(offset) / o.Speed))))
+		}
+		if result := c.Send(ctx, record.Event); !cloudevents.IsACK(result) {
+			failed++
+			log.Printf("Event %d (%s): %v", i+1, record.Event.ID(), result)
+		}
+	}
+	if failed != 0 {
+		return fmt.Errorf("%d of %d events were not delivered", failed, len(records))
+	}
+	log.Printf("Done. %d events delivered in %s", len(records), time.Since(start).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/spf13/cobra"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/schema"
+	"github.com/triggermesh/tmctl/pkg/wiretap"
+)
+
+// SchemasDir is the broker subdirectory with the stored event schemas.
+const SchemasDir = "schemas"
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+
+	From  string
+	Count int
+	Save  bool
+}
+
+func NewCmd(config *config.Config, m *manifest.Manifest) *cobra.Command {
+	schemaCmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Work with the event schemas",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.HelpFunc()(cmd, args)
+		},
+	}
+	schemaCmd.AddCommand(inferCmd(&CliOptions{
+		Config:   config,
+		Manifest: m,
+	}))
+	return schemaCmd
+}
+
+func inferCmd(o *CliOptions) *cobra.Command {
+	inferCmd := &cobra.Command{
+		Use:   "infer <eventType> [--from <recording>] [--count <n>] [--save]",
+		Short: "Infer JSON Schema of the event payloads",
+		Example: `tmctl schema infer com.amazon.s3.objectcreated --count 10
+tmctl schema infer com.amazon.s3.objectcreated --from events.jsonl --save`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return []string{}, cobra.ShellCompDirectiveNoFileComp
+			}
+			if err := o.Manifest.Read(); err != nil {
+				return []string{}, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completion.ListFilteredEventTypes(o.Config.Context, o.Config.ConfigHome, o.Manifest), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.infer(args[0])
+		},
+	}
+	inferCmd.Flags().StringVar(&o.From, "from", "", "Recording file created by \"tmctl watch --record\". Events are captured from the broker if not set")
+	inferCmd.Flags().IntVar(&o.Count, "count", 0, "Stop capturing after the number of events. Capture runs until interrupted if not set")
+	inferCmd.Flags().BoolVar(&o.Save, "save", false, "Store the schema in the broker configuration directory")
+	return inferCmd
+}
+
+func (o *CliOptions) infer(eventType string) error {
+	inferrer := schema.New(eventType)
+	var err error
+	if o.From != "" {
+		err = o.fromRecording(inferrer)
+	} else {
+		err = o.capture(inferrer)
+	}
+	if err != nil {
+		return err
+	}
+	if inferrer.Samples == 0 {
+		return fmt.Errorf("no JSON payloads of %q type observed", eventType)
+	}
+
+	result, err := json.MarshalIndent(inferrer.Schema(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal schema: %w", err)
+	}
+	fmt.Println(string(result))
+	if !o.Save {
+		return nil
+	}
+	dir := filepath.Join(o.Config.ConfigHome, o.Config.Context, SchemasDir)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("schemas directory: %w", err)
+	}
+	path := filepath.Join(dir, schema.FileName(eventType))
+	if err := os.WriteFile(path, result, 0o644); err != nil {
+		return fmt.Errorf("writing schema: %w", err)
+	}
+	log.Printf("Schema inferred from %d events is saved to %s", inferrer.Samples, path)
+	return nil
+}
+
+func (o *CliOptions) fromRecording(inferrer *schema.Inferrer) error {
+	records, err := wiretap.ReadRecords(o.From)
+	if err != nil {
+		return fmt.Errorf("reading %q: %w", o.From, err)
+	}
+	for _, record := range records {
+		if record.Event.Type() != inferrer.EventType {
+			continue
+		}
+		add(inferrer, record.Event)
+		if o.Count != 0 && inferrer.Samples == o.Count {
+			break
+		}
+	}
+	return nil
+}
+
+func (o *CliOptions) capture(inferrer *schema.Inferrer) error {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w, err := wiretap.New(o.Config.Context, o.Config.ConfigHome)
+	if err != nil {
+		return fmt.Errorf("wiretap: %w", err)
+	}
+	_, _ = wiretap.RemoveStaleSessions(o.Config.Context, o.Config.ConfigHome)
+	defer func() {
+		if err := w.Cleanup(ctx); err != nil {
+			log.Printf("Cleanup: %v", err)
+		}
+	}()
+
+	done := make(chan struct{})
+	var once sync.Once
+	var mutex sync.Mutex
+	stopped := false
+	if err := w.Listen(ctx, func(event cloudevents.Event) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if stopped {
+			return
+		}
+		add(inferrer, event)
+		if o.Count != 0 && inferrer.Samples >= o.Count {
+			once.Do(func() { close(done) })
+		}
+	}); err != nil {
+		return fmt.Errorf("wiretap receiver: %w", err)
+	}
+	if err := w.CreateTrigger([]eventingbroker.Filter{{
+		Exact: map[string]string{"type": inferrer.EventType},
+	}}); err != nil {
+		return fmt.Errorf("create trigger: %w", err)
+	}
+	log.Printf("Capturing %q events, press Ctrl+C to stop", inferrer.EventType)
+	select {
+	case <-c:
+	case <-done:
+	}
+	// the receiver may still be handling the events
+	mutex.Lock()
+	stopped = true
+	mutex.Unlock()
+	return nil
+}
+
+func add(inferrer *schema.Inferrer, event cloudevents.Event) {
+	if err := inferrer.Add(event.Data()); err != nil {
+		log.Printf("Skipping event %q: %v", event.ID(), err)
+	}
+}
@@ -19,11 +19,17 @@ package start
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/graph"
 	"github.com/triggermesh/tmctl/pkg/log"
 	"github.com/triggermesh/tmctl/pkg/manifest"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
@@ -38,9 +44,26 @@ type CliOptions struct {
 	Manifest *manifest.Manifest
 	CRD      map[string]crd.CRD
 
-	Restart bool
+	Restart  bool
+	Parallel int
 }
 
+// result is the outcome of the component start.
+type result struct {
+	status   string
+	duration time.Duration
+	err      error
+}
+
+const (
+	statusStarted = "started"
+	statusFailed  = "failed"
+	statusSkipped = "skipped"
+)
+
+// DefaultParallel is the default number of components started simultaneously.
+const DefaultParallel = 4
+
 func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
 	o := &CliOptions{
 		CRD:      crd,
@@ -53,7 +76,7 @@ func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD)
 		Example: "tmctl start",
 		Args:    cobra.RangeArgs(0, 1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-			return []string{"--restart", "--version"}, cobra.ShellCompDirectiveNoFileComp
+			return []string{"--restart", "--parallel", "--version"}, cobra.ShellCompDirectiveNoFileComp
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 0 {
@@ -68,82 +91,177 @@ func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD)
 		},
 	}
 	startCmd.Flags().BoolVar(&o.Restart, "restart", false, "Restart components")
+	startCmd.Flags().IntVar(&o.Parallel, "parallel", DefaultParallel, "Number of components started simultaneously")
+	cobra.CheckErr(startCmd.RegisterFlagCompletionFunc("parallel", cobra.NoFileCompletions))
 	return startCmd
 }
 
 func (o *CliOptions) start() error {
 	ctx := context.Background()
-	var brokerPort string
-	// start eventing first
-	for _, object := range o.Manifest.Objects {
-		if object.Kind == tmbroker.BrokerKind {
-			b, err := tmbroker.New(object.Metadata.Name, o.Config.Triggermesh.Broker)
-			if err != nil {
-				return fmt.Errorf("creating broker object: %w", err)
+	g, err := graph.New(o.Manifest, o.Config, o.CRD)
+	if err != nil {
+		return fmt.Errorf("components graph: %w", err)
+	}
+	if err := o.StartBroker(ctx, o.Restart); err != nil {
+		return err
+	}
+	// targets go first, then triggers, then sources
+	return o.StartComponents(ctx, g, g.Levels(), func(string) bool { return o.Restart })
+}
+
+// StartComponents starts the graph nodes level by level. Components of the same level
+// are started simultaneously, up to o.Parallel at a time. Components which dependencies
+// failed to start are skipped. Summary table is printed when all levels are processed.
+func (o *CliOptions) StartComponents(ctx context.Context, g *graph.Graph, levels [][]*graph.Node, restart func(name string) bool) error {
+	workers := o.Parallel
+	if workers < 1 {
+		workers = 1
+	}
+	results := make(map[string]result)
+	var mu sync.Mutex
+	for _, level := range levels {
+		jobs := make(chan *graph.Node)
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for node := range jobs {
+					name := node.Component.GetName()
+					started := time.Now()
+					err := o.StartComponent(ctx, node.Component, restart(name))
+					r := result{status: statusStarted, duration: time.Since(started), err: err}
+					if err != nil {
+						r.status = statusFailed
+					}
+					mu.Lock()
+					results[name] = r
+					mu.Unlock()
+				}
+			}()
+		}
+		for _, node := range level {
+			mu.Lock()
+			dependency := failedDependency(g, node, results)
+			if dependency != "" {
+				results[node.Component.GetName()] = result{
+					status: statusSkipped,
+					err:    fmt.Errorf("%q is not started", dependency),
+				}
 			}
-			log.Println("Starting broker")
-			container, err := b.(triggermesh.Runnable).Start(ctx, nil, o.Restart)
-			if err != nil {
-				return fmt.Errorf("starting broker container: %w", err)
+			mu.Unlock()
+			if dependency != "" {
+				continue
 			}
-			brokerPort = container.HostPort()
+			jobs <- node
 		}
+		close(jobs)
+		wg.Wait()
 	}
+	return printSummary(levels, results)
+}
 
-	for _, object := range o.Manifest.Objects {
-		if object.APIVersion == tmbroker.APIVersion {
-			continue
+func failedDependency(g *graph.Graph, node *graph.Node, results map[string]result) string {
+	for _, dependency := range g.Dependencies(node.Component.GetName()) {
+		if r, ok := results[dependency]; ok && r.err != nil {
+			return dependency
 		}
-		c, _ := components.GetObject(object.Metadata.Name, o.Config, o.Manifest, o.CRD)
-		if c == nil {
-			continue
-		}
-		if _, ok := c.(triggermesh.Runnable); !ok {
-			continue
-		}
-		if _, ok := c.(triggermesh.Producer); ok {
-			sink := "http://host.docker.internal:" + brokerPort
-			spec := c.GetSpec()
-			if spec == nil {
-				spec = make(map[string]interface{})
+	}
+	return ""
+}
+
+func printSummary(levels [][]*graph.Node, results map[string]result) error {
+	table := tabwriter.NewWriter(os.Stdout, 10, 5, 5, ' ', 0)
+	fmt.Fprintln(table, "Component\tStatus\tTime\tError")
+	var failed []string
+	var print bool
+	for _, level := range levels {
+		for _, node := range level {
+			if _, ok := node.Component.(triggermesh.Runnable); !ok {
+				continue
 			}
-			if service, ok := c.(*service.Service); ok && service.IsSource() {
-				spec["K_SINK"] = sink
-			} else {
-				spec["sink"] = map[string]interface{}{"uri": sink}
+			r, ok := results[node.Component.GetName()]
+			if !ok {
+				continue
 			}
-		}
-		secrets := make(map[string]string, 0)
-		if parent, ok := c.(triggermesh.Parent); ok {
-			_, secretsEnv, err := components.ProcessSecrets(parent, o.Manifest)
-			if err != nil {
-				return fmt.Errorf("processing secrets: %w", err)
+			print = true
+			var duration, errMsg string
+			if r.status != statusSkipped {
+				duration = r.duration.Round(100 * time.Millisecond).String()
 			}
-			secrets = secretsEnv
-		}
-		if reconcilable, ok := c.(triggermesh.Reconcilable); ok {
-			status, err := reconcilable.Initialize(ctx, secrets)
-			if err != nil {
-				return fmt.Errorf("external services initialization: %w", err)
+			if r.err != nil {
+				errMsg = r.err.Error()
+				failed = append(failed, node.Component.GetName())
 			}
-			reconcilable.UpdateStatus(status)
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", node.Component.GetName(), r.status, duration, errMsg)
 		}
-		log.Printf("Starting %s\n", object.Metadata.Name)
-		if _, err := c.(triggermesh.Runnable).Start(ctx, secrets, o.Restart); err != nil {
-			return fmt.Errorf("starting component %q: %w", c.GetName(), err)
-		}
-		if _, ok := c.(triggermesh.Consumer); ok {
-			triggers, err := tmbroker.GetTargetTriggers(c.GetName(), o.Config.Context, o.Config.ConfigHome)
+	}
+	if print {
+		fmt.Println()
+		table.Flush()
+	}
+	if len(failed) != 0 {
+		return fmt.Errorf("components failed to start: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// StartBroker starts the broker container of the manifest.
+func (o *CliOptions) StartBroker(ctx context.Context, restart bool) error {
+	for _, object := range o.Manifest.Objects {
+		if object.Kind == tmbroker.BrokerKind {
+			b, err := tmbroker.New(o.Config.ConfigHome, object.Metadata.Name, o.Config.Triggermesh.Broker, o.Config.Images)
 			if err != nil {
-				return fmt.Errorf("%q target triggers: %w", c.GetName(), err)
+				return fmt.Errorf("creating broker object: %w", err)
 			}
-			for _, t := range triggers {
-				t.(*tmbroker.Trigger).SetTarget(c)
-				if err := t.(*tmbroker.Trigger).WriteLocalConfig(); err != nil {
-					return fmt.Errorf("updating broker config: %w", err)
-				}
+			log.Println("Starting broker")
+			if _, err := b.(triggermesh.Runnable).Start(ctx, nil, restart); err != nil {
+				return fmt.Errorf("starting broker container: %w", err)
 			}
 		}
 	}
 	return nil
 }
+
+// StartComponent starts the runnable component, points it to the broker
+// and updates the broker configuration of the triggers targeting it.
+func (o *CliOptions) StartComponent(ctx context.Context, c triggermesh.Component, restart bool) error {
+	if _, ok := c.(triggermesh.Runnable); !ok {
+		return nil
+	}
+	if _, ok := c.(triggermesh.Producer); ok {
+		sink := tmbroker.LocalURL(o.Config.Context)
+		spec := c.GetSpec()
+		if spec == nil {
+			spec = make(map[string]interface{})
+		}
+		if service, ok := c.(*service.Service); ok && service.IsSource() {
+			spec["K_SINK"] = sink
+		} else {
+			spec["sink"] = map[string]interface{}{"uri": sink}
+		}
+	}
+	secrets := make(map[string]string, 0)
+	if parent, ok := c.(triggermesh.Parent); ok {
+		_, secretsEnv, err := components.ProcessSecrets(parent, o.Manifest)
+		if err != nil {
+			return fmt.Errorf("processing secrets: %w", err)
+		}
+		secrets = secretsEnv
+	}
+	if reconcilable, ok := c.(triggermesh.Reconcilable); ok {
+		status, err := reconcilable.Initialize(ctx, secrets)
+		if err != nil {
+			return fmt.Errorf("external services initialization: %w", err)
+		}
+		reconcilable.UpdateStatus(status)
+	}
+	log.Printf("Starting %s\n", c.GetName())
+	if _, err := c.(triggermesh.Runnable).Start(ctx, secrets, restart); err != nil {
+		return fmt.Errorf("starting component %q: %w", c.GetName(), err)
+	}
+	if err := tmbroker.UpdateComponentTriggers(o.Config.Context, o.Config.ConfigHome, c); err != nil {
+		return fmt.Errorf("updating broker config: %w", err)
+	}
+	return nil
+}
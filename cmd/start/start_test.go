@@ -0,0 +1,205 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package start
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/docker/fake"
+	"github.com/triggermesh/tmctl/pkg/graph"
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/service"
+	"github.com/triggermesh/tmctl/test"
+)
+
+// brokerManifest copies the fixture manifest without the AWS source
+// to the "foo" broker directory.
+func brokerManifest(t *testing.T, c *config.Config) *manifest.Manifest {
+	c.Context = "foo"
+	_, err := tmbroker.CreateBrokerConfig(c.ConfigHome, c.Context)
+	assert.NoError(t, err)
+	data, err := os.ReadFile(test.Manifest())
+	assert.NoError(t, err)
+	m := manifest.New(filepath.Join(c.ConfigHome, c.Context, triggermesh.ManifestFile))
+	assert.NoError(t, os.WriteFile(m.Path, data, 0644))
+	assert.NoError(t, m.Read())
+	assert.NoError(t, m.Remove("foo-awss3source", "AWSS3Source"))
+	assert.NoError(t, m.Remove("foo-awss3source-secret", "Secret"))
+	return m
+}
+
+func TestStart(t *testing.T) {
+	runtime, c := fake.Setup(t)
+	o := &CliOptions{
+		Config:   c,
+		Manifest: brokerManifest(t, c),
+		CRD:      test.CRD(),
+		Parallel: DefaultParallel,
+	}
+	assert.NoError(t, o.start())
+
+	assert.ElementsMatch(t, []string{"foo-broker", "sockeye", "foo-transformation"}, runtime.CallsOf("create"))
+	assert.Equal(t, map[string]bool{docker.NetworkName("foo"): true}, runtime.Networks)
+
+	broker := runtime.Container("foo-broker")
+	assert.Equal(t, "gcr.io/triggermesh/memory-broker:v1.1.0", broker.Config.Image)
+	assert.Equal(t, "/memory-broker", broker.Config.Entrypoint[0])
+
+	ports, err := tmbroker.LoadHostPorts(c.ConfigHome, "foo")
+	assert.NoError(t, err)
+	for _, name := range []string{"foo-broker", "sockeye", "foo-transformation"} {
+		container := runtime.Container(name)
+		assert.True(t, container.Running, name)
+		assert.Equal(t, docker.NetworkName("foo"), string(container.HostConfig.NetworkMode), name)
+		assert.Equal(t, ports[name], container.HostPort("8080/tcp"), name)
+	}
+	assert.Equal(t, tmbroker.LocalURL("foo"), runtime.Container("foo-transformation").Env()["K_SINK"])
+	assert.NotContains(t, runtime.Container("sockeye").Env(), "K_SINK")
+
+	// running containers are not recreated
+	assert.NoError(t, o.start())
+	assert.Len(t, runtime.CallsOf("create"), 3)
+
+	// running containers that do not answer the probe are recreated
+	runtime.Container("sockeye").Hang()
+	assert.NoError(t, o.start())
+	assert.Equal(t, []string{"sockeye"}, runtime.CallsOf("create")[3:])
+
+	o.Restart = true
+	assert.NoError(t, o.start())
+	assert.Len(t, runtime.CallsOf("create"), 7)
+	assert.Equal(t, 1, runtime.Container("sockeye").Starts)
+}
+
+func TestStartFailedDependency(t *testing.T) {
+	runtime, c := fake.Setup(t)
+	runtime.StartErrors["sockeye"] = errors.New("exec format error")
+	o := &CliOptions{
+		Config:   c,
+		Manifest: brokerManifest(t, c),
+		CRD:      test.CRD(),
+		Parallel: DefaultParallel,
+	}
+	err := o.start()
+	assert.EqualError(t, err, "components failed to start: sockeye, foo-transformation")
+
+	// transformation sends events to the failed target
+	assert.Nil(t, runtime.Container("foo-transformation"))
+	assert.False(t, runtime.Container("sockeye").Running)
+	assert.True(t, runtime.Container("foo-broker").Running)
+}
+
+func TestStartIndependentComponents(t *testing.T) {
+	runtime, c := fake.Setup(t)
+	m := brokerManifest(t, c)
+	for _, name := range []string{"sockeye-1", "sockeye-2"} {
+		object, err := service.New(name, "docker.io/n3wscott/sockeye:v0.7.0", "foo", service.Consumer, nil).AsK8sObject()
+		assert.NoError(t, err)
+		object.Metadata.Namespace = ""
+		m.Objects = append(m.Objects, object, kubernetes.Object{
+			APIVersion: tmbroker.APIVersion,
+			Kind:       tmbroker.TriggerKind,
+			Metadata: kubernetes.Metadata{
+				Name:   "foo-trigger-" + name,
+				Labels: map[string]string{"triggermesh.io/context": "foo"},
+			},
+			Spec: map[string]interface{}{
+				"broker": map[string]interface{}{"group": "eventing.triggermesh.io", "kind": tmbroker.BrokerKind, "name": "foo"},
+				"target": map[string]interface{}{"ref": map[string]interface{}{"apiVersion": "serving.knative.dev/v1", "kind": "Service", "name": name}},
+			},
+		})
+	}
+	runtime.StartErrors["sockeye-1"] = errors.New("exec format error")
+	o := &CliOptions{
+		Config:   c,
+		Manifest: m,
+		CRD:      test.CRD(),
+		Parallel: DefaultParallel,
+	}
+	g, err := graph.New(m, c, o.CRD)
+	assert.NoError(t, err)
+	// independent targets share the first level, their triggers share the second one
+	assert.Len(t, g.Levels()[0], 3)
+	assert.Len(t, g.Levels()[1], 3)
+
+	err = o.start()
+	assert.EqualError(t, err, "components failed to start: sockeye-1, foo-transformation")
+	assert.True(t, runtime.Container("sockeye-2").Running)
+	// transformation events may be routed to the failed target
+	assert.Nil(t, runtime.Container("foo-transformation"))
+}
+
+func TestStartTargetWithDeadLetterSink(t *testing.T) {
+	_, c := fake.Setup(t)
+	m := brokerManifest(t, c)
+	for _, name := range []string{"sockeye-1", "sockeye-2"} {
+		object, err := service.New(name, "docker.io/n3wscott/sockeye:v0.7.0", "foo", service.Consumer, nil).AsK8sObject()
+		assert.NoError(t, err)
+		object.Metadata.Namespace = ""
+		m.Objects = append(m.Objects, object)
+	}
+	// trigger config without the component addresses
+	resetTrigger := func() {
+		trigger, err := tmbroker.NewTrigger("foo-trigger-dls", "foo", c.ConfigHome, nil, nil)
+		assert.NoError(t, err)
+		trigger.(*tmbroker.Trigger).Target = duckv1.Destination{Ref: &duckv1.KReference{Name: "sockeye-1"}}
+		trigger.(*tmbroker.Trigger).Delivery = &eventingduckv1.DeliverySpec{
+			DeadLetterSink: &duckv1.Destination{Ref: &duckv1.KReference{Name: "sockeye-2"}},
+		}
+		assert.NoError(t, trigger.(*tmbroker.Trigger).WriteLocalConfig())
+	}
+
+	o := &CliOptions{
+		Config:   c,
+		Manifest: m,
+		CRD:      test.CRD(),
+		Parallel: DefaultParallel,
+	}
+	g, err := graph.New(m, c, o.CRD)
+	assert.NoError(t, err)
+	// target and its dead letter sink are started simultaneously
+	var level []string
+	for _, node := range g.Levels()[0] {
+		level = append(level, node.Component.GetName())
+	}
+	assert.Subset(t, level, []string{"sockeye-1", "sockeye-2"})
+
+	o.Restart = true
+	for i := 0; i < 10; i++ {
+		resetTrigger()
+		assert.NoError(t, o.start())
+		triggers, err := tmbroker.LocalTriggers("foo", c.ConfigHome)
+		assert.NoError(t, err)
+		target := triggers["foo-trigger-dls"].Target
+		assert.Equal(t, "http://sockeye-1:8080", target.URL)
+		if assert.NotNil(t, target.DeliveryOptions) {
+			assert.Equal(t, "http://sockeye-2:8080", *target.DeliveryOptions.DeadLetterURL)
+		}
+	}
+}
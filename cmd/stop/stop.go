@@ -25,19 +25,25 @@ import (
 
 	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/graph"
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
 	"github.com/triggermesh/tmctl/pkg/log"
 	"github.com/triggermesh/tmctl/pkg/manifest"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
 	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
 )
 
 type CliOptions struct {
 	Config   *config.Config
 	Manifest *manifest.Manifest
+	CRD      map[string]crd.CRD
 }
 
-func NewCmd(config *config.Config, m *manifest.Manifest) *cobra.Command {
+func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
 	o := &CliOptions{
+		CRD:      crd,
 		Config:   config,
 		Manifest: m,
 	}
@@ -62,22 +68,79 @@ func NewCmd(config *config.Config, m *manifest.Manifest) *cobra.Command {
 
 func (o *CliOptions) stop() error {
 	ctx := context.Background()
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return fmt.Errorf("docker client: %w", err)
+		return fmt.Errorf("container runtime: %w", err)
 	}
 
-	for _, object := range o.Manifest.Objects {
-		if object.Kind == tmbroker.TriggerKind || object.Kind == "Secret" {
+	resolved, unresolved := o.resolve()
+	g, err := graph.New(resolved, o.Config, o.CRD)
+	if err != nil {
+		log.Printf("Components graph: %v", err)
+		g, unresolved = &graph.Graph{}, containerNames(o.Manifest.Objects)
+	}
+	// objects that are not in the graph are stopped by their names
+	for _, name := range unresolved {
+		log.Printf("Stopping %s\n", name)
+		if err := docker.ForceStop(ctx, name, runtime); err != nil {
+			log.Printf("Stopping %q: %v", name, err)
+		}
+	}
+	// sources go first, broker is the last one
+	for _, node := range g.StopOrder() {
+		if node.IsTrigger() {
 			continue
 		}
-		if object.Kind == tmbroker.BrokerKind {
-			object.Metadata.Name += "-broker"
+		name := node.Component.GetName()
+		if node == g.Broker {
+			name += "-broker"
 		}
-		log.Printf("Stopping %s\n", object.Metadata.Name)
-		if err := docker.ForceStop(ctx, object.Metadata.Name, client); err != nil {
-			log.Printf("Stopping %q: %v", object.Metadata.Name, err)
+		log.Printf("Stopping %s\n", name)
+		if err := docker.ForceStop(ctx, name, runtime); err != nil {
+			log.Printf("Stopping %q: %v", name, err)
+		}
+	}
+	// managed Redis is stopped after the broker, its data volume is kept
+	if g.Broker == nil {
+		return nil
+	}
+	if b, ok := g.Broker.Component.(*tmbroker.Broker); ok && b.ManagedRedis() != nil {
+		log.Printf("Stopping %s\n", b.ManagedRedis().ContainerName())
+		if err := b.ManagedRedis().Stop(ctx, runtime); err != nil {
+			log.Printf("Stopping %q: %v", b.ManagedRedis().ContainerName(), err)
 		}
 	}
 	return nil
 }
+
+// resolve splits the manifest into the objects that can be parsed as components
+// and the container names of the objects that can not.
+func (o *CliOptions) resolve() (*manifest.Manifest, []string) {
+	resolved := manifest.New(o.Manifest.Path)
+	var unresolved []kubernetes.Object
+	for _, object := range o.Manifest.Objects {
+		if object.Kind != "Secret" {
+			if c, err := components.GetObject(object.Metadata.Name, o.Config, o.Manifest, o.CRD); err != nil || c == nil {
+				unresolved = append(unresolved, object)
+				continue
+			}
+		}
+		resolved.Objects = append(resolved.Objects, object)
+	}
+	return resolved, containerNames(unresolved)
+}
+
+// containerNames returns the names of the containers running the objects.
+func containerNames(objects []kubernetes.Object) []string {
+	var names []string
+	for _, object := range objects {
+		switch object.Kind {
+		case tmbroker.TriggerKind, "Secret":
+		case tmbroker.BrokerKind:
+			names = append(names, tmbroker.ContainerName(object.Metadata.Name))
+		default:
+			names = append(names, object.Metadata.Name)
+		}
+	}
+	return names
+}
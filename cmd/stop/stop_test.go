@@ -0,0 +1,92 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stop
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/docker/fake"
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/test"
+)
+
+// setup copies the fixture broker without the AWS source to the "foo" broker
+// directory and creates the containers of the named components in the runtime.
+func setup(t *testing.T, containers ...string) (*fake.Runtime, *CliOptions) {
+	runtime, c := fake.Setup(t)
+	c.Context = "foo"
+	_, err := tmbroker.CreateBrokerConfig(c.ConfigHome, c.Context)
+	assert.NoError(t, err)
+	data, err := os.ReadFile(test.Manifest())
+	assert.NoError(t, err)
+	m := manifest.New(filepath.Join(c.ConfigHome, c.Context, triggermesh.ManifestFile))
+	assert.NoError(t, os.WriteFile(m.Path, data, 0644))
+	assert.NoError(t, m.Read())
+	assert.NoError(t, m.Remove("foo-awss3source", "AWSS3Source"))
+	assert.NoError(t, m.Remove("foo-awss3source-secret", "Secret"))
+
+	for _, name := range containers {
+		_, err := runtime.CreateContainer(context.Background(), name, &container.Config{}, &container.HostConfig{})
+		assert.NoError(t, err)
+	}
+	return runtime, &CliOptions{
+		Config:   c,
+		Manifest: m,
+		CRD:      test.CRD(),
+	}
+}
+
+func TestStop(t *testing.T) {
+	runtime, o := setup(t, "foo-broker", "sockeye", "foo-transformation")
+	assert.NoError(t, o.stop())
+
+	assert.Empty(t, runtime.Containers)
+	// broker is stopped last
+	assert.Equal(t, "foo-broker", runtime.CallsOf("remove")[2])
+}
+
+func TestStopUnresolvedObjects(t *testing.T) {
+	runtime, o := setup(t, "foo-broker", "sockeye", "foo-transformation", "foo-unknown", "foo-unlabeled")
+	o.Manifest.Objects = append(o.Manifest.Objects,
+		kubernetes.Object{
+			APIVersion: "example.com/v1",
+			Kind:       "UnknownSource",
+			Metadata: kubernetes.Metadata{
+				Name:   "foo-unknown",
+				Labels: map[string]string{"triggermesh.io/context": "foo"},
+			},
+		},
+		kubernetes.Object{
+			APIVersion: "sources.triggermesh.io/v1alpha1",
+			Kind:       "AWSS3Source",
+			Metadata:   kubernetes.Metadata{Name: "foo-unlabeled"},
+		},
+	)
+	assert.NoError(t, o.stop())
+
+	assert.Empty(t, runtime.Containers)
+	assert.Equal(t, "foo-broker", runtime.CallsOf("remove")[4])
+}
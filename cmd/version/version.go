@@ -49,13 +49,13 @@ func NewCmd(ver, commit string, c *config.Config) *cobra.Command {
 }
 
 func dockerVersion() string {
-	client, err := docker.NewClient()
+	containerRuntime, err := docker.NewRuntime()
 	if err != nil {
 		return fmt.Sprintf("Not available (%v)", err)
 	}
-	ver, err := client.ServerVersion(context.Background())
+	ver, err := containerRuntime.Version(context.Background())
 	if err != nil {
 		return fmt.Sprintf("Not available (%v)", err)
 	}
-	return ver.Platform.Name
+	return ver
 }
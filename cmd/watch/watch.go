@@ -25,12 +25,19 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
 
 	"github.com/spf13/cobra"
 
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
 	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/log"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
 	"github.com/triggermesh/tmctl/pkg/wiretap"
 )
 
@@ -39,6 +46,13 @@ type CliOptions struct {
 
 	EventTypes string
 	Source     string
+	Attributes []string
+	Exclude    []string
+	Match      string
+	Filter     string
+	Record     string
+	Output     string
+	Template   string
 }
 
 type brokerLog struct {
@@ -51,10 +65,13 @@ type brokerLog struct {
 func NewCmd(config *config.Config) *cobra.Command {
 	o := &CliOptions{Config: config}
 	watchCmd := &cobra.Command{
-		Use:     "watch [broker]",
-		Short:   "Watch events flowing through the broker",
-		Example: "tmctl watch",
-		Args:    cobra.RangeArgs(0, 1),
+		Use:   "watch [broker]",
+		Short: "Watch events flowing through the broker",
+		Example: `tmctl watch
+tmctl watch --filter-attr source=foo-* --exclude type=*.heartbeat
+tmctl watch --record events.jsonl
+tmctl watch -o template --template '{{.type}}: {{.data}}'`,
+		Args: cobra.RangeArgs(0, 1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
 			return []string{}, cobra.ShellCompDirectiveNoFileComp
 		},
@@ -66,6 +83,19 @@ func NewCmd(config *config.Config) *cobra.Command {
 		},
 	}
 	watchCmd.Flags().StringVarP(&o.EventTypes, "eventTypes", "e", "", "Filter events based on type attribute")
+	watchCmd.Flags().StringArrayVar(&o.Attributes, "filter-attr", []string{}, "Show events with the attribute value, e.g. \"source=foo-*\". Can be repeated")
+	watchCmd.Flags().StringArrayVar(&o.Exclude, "exclude", []string{}, "Hide events with the attribute value, e.g. \"type=*.heartbeat\". Can be repeated")
+	watchCmd.Flags().StringVar(&o.Match, "match", tmbroker.MatchAll, "Show events matching all or any of the --filter-attr expressions")
+	watchCmd.Flags().StringVar(&o.Filter, "filter", "", "Raw filter JSON")
+	watchCmd.Flags().StringVar(&o.Record, "record", "", "Store received events in the file as JSON lines")
+	watchCmd.Flags().StringVarP(&o.Output, "output", "o", wiretap.OutputPretty, "Events output format. One of pretty, json, template")
+	watchCmd.Flags().StringVar(&o.Template, "template", "", "Go template for the \"template\" output format, e.g. \"{{.type}}: {{.data}}\"")
+	cobra.CheckErr(watchCmd.RegisterFlagCompletionFunc("match", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{tmbroker.MatchAll, tmbroker.MatchAny}, cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(watchCmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{wiretap.OutputPretty, wiretap.OutputJSON, wiretap.OutputTemplate}, cobra.ShellCompDirectiveNoFileComp
+	}))
 	return watchCmd
 }
 
@@ -73,41 +103,100 @@ func (o *CliOptions) watch() error {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
-	ctx := context.Background()
+	printer, err := wiretap.NewPrinter(o.Output, o.Template)
+	if err != nil {
+		return err
+	}
+	filters, err := o.filters()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	w, err := wiretap.New(o.Config.Context, o.Config.ConfigHome)
 	if err != nil {
 		return fmt.Errorf("wiretap: %w", err)
 	}
+	if removed, err := wiretap.RemoveStaleSessions(o.Config.Context, o.Config.ConfigHome); err != nil {
+		log.Printf("Stale sessions: %v", err)
+	} else if len(removed) != 0 {
+		log.Printf("Removed stale watch triggers: %s", strings.Join(removed, ", "))
+	}
 	defer func() {
 		if err := w.Cleanup(ctx); err != nil {
 			log.Printf("Cleanup: %v", err)
 		}
 	}()
+
+	var recorder *wiretap.Recorder
+	if o.Record != "" {
+		if recorder, err = wiretap.NewRecorder(o.Record); err != nil {
+			return fmt.Errorf("recording file: %w", err)
+		}
+		defer recorder.Close()
+	}
+
 	log.Println("Connecting to broker")
-	eventDisplayLogs, err := w.CreateAdapter(ctx)
-	if err != nil {
-		return fmt.Errorf("create container: %w", err)
+	var mutex sync.Mutex
+	if err := w.Listen(ctx, func(event cloudevents.Event) {
+		receivedAt := time.Now()
+		mutex.Lock()
+		defer mutex.Unlock()
+		if err := printer.Print(os.Stdout, event); err != nil {
+			log.Printf("Printing event %q: %v", event.ID(), err)
+		}
+		if recorder != nil {
+			if err := recorder.Write(event, receivedAt); err != nil {
+				log.Printf("Recording event %q: %v", event.ID(), err)
+			}
+		}
+	}); err != nil {
+		return fmt.Errorf("wiretap receiver: %w", err)
 	}
-	if err := w.CreateTrigger(strings.Split(o.EventTypes, ",")); err != nil {
+	if err := w.CreateTrigger(filters); err != nil {
 		return fmt.Errorf("create trigger: %w", err)
 	}
-	brokerLogs, err := w.BrokerLogs(ctx, o.Config.Triggermesh.Broker)
+	brokerLogs, err := w.BrokerLogs(ctx, o.Config.Triggermesh.Broker, o.Config.Images)
 	if err != nil {
 		return fmt.Errorf("broker logs: %w", err)
 	}
-	log.Println("Watching...")
+	log.Printf("Watching... (session %s)", w.Session.ID)
 	go listenBroker(brokerLogs, c)
-	go listenEvents(eventDisplayLogs, c)
 	<-c
 	log.Println("Cleaning up")
 	return nil
 }
 
-func listenEvents(output io.ReadCloser, done chan os.Signal) {
-	readLogs(output, done, func(data []byte) {
-		fmt.Println(string(data))
-	})
+// filters composes the wiretap trigger filters from the command flags.
+// Top level filters are combined with the logical AND.
+func (o *CliOptions) filters() ([]eventingbroker.Filter, error) {
+	var result []eventingbroker.Filter
+	var types []eventingbroker.Filter
+	for _, eventType := range strings.Split(o.EventTypes, ",") {
+		if eventType = strings.TrimSpace(eventType); eventType != "" {
+			types = append(types, *tmbroker.FilterAttribute("type", eventType))
+		}
+	}
+	switch len(types) {
+	case 0:
+	case 1:
+		result = append(result, types[0])
+	default:
+		result = append(result, eventingbroker.Filter{Any: types})
+	}
+
+	var raw []string
+	if o.Filter != "" {
+		raw = append(raw, o.Filter)
+	}
+	expressions, err := tmbroker.ComposeFilters(o.Attributes, o.Match, o.Exclude, raw)
+	if err != nil {
+		return nil, err
+	}
+	result = append(result, expressions...)
+	return result, nil
 }
 
 func listenBroker(output io.ReadCloser, done chan os.Signal) {
@@ -0,0 +1,28 @@
+#!/usr/bin/env bash
+
+# Copyright 2023 TriggerMesh Inc.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Replaces the CRD bundle embedded in the binary with the TriggerMesh release CRDs.
+# Default components version in pkg/config must be updated along with the bundle.
+
+set -euo pipefail
+
+VERSION=${1:?usage: $0 <triggermesh version>}
+CRD_DIR="$(dirname "$0")/../pkg/triggermesh/crd"
+
+curl -fsSL -o "${CRD_DIR}/triggermesh-crds.yaml" \
+  "https://github.com/triggermesh/triggermesh/releases/download/${VERSION}/triggermesh-crds.yaml"
+sed -i.bak "s/^const BundledVersion = .*/const BundledVersion = \"${VERSION}\"/" "${CRD_DIR}/bundle.go"
+rm "${CRD_DIR}/bundle.go.bak"
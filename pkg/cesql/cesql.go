@@ -0,0 +1,163 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cesql parses and evaluates CloudEvents SQL expressions
+// with the CloudEvents SDK implementation of the specification.
+package cesql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/antlr/antlr4/runtime/Go/antlr"
+	cesqlv2 "github.com/cloudevents/sdk-go/sql/v2"
+	"github.com/cloudevents/sdk-go/sql/v2/gen"
+	cesqlparser "github.com/cloudevents/sdk-go/sql/v2/parser"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/types"
+)
+
+// SyntaxError is the expression parsing error.
+// Position is the 1-based character offset of the offending token.
+type SyntaxError struct {
+	Position int
+	Message  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Position, e.Message)
+}
+
+// EvaluationError is the expression evaluation error.
+// Filter expression that failed to evaluate does not pass the event.
+type EvaluationError struct {
+	Message string
+}
+
+func (e *EvaluationError) Error() string {
+	return fmt.Sprintf("evaluation error: %s", e.Message)
+}
+
+// Parse returns the parsed CESQL expression. Keywords and function names
+// are case-insensitive.
+func Parse(expression string) (cesqlv2.Expression, error) {
+	if err := checkSyntax(expression); err != nil {
+		return nil, err
+	}
+	return parse(expression)
+}
+
+// parse calls the SDK parser that may panic on the expressions
+// it cannot build the tree for.
+func parse(expression string) (result cesqlv2.Expression, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, &SyntaxError{Position: 1, Message: fmt.Sprint(r)}
+		}
+	}()
+	result, err = cesqlparser.Parse(expression)
+	if err != nil {
+		return nil, &SyntaxError{Position: 1, Message: err.Error()}
+	}
+	return result, nil
+}
+
+// Match parses the expression and evaluates it against the event.
+func Match(expression string, event cloudevents.Event) (bool, error) {
+	parsed, err := Parse(expression)
+	if err != nil {
+		return false, err
+	}
+	value, err := parsed.Evaluate(event)
+	if err != nil {
+		return false, &EvaluationError{Message: err.Error()}
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, &EvaluationError{Message: fmt.Sprintf("expression result %v is not boolean", value)}
+	}
+	return result, nil
+}
+
+// AttributeValue returns the CloudEvent context attribute or the extension value.
+// Integer and boolean extensions keep their types, other values are strings.
+func AttributeValue(event cloudevents.Event, name string) (interface{}, bool) {
+	switch name {
+	case "specversion":
+		return event.SpecVersion(), true
+	case "id":
+		return event.ID(), true
+	case "source":
+		return event.Source(), true
+	case "type":
+		return event.Type(), true
+	case "subject":
+		return event.Subject(), event.Subject() != ""
+	case "time":
+		return types.FormatTime(event.Time()), !event.Time().IsZero()
+	case "dataschema":
+		return event.DataSchema(), event.DataSchema() != ""
+	case "datacontenttype":
+		return event.DataContentType(), event.DataContentType() != ""
+	}
+	value, set := event.Extensions()[name]
+	if !set {
+		return nil, false
+	}
+	switch v := value.(type) {
+	case int32, bool:
+		return v, true
+	}
+	s, err := types.Format(value)
+	if err != nil {
+		return fmt.Sprint(value), true
+	}
+	return s, true
+}
+
+// checkSyntax runs the generated lexer and parser over the expression
+// to report the position of the first syntax error, if any.
+func checkSyntax(expression string) error {
+	listener := &errorListener{expression: expression}
+	lexer := gen.NewCESQLParserLexer(cesqlparser.NewCaseChangingStream(antlr.NewInputStream(expression), true))
+	lexer.RemoveErrorListeners()
+	lexer.AddErrorListener(listener)
+	parser := gen.NewCESQLParserParser(antlr.NewCommonTokenStream(lexer, antlr.TokenDefaultChannel))
+	parser.RemoveErrorListeners()
+	parser.AddErrorListener(listener)
+	parser.Cesql()
+	if listener.err != nil {
+		return listener.err
+	}
+	return nil
+}
+
+type errorListener struct {
+	antlr.DefaultErrorListener
+	expression string
+	err        *SyntaxError
+}
+
+func (l *errorListener) SyntaxError(_ antlr.Recognizer, _ interface{}, line, column int, msg string, _ antlr.RecognitionException) {
+	if l.err != nil {
+		return
+	}
+	position := column + 1
+	for _, previous := range strings.SplitN(l.expression, "\n", line)[:line-1] {
+		position += len([]rune(previous)) + 1
+	}
+	l.err = &SyntaxError{Position: position, Message: msg}
+}
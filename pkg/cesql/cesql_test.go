@@ -0,0 +1,144 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cesql
+
+import (
+	"errors"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	valid := []string{
+		"type = 'com.example.created'",
+		`source LIKE "%/orders/%" AND NOT EXISTS subject`,
+		"type IN ('a', 'b') OR (count * 2 + 1 >= 10)",
+		"LOWER(subject) NOT LIKE 'test%' XOR sequence <> -1",
+		"CONCAT_WS('-', source, type) != 'it''s'",
+		"SUBSTRING(id, 1, 3) = 'abc' AND TRUE",
+		"type = 'foo' and not exists subject or id in ('1', '2')",
+		"lower(type) like 'foo%'",
+	}
+	for _, expression := range valid {
+		t.Run(expression, func(t *testing.T) {
+			_, err := Parse(expression)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := map[string]int{
+		"":                           1,
+		"type = ":                    8,
+		"type = 'foo":                8,
+		"type == 'foo'":              7,
+		"type LIKE foo":              11,
+		"type IN ()":                 10,
+		"(type = 'foo'":              14,
+		"type = 'foo' AND":           17,
+		"source NOT = 'bar'":         12,
+		"type = 'foo' # comment":     14,
+		"EXISTS 'type'":              8,
+		"type = 99999999999":         1,
+		"type ! 'foo'":               6,
+		"CONCAT('a', 'b' 'c') = 'd'": 17,
+		"type = 'foo'\nAND":          17,
+	}
+	for expression, position := range cases {
+		t.Run(expression, func(t *testing.T) {
+			_, err := Parse(expression)
+			var syntaxErr *SyntaxError
+			assert.True(t, errors.As(err, &syntaxErr), "expected syntax error, got %v", err)
+			if syntaxErr != nil {
+				assert.Equal(t, position, syntaxErr.Position, syntaxErr.Error())
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	event := cloudevents.NewEvent()
+	event.SetID("1234")
+	event.SetSource("/shop/orders")
+	event.SetType("com.example.order.created")
+	event.SetSubject("order.json")
+	event.SetExtension("amount", 42)
+	event.SetExtension("priority", true)
+
+	cases := map[string]struct {
+		expected bool
+		err      bool
+	}{
+		"type = 'com.example.order.created'":                  {expected: true},
+		"type <> 'com.example.order.created'":                 {expected: false},
+		"source LIKE '%/orders' AND subject LIKE '%.json'":    {expected: true},
+		"source like '%/orders' and subject not like '%.xml'": {expected: true},
+		"source NOT LIKE '/shop/%'":                           {expected: false},
+		"id LIKE '12_4' AND id LIKE '1\\_%' = FALSE":          {expected: true},
+		"type IN ('a', 'com.example.order.created')":          {expected: true},
+		"amount > 40 AND amount * 2 = 84 AND amount % 5 = 2":  {expected: true},
+		"amount = '42'":                                         {expected: true},
+		"priority AND NOT EXISTS dataschema":                    {expected: true},
+		"EXISTS region OR TRUE XOR TRUE":                        {expected: false},
+		"upper(left(subject, 5)) = 'ORDER'":                     {expected: true},
+		"SUBSTRING(source, -6) = 'orders' AND LENGTH(id) = 4":   {expected: true},
+		"CONCAT_WS('/', 'a', 'b') = 'a/b' AND ABS(-3) = 3":      {expected: true},
+		"IS_INT(id) AND INT(id) = 1234 AND NOT IS_BOOL(amount)": {expected: true},
+		"region = 'eu'":                                         {err: true},
+		"amount / 0 = 1":                                        {err: true},
+		"subject > 1":                                           {err: true},
+		"UNKNOWN(type)":                                         {err: true},
+		"type":                                                  {err: true},
+	}
+	for expression, tc := range cases {
+		t.Run(expression, func(t *testing.T) {
+			result, err := Match(expression, event)
+			if tc.err {
+				var evaluationErr *EvaluationError
+				assert.True(t, errors.As(err, &evaluationErr), "expected evaluation error, got %v", err)
+				assert.False(t, result)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestAttributeValue(t *testing.T) {
+	event := cloudevents.NewEvent()
+	event.SetID("1234")
+	event.SetExtension("amount", 42)
+	event.SetExtension("priority", true)
+	event.SetExtension("region", "eu")
+
+	for name, expected := range map[string]interface{}{
+		"id":       "1234",
+		"amount":   int32(42),
+		"priority": true,
+		"region":   "eu",
+	} {
+		value, set := AttributeValue(event, name)
+		assert.True(t, set, name)
+		assert.Equal(t, expected, value, name)
+	}
+	_, set := AttributeValue(event, "subject")
+	assert.False(t, set)
+}
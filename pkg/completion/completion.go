@@ -32,7 +32,7 @@ import (
 func ListSources(m *manifest.Manifest) []string {
 	var list []string
 	for _, object := range m.Objects {
-		if object.APIVersion == "sources.triggermesh.io/v1alpha1" ||
+		if crd.IsSource(object.APIVersion, object.Kind) ||
 			object.APIVersion == "flow.triggermesh.io/v1alpha1" {
 			list = append(list, object.Metadata.Name)
 		}
@@ -48,7 +48,7 @@ func ListSources(m *manifest.Manifest) []string {
 func ListTargets(m *manifest.Manifest) []string {
 	var list []string
 	for _, object := range m.Objects {
-		if object.APIVersion == "targets.triggermesh.io/v1alpha1" ||
+		if crd.IsTarget(object.APIVersion, object.Kind) ||
 			object.APIVersion == "flow.triggermesh.io/v1alpha1" {
 			list = append(list, object.Metadata.Name)
 		}
@@ -0,0 +1,177 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// BrokerConfigFile is the broker settings file in the broker directory.
+	BrokerConfigFile = "config.yaml"
+
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// LocalBrokerConfig is the broker settings that override
+// the global broker configuration for a single broker.
+type LocalBrokerConfig struct {
+	Backend string               `yaml:"backend,omitempty"`
+	Version string               `yaml:"version,omitempty"`
+	Memory  InMemoryBrokerConfig `yaml:"memory,omitempty"`
+	Redis   RedisBrokerConfig    `yaml:"redis,omitempty"`
+}
+
+// LoadLocalBrokerConfig reads the broker settings, empty settings
+// are returned if the broker does not have the config file.
+func LoadLocalBrokerConfig(configHome, broker string) (LocalBrokerConfig, error) {
+	var c LocalBrokerConfig
+	data, err := os.ReadFile(filepath.Join(configHome, broker, BrokerConfigFile))
+	if os.IsNotExist(err) {
+		return c, nil
+	} else if err != nil {
+		return c, fmt.Errorf("read broker config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("decode broker config: %w", err)
+	}
+	return c, nil
+}
+
+// Save writes the broker settings to the broker directory.
+func (c LocalBrokerConfig) Save(configHome, broker string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(configHome, broker, BrokerConfigFile), data, 0644)
+}
+
+// Merge returns the global broker configuration overridden by the broker settings.
+// Backend is inherited from the global configuration if it is not set.
+func (c LocalBrokerConfig) Merge(global BrokerConfig) BrokerConfig {
+	result := global
+	if c.Version != "" {
+		result.Version = c.Version
+	}
+	backend := c.Backend
+	if backend == "" {
+		backend = BackendRedis
+		if global.Memory != nil || global.Redis == nil {
+			backend = BackendMemory
+		}
+	}
+	switch backend {
+	case BackendMemory:
+		memory := InMemoryBrokerConfig{
+			BufferSize:     defaultMemoryBufferSize,
+			ProduceTimeout: defaultProduceTimeout,
+		}
+		if global.Memory != nil {
+			memory = *global.Memory
+		}
+		if c.Memory.BufferSize != "" {
+			memory.BufferSize = c.Memory.BufferSize
+		}
+		if c.Memory.ProduceTimeout != "" {
+			memory.ProduceTimeout = c.Memory.ProduceTimeout
+		}
+		result.Memory, result.Redis = &memory, nil
+	case BackendRedis:
+		var redis RedisBrokerConfig
+		if global.Redis != nil {
+			redis = *global.Redis
+		}
+		if c.Redis.Address != "" {
+			redis.Address = c.Redis.Address
+		}
+		if c.Redis.Username != "" {
+			redis.Username = c.Redis.Username
+		}
+		if c.Redis.Password != "" {
+			redis.Password = c.Redis.Password
+		}
+		result.Memory, result.Redis = nil, &redis
+	}
+	return result
+}
+
+// BrokerVersion returns the broker version set in the broker settings
+// or the global broker version if the broker does not override it.
+func BrokerVersion(configHome, broker string) (string, error) {
+	local, err := LoadLocalBrokerConfig(configHome, broker)
+	if err != nil {
+		return "", err
+	}
+	if local.Version != "" {
+		return local.Version, nil
+	}
+	c, err := loadDefaultConfig()
+	if err != nil {
+		return "", fmt.Errorf("unable to load config: %w", err)
+	}
+	return c.Triggermesh.Broker.Version, nil
+}
+
+// GetBroker reads the broker settings value.
+func GetBroker(broker, key string) (string, error) {
+	c, err := loadBrokerConfig(broker)
+	if err != nil {
+		return "", err
+	}
+	if key == "" {
+		out, err := yaml.Marshal(c)
+		if err != nil {
+			return "", err
+		}
+		return string(out), nil
+	}
+	return readValue(strings.Split(key, "."), reflect.TypeOf(c), reflect.ValueOf(c)), nil
+}
+
+// SetBroker writes the broker settings value.
+func SetBroker(broker, key, value string) error {
+	c, err := loadBrokerConfig(broker)
+	if err != nil {
+		return err
+	}
+	if key == "backend" && value != BackendMemory && value != BackendRedis && value != "" {
+		return fmt.Errorf("unsupported broker backend %q, expected %q or %q", value, BackendMemory, BackendRedis)
+	}
+	if err := setValue(strings.Split(key, "."), value, reflect.TypeOf(c), reflect.ValueOf(&c).Elem()); err != nil {
+		if errors.Is(err, errNotSettable) {
+			return fmt.Errorf("%q %w, edit %s", key, err, filepath.Join(HomeAbsPath(), broker, BrokerConfigFile))
+		}
+		return err
+	}
+	return c.Save(HomeAbsPath(), broker)
+}
+
+func loadBrokerConfig(broker string) (LocalBrokerConfig, error) {
+	if _, err := os.Stat(filepath.Join(HomeAbsPath(), broker)); err != nil {
+		return LocalBrokerConfig{}, fmt.Errorf("broker %q does not exist", broker)
+	}
+	return LoadLocalBrokerConfig(HomeAbsPath(), broker)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalBrokerConfigMerge(t *testing.T) {
+	memoryGlobal := BrokerConfig{
+		Version: "v1.0.0",
+		Memory:  &InMemoryBrokerConfig{BufferSize: "100", ProduceTimeout: "1s"},
+	}
+	redisGlobal := BrokerConfig{
+		Version: "v1.0.0",
+		Redis:   &RedisBrokerConfig{Address: "redis:6379", Username: "default", Password: "secret"},
+	}
+
+	cases := map[string]struct {
+		local    LocalBrokerConfig
+		global   BrokerConfig
+		expected BrokerConfig
+	}{
+		"empty settings": {
+			global:   memoryGlobal,
+			expected: memoryGlobal,
+		},
+		"memory overrides": {
+			local: LocalBrokerConfig{
+				Version: "v1.1.0",
+				Memory:  InMemoryBrokerConfig{BufferSize: "500"},
+			},
+			global: memoryGlobal,
+			expected: BrokerConfig{
+				Version: "v1.1.0",
+				Memory:  &InMemoryBrokerConfig{BufferSize: "500", ProduceTimeout: "1s"},
+			},
+		},
+		"memory backend over redis": {
+			local:  LocalBrokerConfig{Backend: BackendMemory},
+			global: redisGlobal,
+			expected: BrokerConfig{
+				Version: "v1.0.0",
+				Memory:  &InMemoryBrokerConfig{BufferSize: defaultMemoryBufferSize, ProduceTimeout: defaultProduceTimeout},
+			},
+		},
+		"redis backend over memory": {
+			local: LocalBrokerConfig{
+				Backend: BackendRedis,
+				Redis:   RedisBrokerConfig{Address: "localhost:6379"},
+			},
+			global: memoryGlobal,
+			expected: BrokerConfig{
+				Version: "v1.0.0",
+				Redis:   &RedisBrokerConfig{Address: "localhost:6379"},
+			},
+		},
+		"redis address override": {
+			local:  LocalBrokerConfig{Redis: RedisBrokerConfig{Address: "localhost:6379"}},
+			global: redisGlobal,
+			expected: BrokerConfig{
+				Version: "v1.0.0",
+				Redis:   &RedisBrokerConfig{Address: "localhost:6379", Username: "default", Password: "secret"},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.local.Merge(tc.global))
+		})
+	}
+	// global configuration must stay intact
+	assert.Equal(t, "100", memoryGlobal.Memory.BufferSize)
+	assert.Equal(t, "redis:6379", redisGlobal.Redis.Address)
+}
+
+func TestBrokerSettings(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	assert.Error(t, SetBroker("foo", "backend", BackendRedis))
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(HomeAbsPath(), "foo"), os.ModePerm))
+	assert.NoError(t, SetBroker("foo", "backend", BackendRedis))
+	assert.NoError(t, SetBroker("foo", "redis.address", "localhost:6379"))
+	assert.NoError(t, SetBroker("foo", "memory.buffer-size", "50"))
+	assert.Error(t, SetBroker("foo", "backend", "kafka"))
+
+	value, err := GetBroker("foo", "redis.address")
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost:6379", value)
+
+	c, err := LoadLocalBrokerConfig(HomeAbsPath(), "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, BackendRedis, c.Backend)
+	assert.Equal(t, "50", c.Memory.BufferSize)
+}
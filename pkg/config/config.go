@@ -18,13 +18,16 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -34,13 +37,18 @@ const (
 	defaultConfigFile = "config.yaml"
 	defaultContext    = ""
 
-	defaultTmVersion     = "v1.23.0"
+	// Components version must match the bundled CRD version
+	defaultTmVersion     = "v1.23.2"
 	defaultBrokerVersion = "v1.1.0"
 
 	defaultDockerTimeout = "5s"
+	// Restart policy of the components containers
+	defaultRestartPolicy = "on-failure"
 
 	MemoryBrokerImage = "gcr.io/triggermesh/memory-broker"
 	RedisBrokerImage  = "gcr.io/triggermesh/redis-broker"
+	// Redis image of the broker managed backend
+	ManagedRedisImage = "redis:7.0-alpine"
 
 	// In-memory broker params
 	defaultMemoryBufferSize = "100"
@@ -50,6 +58,24 @@ const (
 
 	// TriggerMesh DockerHub Registry
 	DockerRegistry = "triggermesh"
+
+	// Roles of the user-defined component kinds
+	SourceRole = "source"
+	TargetRole = "target"
+
+	// OfflineEnv enables the offline mode regardless of the config value.
+	OfflineEnv = "TMCTL_OFFLINE"
+)
+
+var (
+	// releasesURL is the latest release endpoint of the TriggerMesh projects.
+	releasesURL = "https://api.github.com/repos/triggermesh/%s/releases/latest"
+	// releasesClient gives up on the latest release lookup quickly
+	// so that the config creation does not stall without the internet access.
+	releasesClient = &http.Client{Timeout: 3 * time.Second}
+
+	// errNotSettable is returned for the keys that cannot be set with a single value.
+	errNotSettable = errors.New("key is not settable")
 )
 
 type Config struct {
@@ -60,15 +86,51 @@ type Config struct {
 	Context     string   `yaml:"context"`
 	Triggermesh TmConfig `yaml:"triggermesh"`
 	Docker      Docker   `yaml:"docker"`
+	// Offline mode disables the network requests: versions are not resolved,
+	// CRDs are read from the cache or the bundle, images are not pulled.
+	Offline bool   `yaml:"offline,omitempty"`
+	Images  Images `yaml:"images,omitempty"`
+}
+
+// Images are the components images settings.
+type Images struct {
+	// Mirror replaces the registry of the default components images,
+	// e.g. "registry.example.com/mirror" turns "gcr.io/triggermesh/memory-broker"
+	// into "registry.example.com/mirror/triggermesh/memory-broker"
+	Mirror string `yaml:"mirror,omitempty"`
+	// Images of the components by their kinds, e.g. "AWSS3Source", "MemoryBroker",
+	// "RedisBroker" or "Redis" for the managed broker backend. Take precedence over the mirror
+	Overrides map[string]string `yaml:"overrides,omitempty"`
 }
 
 type Docker struct {
-	StartTimeout string `yaml:"timeout"`
+	StartTimeout  string `yaml:"timeout"`
+	RestartPolicy string `yaml:"restart-policy,omitempty"`
+	// Container runtime, "docker" or "podman". Docker is used if not set
+	Runtime string `yaml:"runtime,omitempty"`
+	// Runtime API socket path, the runtime default is used if not set
+	Socket string `yaml:"socket,omitempty"`
 }
 
 type TmConfig struct {
 	ComponentsVersion string       `yaml:"version"`
 	Broker            BrokerConfig `yaml:"broker"`
+	// Additional CRD files with the user-defined component kinds,
+	// relative paths are resolved from the config directory
+	CRDSources []string `yaml:"crd-sources,omitempty"`
+	// Adapters of the user-defined component kinds
+	Kinds map[string]CustomKind `yaml:"kinds,omitempty"`
+}
+
+// CustomKind is the adapter of the user-defined component kind.
+type CustomKind struct {
+	Image string `yaml:"image"`
+	// Role is either "source" or "target", kinds of the API groups
+	// that start with "sources." or "targets." may omit it
+	Role string `yaml:"role,omitempty"`
+	// Adapter environment variables mapped to the dot-separated spec paths.
+	// Top-level spec fields are passed as upper snake case variables if not set
+	Env map[string]string `yaml:"env,omitempty"`
 }
 
 type BrokerConfig struct {
@@ -81,14 +143,14 @@ type BrokerConfig struct {
 }
 
 type InMemoryBrokerConfig struct {
-	BufferSize     string `yaml:"buffer-size"`
-	ProduceTimeout string `yaml:"produce-timeout"`
+	BufferSize     string `yaml:"buffer-size,omitempty"`
+	ProduceTimeout string `yaml:"produce-timeout,omitempty"`
 }
 
 type RedisBrokerConfig struct {
-	Address    string `yaml:"address"`
-	Username   string `yaml:"username"`
-	Password   string `yaml:"password"`
+	Address    string `yaml:"address,omitempty"`
+	Username   string `yaml:"username,omitempty"`
+	Password   string `yaml:"password,omitempty"`
 	TLSEnabled bool   `yaml:"tls-enabled,omitempty"`
 	SkipVerify bool   `yaml:"skip-verify,omitempty"`
 }
@@ -114,8 +176,9 @@ func (c *Config) createDefault() error {
 	}
 	c.Context = defaultContext
 	c.Docker.StartTimeout = defaultDockerTimeout
-	c.Triggermesh.ComponentsVersion = latestOrDefaultTag("triggermesh", defaultTmVersion)
-	c.Triggermesh.Broker.Version = latestOrDefaultTag("brokers", defaultBrokerVersion)
+	c.Docker.RestartPolicy = defaultRestartPolicy
+	c.Triggermesh.ComponentsVersion = c.latestOrDefaultTag("triggermesh", defaultTmVersion)
+	c.Triggermesh.Broker.Version = c.latestOrDefaultTag("brokers", defaultBrokerVersion)
 	c.Triggermesh.Broker.Memory = &InMemoryBrokerConfig{
 		BufferSize:     defaultMemoryBufferSize,
 		ProduceTimeout: defaultProduceTimeout,
@@ -126,8 +189,73 @@ func (c *Config) createDefault() error {
 	return c.Save()
 }
 
-func latestOrDefaultTag(project, defaultVersion string) string {
-	r, err := http.Get("https://api.github.com/repos/triggermesh/" + project + "/releases/latest")
+// IsOffline returns true if the offline mode is enabled in the config
+// or with the environment variable.
+func (c *Config) IsOffline() bool {
+	if offline, err := strconv.ParseBool(os.Getenv(OfflineEnv)); err == nil {
+		return offline
+	}
+	return c.Offline
+}
+
+// Offline returns the offline mode of the default config.
+func Offline() bool {
+	c, _ := loadDefaultConfig()
+	return c.IsOffline()
+}
+
+// Override returns the image set for the component kind.
+func (i Images) Override(kind string) (string, bool) {
+	for k, image := range i.Overrides {
+		if strings.EqualFold(k, kind) && image != "" {
+			return image, true
+		}
+	}
+	return "", false
+}
+
+// Image returns the image of the component kind: the override
+// if it is set or the default image pulled through the mirror.
+func (i Images) Image(kind, defaultImage string) string {
+	if image, ok := i.Override(kind); ok {
+		return image
+	}
+	return i.Mirrored(defaultImage)
+}
+
+// WithoutMirror returns the images settings with the overrides only,
+// for the platforms that pull the default images from their public registries.
+func (i Images) WithoutMirror() Images {
+	i.Mirror = ""
+	return i
+}
+
+// Mirrored returns the image reference with the registry replaced by the mirror,
+// Docker Hub images are expanded to their full paths, e.g. "library/redis".
+func (i Images) Mirrored(image string) string {
+	if i.Mirror == "" {
+		return image
+	}
+	path := image
+	if parts := strings.SplitN(image, "/", 2); len(parts) == 2 && isRegistryHost(parts[0]) {
+		path = parts[1]
+	} else if len(parts) == 1 {
+		path = "library/" + image
+	}
+	return strings.TrimSuffix(i.Mirror, "/") + "/" + path
+}
+
+// isRegistryHost checks if the first image reference component is the registry,
+// the same way Docker distinguishes it from the Docker Hub namespace.
+func isRegistryHost(component string) bool {
+	return strings.ContainsAny(component, ".:") || component == "localhost"
+}
+
+func (c *Config) latestOrDefaultTag(project, defaultVersion string) string {
+	if c.IsOffline() {
+		return defaultVersion
+	}
+	r, err := releasesClient.Get(fmt.Sprintf(releasesURL, project))
 	if err != nil {
 		return defaultVersion
 	}
@@ -172,7 +300,12 @@ func Set(key, value string) error {
 	if err != nil {
 		return fmt.Errorf("unable to load config: %w", err)
 	}
-	setValue(strings.Split(key, "."), value, reflect.TypeOf(*c), reflect.ValueOf(c))
+	if err := setValue(strings.Split(key, "."), value, reflect.TypeOf(*c), reflect.ValueOf(c).Elem()); err != nil {
+		if errors.Is(err, errNotSettable) {
+			return fmt.Errorf("%q %w, edit %s", key, err, filepath.Join(c.ConfigHome, defaultConfigFile))
+		}
+		return err
+	}
 	return c.Save()
 }
 
@@ -188,31 +321,53 @@ func HomeAbsPath() string {
 	return filepath.Join(absHome, defaultConfigPath)
 }
 
-func setValue(keys []string, value string, t reflect.Type, v reflect.Value) {
+func setValue(keys []string, value string, t reflect.Type, v reflect.Value) error {
+	if t.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return setValue(keys, value, t.Elem(), v.Elem())
+	}
+	// lists and maps of structures are edited in the file
+	if t.Kind() != reflect.Struct {
+		return errNotSettable
+	}
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
 		if field.Tag.Get("yaml") == keys[0] || field.Tag.Get("yaml") == keys[0]+",omitempty" {
 			if len(keys) == 1 {
-				switch v.Kind() {
-				case reflect.Struct:
-					if vv := v.FieldByName(field.Name); vv.Kind() == reflect.String {
-						vv.SetString(value)
-					}
-				case reflect.Pointer:
-					if vv := v.Elem().FieldByName(field.Name); vv.Kind() == reflect.String {
-						vv.SetString(value)
+				vv := v.FieldByName(field.Name)
+				switch vv.Kind() {
+				case reflect.String:
+					vv.SetString(value)
+				case reflect.Bool:
+					b, err := strconv.ParseBool(value)
+					if err != nil {
+						return fmt.Errorf("%q value must be boolean", keys[0])
 					}
+					vv.SetBool(b)
+				default:
+					return errNotSettable
 				}
-				return
+				return nil
 			}
-			switch v.Kind() {
-			case reflect.Pointer:
-				setValue(keys[1:], value, field.Type, v.Elem().FieldByName(field.Name))
-			case reflect.Struct:
-				setValue(keys[1:], value, field.Type, v.FieldByName(field.Name))
+			if isStringMap(field.Type) && len(keys) == 2 {
+				// empty value removes the key
+				vv := v.FieldByName(field.Name)
+				if vv.IsNil() {
+					vv.Set(reflect.MakeMap(field.Type))
+				}
+				var mapValue reflect.Value
+				if value != "" {
+					mapValue = reflect.ValueOf(value)
+				}
+				vv.SetMapIndex(reflect.ValueOf(keys[1]), mapValue)
+				return nil
 			}
+			return setValue(keys[1:], value, field.Type, v.FieldByName(field.Name))
 		}
 	}
+	return nil
 }
 
 func readValue(keys []string, t reflect.Type, v reflect.Value) string {
@@ -238,10 +393,24 @@ func readValue(keys []string, t reflect.Type, v reflect.Value) string {
 		if len(keys) == 1 {
 			return v.String()
 		}
+	case reflect.Bool:
+		if len(keys) == 1 {
+			return strconv.FormatBool(v.Bool())
+		}
+	case reflect.Map:
+		if isStringMap(t) && len(keys) == 2 && v.IsValid() {
+			if value := v.MapIndex(reflect.ValueOf(keys[1])); value.IsValid() {
+				return value.String()
+			}
+		}
 	}
 	return ""
 }
 
+func isStringMap(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.String
+}
+
 func loadDefaultConfig() (*Config, error) {
 	c := &Config{
 		ConfigHome: HomeAbsPath(),
@@ -0,0 +1,174 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOffline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(OfflineEnv, "")
+	c := &Config{ConfigHome: HomeAbsPath()}
+	assert.NoError(t, os.MkdirAll(c.ConfigHome, os.ModePerm))
+	assert.NoError(t, c.Save())
+	assert.False(t, Offline())
+
+	assert.EqualError(t, Set("offline", "yes"), `"offline" value must be boolean`)
+	assert.NoError(t, Set("offline", "true"))
+	value, err := Get("offline")
+	assert.NoError(t, err)
+	assert.Equal(t, "true", value)
+	assert.True(t, Offline())
+
+	// offline mode pins the default versions
+	c, err = loadDefaultConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, defaultTmVersion, c.latestOrDefaultTag("triggermesh", defaultTmVersion))
+
+	t.Setenv(OfflineEnv, "false")
+	assert.False(t, Offline())
+}
+
+func TestLatestOrDefaultTag(t *testing.T) {
+	t.Setenv(OfflineEnv, "")
+	delay := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/brokers":
+			_, _ = w.Write([]byte(`{"tag_name": "v1.2.0"}`))
+		default:
+			<-delay
+		}
+	}))
+	defer server.Close()
+	defer close(delay)
+	defer func(url string, timeout time.Duration) {
+		releasesURL, releasesClient.Timeout = url, timeout
+	}(releasesURL, releasesClient.Timeout)
+	releasesURL = server.URL + "/%s"
+	releasesClient.Timeout = 100 * time.Millisecond
+
+	c := &Config{}
+	assert.Equal(t, "v1.2.0", c.latestOrDefaultTag("brokers", defaultBrokerVersion))
+	// unresponsive API does not stall the lookup
+	assert.Equal(t, defaultTmVersion, c.latestOrDefaultTag("triggermesh", defaultTmVersion))
+
+	t.Setenv(OfflineEnv, "true")
+	assert.Equal(t, defaultBrokerVersion, c.latestOrDefaultTag("brokers", defaultBrokerVersion))
+}
+
+func TestImages(t *testing.T) {
+	images := Images{
+		Mirror: "registry.example.com/mirror/",
+		Overrides: map[string]string{
+			"AWSS3Source": "registry.example.com/awssqssource-adapter:dev",
+			"Redis":       "",
+		},
+	}
+	cases := map[string]struct {
+		kind     string
+		image    string
+		expected string
+	}{
+		"override": {
+			kind:     "awss3source",
+			image:    "gcr.io/triggermesh/awssqssource-adapter:v1.23.2",
+			expected: "registry.example.com/awssqssource-adapter:dev",
+		},
+		"registry mirror": {
+			kind:     "MemoryBroker",
+			image:    "gcr.io/triggermesh/memory-broker:v1.1.0",
+			expected: "registry.example.com/mirror/triggermesh/memory-broker:v1.1.0",
+		},
+		"docker hub official image": {
+			kind:     "Redis",
+			image:    "redis:7.0-alpine",
+			expected: "registry.example.com/mirror/library/redis:7.0-alpine",
+		},
+		"docker hub image": {
+			kind:     "Service",
+			image:    "example/inventory:v1",
+			expected: "registry.example.com/mirror/example/inventory:v1",
+		},
+		"registry with port": {
+			kind:     "Service",
+			image:    "localhost:5000/inventory:v1",
+			expected: "registry.example.com/mirror/inventory:v1",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, images.Image(tc.kind, tc.image))
+		})
+	}
+	assert.Equal(t, "redis:7.0-alpine", Images{}.Image("Redis", "redis:7.0-alpine"))
+}
+
+func TestSetImages(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	c := &Config{ConfigHome: HomeAbsPath()}
+	assert.NoError(t, os.MkdirAll(c.ConfigHome, os.ModePerm))
+	assert.NoError(t, c.Save())
+
+	assert.NoError(t, Set("images.mirror", "registry.example.com/mirror"))
+	assert.NoError(t, Set("images.overrides.AWSS3Source", "registry.example.com/awssqssource-adapter:dev"))
+	c, err := loadDefaultConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, Images{
+		Mirror:    "registry.example.com/mirror",
+		Overrides: map[string]string{"AWSS3Source": "registry.example.com/awssqssource-adapter:dev"},
+	}, c.Images)
+
+	value, err := Get("images.overrides.AWSS3Source")
+	assert.NoError(t, err)
+	assert.Equal(t, "registry.example.com/awssqssource-adapter:dev", value)
+
+	// empty value removes the override
+	assert.NoError(t, Set("images.overrides.AWSS3Source", ""))
+	c, err = loadDefaultConfig()
+	assert.NoError(t, err)
+	assert.Empty(t, c.Images.Overrides)
+}
+
+func TestSetNested(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	c := &Config{ConfigHome: HomeAbsPath()}
+	assert.NoError(t, os.MkdirAll(c.ConfigHome, os.ModePerm))
+	assert.NoError(t, c.Save())
+
+	assert.NoError(t, Set("triggermesh.broker.memory.buffer-size", "50"))
+	value, err := Get("triggermesh.broker.memory.buffer-size")
+	assert.NoError(t, err)
+	assert.Equal(t, "50", value)
+
+	for _, key := range []string{
+		"triggermesh.kinds.FooSource.image",
+		"triggermesh.kinds",
+		"triggermesh.crd-sources.0",
+	} {
+		assert.EqualError(t, Set(key, "x"), fmt.Sprintf("%q key is not settable, edit %s", key, filepath.Join(c.ConfigHome, defaultConfigFile)), key)
+	}
+}
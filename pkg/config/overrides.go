@@ -23,6 +23,7 @@ type configOverride func(*Config) bool
 var overrides = []configOverride{
 	brokerImageReplacement(),
 	dockerTimeoutAppend(),
+	dockerRestartPolicyAppend(),
 }
 
 func (c *Config) applyOverrides() error {
@@ -47,13 +48,13 @@ func brokerImageReplacement() configOverride {
 		if len(imageRef) == 2 {
 			if c.Triggermesh.Broker.Version == "" {
 				if imageRef[1] == "latest" {
-					imageRef[1] = latestOrDefaultTag("brokers", defaultBrokerVersion)
+					imageRef[1] = c.latestOrDefaultTag("brokers", defaultBrokerVersion)
 				}
 				c.Triggermesh.Broker.Version = imageRef[1]
 			}
 		}
 		if c.Triggermesh.Broker.Version == "" {
-			c.Triggermesh.Broker.Version = latestOrDefaultTag("brokers", defaultBrokerVersion)
+			c.Triggermesh.Broker.Version = c.latestOrDefaultTag("brokers", defaultBrokerVersion)
 		}
 		c.Triggermesh.Broker.Image = ""
 		return true
@@ -69,3 +70,13 @@ func dockerTimeoutAppend() configOverride {
 		return true
 	}
 }
+
+func dockerRestartPolicyAppend() configOverride {
+	return func(c *Config) bool {
+		if c.Docker.RestartPolicy != "" {
+			return false
+		}
+		c.Docker.RestartPolicy = defaultRestartPolicy
+		return true
+	}
+}
@@ -22,34 +22,47 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
 
 	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/metrics"
 )
 
-// time to wait for adapter init logs to show up.
-var initLogsWaitPeriod time.Duration = 2 * time.Second
-
-type imagePullEvent struct {
-	Status         string `json:"status"`
-	Error          string `json:"error"`
-	Progress       string `json:"progress"`
-	ProgressDetail struct {
-		Current int `json:"current"`
-		Total   int `json:"total"`
-	} `json:"progressDetail"`
-}
+// mainPort is the container port of the components HTTP endpoint.
+const mainPort nat.Port = "8080/tcp"
+
+// metricsPort is the container port of the components metrics endpoint.
+const metricsPort nat.Port = metrics.Port + "/tcp"
+
+// probeTimeout is the HTTP health probe timeout.
+const probeTimeout = 2 * time.Second
+
+var (
+	// time to wait for adapter init logs to show up.
+	initLogsWaitPeriod time.Duration = 2 * time.Second
+	// period of the container state checks on start.
+	statePollPeriod = 500 * time.Millisecond
+)
 
 type Container struct {
 	ID     string
 	Name   string
 	Image  string
 	Online bool
+	// Restarting is set when the container is being restarted
+	// by the Docker restart policy.
+	Restarting   bool
+	RestartCount int
+	// Health is the runtime health check status, empty
+	// if the container does not have the health check.
+	Health string
 
 	CreateContainerOptions []ContainerOption
 	CreateHostOptions      []HostOption
@@ -58,112 +71,101 @@ type Container struct {
 	runtimeContainerConfig container.Config
 }
 
-func NewClient() (*client.Client, error) {
-	return client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-}
-
-func CheckDaemon() error {
-	c, err := NewClient()
-	if err != nil {
-		return err
-	}
-	_, err = c.ServerVersion(context.Background())
-	return err
-}
-
-func (c *Container) Logs(ctx context.Context, client *client.Client, since time.Time, follow bool) (io.ReadCloser, error) {
-	options := types.ContainerLogsOptions{
-		ShowStdout: true,
-		ShowStderr: true,
-		Follow:     follow,
-		Since:      since.Format("2006-01-02T15:04:05.999999999Z07:00")}
-	return client.ContainerLogs(ctx, c.ID, options)
+func (c *Container) Logs(ctx context.Context, runtime Runtime, since time.Time, follow bool) (io.ReadCloser, error) {
+	return runtime.ContainerLogs(ctx, c.ID, since, follow)
 }
 
-func (c *Container) Remove(ctx context.Context, client *client.Client) error {
-	id, err := nameToID(ctx, c.Name, client)
-	if err != nil {
-		return err
-	}
-	return client.ContainerRemove(ctx, id, types.ContainerRemoveOptions{
-		RemoveVolumes: true,
-		Force:         true,
-	})
+func (c *Container) Remove(ctx context.Context, runtime Runtime) error {
+	return runtime.RemoveContainer(ctx, c.Name)
 }
 
-func (c *Container) pullImage(ctx context.Context, client *client.Client) error {
-	reader, err := client.ImagePull(ctx, c.Image, types.ImagePullOptions{})
+func (c *Container) Start(ctx context.Context, runtime Runtime, restart bool) (*Container, error) {
+	restartPolicy, err := config.Get("docker.restart-policy")
 	if err != nil {
-		return err
-	}
-	defer reader.Close()
-
-	d := json.NewDecoder(reader)
-	var e *imagePullEvent
-	var downloading bool
-	for {
-		if err := d.Decode(&e); err != nil {
-			if err == io.EOF {
-				break
-			}
-			return err
-		}
-		if e.Status == "Downloading" {
-			downloading = true
-			fmt.Printf("\r%s", e.Progress)
-		}
-	}
-	if downloading {
-		fmt.Printf("\n")
+		return nil, fmt.Errorf("config read: %w", err)
 	}
-	return nil
-}
 
-func (c *Container) Start(ctx context.Context, client *client.Client, restart bool) (*Container, error) {
 	cc := container.Config{}
 	for _, opt := range c.CreateContainerOptions {
 		opt(&cc)
 	}
 
 	hc := container.HostConfig{}
+	if restartPolicy != "" {
+		// component options may override the default policy
+		WithRestartPolicy(restartPolicy)(&hc)
+	}
 	for _, opt := range c.CreateHostOptions {
 		opt(&hc)
 	}
 
-	if err := c.pullImage(ctx, client); err != nil {
-		return nil, fmt.Errorf("pulling image: %w", err)
+	offline := config.Offline()
+	if !offline {
+		if err := runtime.PullImage(ctx, c.Image); err != nil {
+			return nil, fmt.Errorf("pulling image: %w", err)
+		}
+	}
+
+	if hc.NetworkMode.IsUserDefined() {
+		if err := runtime.EnsureNetwork(ctx, string(hc.NetworkMode)); err != nil {
+			return nil, fmt.Errorf("container network: %w", err)
+		}
 	}
 
 	var containerIsRunning bool
-	existingContainer, _ := c.LookupHostConfig(ctx, client)
+	existingContainer, _ := c.LookupHostConfig(ctx, runtime)
 	if existingContainer != nil {
 		if c.Image != existingContainer.Image {
 			restart = true
 		}
+		if existingContainer.runtimeHostConfig.NetworkMode != hc.NetworkMode {
+			// containers created before the network was introduced
+			restart = true
+		}
+		if port := existingContainer.BoundPort(mainPort); port != "" && port != hostPort(hc, mainPort) {
+			// host port was reassigned
+			restart = true
+		}
 		if existingContainer.Online {
 			containerIsRunning = true
 		}
+		// Adapter images are distroless, they have neither a shell nor an HTTP
+		// client to run the runtime health check command with, so the runtime
+		// never marks a hung adapter unhealthy and the restart policy only acts
+		// on the exited ones. Running containers without the runtime health
+		// check are probed over HTTP instead and recreated if they do not respond.
+		if containerIsRunning && existingContainer.Health == "" && existingContainer.Probe(ctx) != nil {
+			restart = true
+		}
 	}
 	if restart {
 		// remove errors usually means that container doesn't exist
 		// ignore it and try to create a new one.
-		_ = c.Remove(ctx, client)
+		_ = c.Remove(ctx, runtime)
 	} else if containerIsRunning {
 		return existingContainer, nil
 	}
 
-	resp, err := client.ContainerCreate(ctx, &cc, &hc, nil, nil, c.Name)
+	id, err := runtime.CreateContainer(ctx, c.Name, &cc, &hc)
 	if err != nil {
-		return nil, fmt.Errorf("docker create: %w", err)
+		if offline {
+			return nil, fmt.Errorf("container create: %w, images are not pulled in the offline mode", err)
+		}
+		return nil, fmt.Errorf("container create: %w", err)
 	}
 
-	c.ID = resp.ID
+	c.ID = id
 	c.runtimeHostConfig = hc
 	c.runtimeContainerConfig = cc
 
 	sinceStart := time.Now()
-	if err := client.ContainerStart(ctx, c.ID, types.ContainerStartOptions{}); err != nil {
-		return nil, fmt.Errorf("docker start: %w", err)
+	if err := runtime.StartContainer(ctx, c.ID); err != nil {
+		if isPortConflict(err) {
+			// do not leave the created container behind, next start would fail on the name conflict
+			_ = c.Remove(ctx, runtime)
+			return nil, fmt.Errorf("container %q host ports %s: port is already in use by another process", c.Name, strings.Join(hostPorts(hc), ", "))
+		}
+		return nil, fmt.Errorf("container start: %w", err)
 	}
 	configTimeout, err := config.Get("docker.timeout")
 	if err != nil {
@@ -173,13 +175,13 @@ func (c *Container) Start(ctx context.Context, client *client.Client, restart bo
 	if err != nil {
 		return nil, fmt.Errorf("config timeout value: %w", err)
 	}
-	if err := c.isRunning(ctx, client, timeout); err != nil {
-		return nil, fmt.Errorf("docker connect: %w", err)
+	if err := c.isRunning(ctx, runtime, timeout); err != nil {
+		return nil, fmt.Errorf("container connect: %w", err)
 	}
 	time.Sleep(initLogsWaitPeriod)
-	logsReader, err := c.Logs(ctx, client, sinceStart, false)
+	logsReader, err := c.Logs(ctx, runtime, sinceStart, false)
 	if err != nil {
-		return nil, fmt.Errorf("docker read logs: %w", err)
+		return nil, fmt.Errorf("container read logs: %w", err)
 	}
 	defer logsReader.Close()
 
@@ -199,42 +201,54 @@ func (c *Container) Start(ctx context.Context, client *client.Client, restart bo
 	return c, nil
 }
 
-func nameToID(ctx context.Context, name string, client *client.Client) (string, error) {
-	containers, err := client.ContainerList(ctx, types.ContainerListOptions{
-		All: true,
-	})
-	if err != nil {
-		return "", err
+// hostPort returns the host port bound to the container port in the host config.
+func hostPort(hc container.HostConfig, containerPort nat.Port) string {
+	for _, binding := range hc.PortBindings[containerPort] {
+		return binding.HostPort
 	}
-	for _, container := range containers {
-		for _, cName := range container.Names {
-			if cName == "/"+name {
-				return container.ID, nil
-			}
+	return ""
+}
+
+// hostPorts returns all host ports bound in the host config.
+func hostPorts(hc container.HostConfig) []string {
+	var ports []string
+	for _, bindings := range hc.PortBindings {
+		for _, binding := range bindings {
+			ports = append(ports, binding.HostPort)
 		}
 	}
-	return "", nil
+	sort.Strings(ports)
+	return ports
 }
 
-func (c *Container) LookupHostConfig(ctx context.Context, client *client.Client) (*Container, error) {
-	id, err := nameToID(ctx, c.Name, client)
-	if err != nil {
-		return nil, err
-	}
-	jsn, err := client.ContainerInspect(ctx, id)
+// isPortConflict checks if the container failed to start because its host port is busy.
+func isPortConflict(err error) bool {
+	return strings.Contains(err.Error(), "port is already allocated") ||
+		strings.Contains(err.Error(), "address already in use")
+}
+
+func (c *Container) LookupHostConfig(ctx context.Context, runtime Runtime) (*Container, error) {
+	info, err := runtime.InspectContainer(ctx, c.Name)
 	if err != nil {
 		return nil, err
 	}
-	c.ID = id
-	if jsn.State.Running {
+	c.ID = info.ID
+	if info.Running {
 		c.Online = true
 	}
-	c.runtimeHostConfig = *jsn.HostConfig
-	c.runtimeContainerConfig = *jsn.Config
+	c.Restarting = info.Restarting
+	c.RestartCount = info.RestartCount
+	c.Health = info.Health
+	c.runtimeHostConfig = info.HostConfig
+	c.runtimeContainerConfig = info.Config
 	return c, nil
 }
 
+// HostPort returns the host port bound to the main container port.
 func (c *Container) HostPort() string {
+	if port := c.BoundPort(mainPort); port != "" {
+		return port
+	}
 	for _, bindings := range c.runtimeHostConfig.PortBindings {
 		for _, binding := range bindings {
 			return binding.HostPort
@@ -243,35 +257,99 @@ func (c *Container) HostPort() string {
 	return ""
 }
 
-func (c *Container) isRunning(ctx context.Context, client *client.Client, timeout time.Duration) error {
-	ticker := time.NewTicker(500 * time.Millisecond)
+// BoundPort returns the host port bound to the container port.
+func (c *Container) BoundPort(containerPort nat.Port) string {
+	for _, binding := range c.runtimeHostConfig.PortBindings[containerPort] {
+		return binding.HostPort
+	}
+	return ""
+}
+
+func (c *Container) isRunning(ctx context.Context, runtime Runtime, timeout time.Duration) error {
+	ticker := time.NewTicker(statePollPeriod)
 	defer ticker.Stop()
 	cancel := time.After(timeout)
 	for {
-		container, err := client.ContainerInspect(ctx, c.ID)
+		info, err := runtime.InspectContainer(ctx, c.ID)
 		if err != nil {
 			return err
 		}
 		select {
 		case <-cancel:
-			return fmt.Errorf("container init timeout, state: %s", container.State.Status)
+			return fmt.Errorf("container init timeout, state: %s", info.Status)
 		case <-ticker.C:
-			if container.State.Running {
-				return nil
+			if !info.Running {
+				continue
 			}
+			// containers with the health check must report healthy status
+			switch info.Health {
+			case types.Unhealthy:
+				if info.HealthLog == "" {
+					return fmt.Errorf("container is unhealthy: no health check results")
+				}
+				return fmt.Errorf("container is unhealthy: %s", info.HealthLog)
+			case types.Starting:
+				continue
+			}
+			return nil
 		}
 	}
 }
 
-func ForceStop(ctx context.Context, name string, client *client.Client) error {
-	id, err := nameToID(ctx, name, client)
+// HealthStatus returns the Docker health check status of the container or,
+// if the container does not have the health check, the HTTP probe result.
+func (c *Container) HealthStatus(ctx context.Context, probePort nat.Port, path string) string {
+	if c.Health != "" {
+		return c.Health
+	}
+	if err := c.ProbeHTTP(ctx, probePort, path); err != nil {
+		return types.Unhealthy
+	}
+	return types.Healthy
+}
+
+// Probe checks the container HTTP endpoint or, for the components
+// that do not serve HTTP, the metrics endpoint.
+func (c *Container) Probe(ctx context.Context) error {
+	err := c.ProbeHTTP(ctx, mainPort, "/")
+	if err != nil && c.BoundPort(metricsPort) != "" {
+		return c.ProbeHTTP(ctx, metricsPort, "/metrics")
+	}
+	return err
+}
+
+// ProbeHTTP sends the request to the path of the host port bound to the container port.
+// Any response except the server errors means that the container is healthy.
+func (c *Container) ProbeHTTP(ctx context.Context, containerPort nat.Port, path string) error {
+	port := c.BoundPort(containerPort)
+	if port == "" {
+		return fmt.Errorf("port %s is not published", containerPort)
+	}
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://localhost:%s%s", port, path), nil)
 	if err != nil {
 		return err
 	}
-	return client.ContainerRemove(ctx, id, types.ContainerRemoveOptions{
-		RemoveVolumes: true,
-		Force:         true,
-	})
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+	return nil
+}
+
+// ForceStop removes the container regardless of its state.
+func ForceStop(ctx context.Context, name string, runtime Runtime) error {
+	return runtime.RemoveContainer(ctx, name)
+}
+
+// NetworkName returns the name of the broker components network.
+func NetworkName(broker string) string {
+	return "tmctl-" + broker
 }
 
 func readLogs(logs io.ReadCloser) []string {
@@ -0,0 +1,59 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	assert.NoError(t, err)
+
+	c := &Container{
+		runtimeHostConfig: container.HostConfig{
+			PortBindings: nat.PortMap{
+				"8080/tcp": []nat.PortBinding{{HostPort: u.Port()}},
+			},
+		},
+	}
+	ctx := context.Background()
+	assert.Equal(t, u.Port(), c.HostPort())
+	assert.Equal(t, types.Healthy, c.HealthStatus(ctx, "8080/tcp", "/"))
+	assert.Equal(t, types.Unhealthy, c.HealthStatus(ctx, "8080/tcp", "/broken"))
+	assert.Equal(t, types.Unhealthy, c.HealthStatus(ctx, "9092/tcp", "/metrics"))
+
+	c.Health = types.Starting
+	assert.Equal(t, types.Starting, c.HealthStatus(ctx, "8080/tcp", "/"))
+}
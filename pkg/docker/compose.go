@@ -19,7 +19,9 @@ package docker
 type ComposeService struct {
 	ContainerName string   `json:"container_name"`
 	Entrypoint    []string `json:"entrypoint,omitempty"`
+	Command       []string `json:"command,omitempty"`
 	Image         string   `json:"image"`
 	Ports         []string `json:"ports"`
 	Environment   []string `json:"environment"`
+	Volumes       []string `json:"volumes,omitempty"`
 }
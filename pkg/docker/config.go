@@ -18,6 +18,7 @@ package docker
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/go-connections/nat"
@@ -50,15 +51,31 @@ func WithPort(port nat.Port) ContainerOption {
 	}
 }
 
+// WithExposedPort exposes the additional container port.
+func WithExposedPort(port nat.Port) ContainerOption {
+	return func(cc *container.Config) {
+		if cc.ExposedPorts == nil {
+			cc.ExposedPorts = nat.PortSet{}
+		}
+		cc.ExposedPorts[port] = struct{}{}
+	}
+}
+
 func WithEntrypoint(entrypoint []string) ContainerOption {
 	return func(cc *container.Config) {
 		cc.Entrypoint = entrypoint
 	}
 }
 
+func WithCmd(cmd []string) ContainerOption {
+	return func(cc *container.Config) {
+		cc.Cmd = cmd
+	}
+}
+
 func WithVolumeBind(bind string) HostOption {
 	return func(hc *container.HostConfig) {
-		hc.Binds = []string{bind}
+		hc.Binds = append(hc.Binds, bind)
 	}
 }
 
@@ -75,12 +92,72 @@ func WithHostPortBinding(containerPort nat.Port) HostOption {
 	}
 }
 
+// WithAdditionalHostPortBinding binds the additional container port to the random host port.
+func WithAdditionalHostPortBinding(containerPort nat.Port) HostOption {
+	return func(hc *container.HostConfig) {
+		if hc.PortBindings == nil {
+			hc.PortBindings = nat.PortMap{}
+		}
+		hc.PortBindings[containerPort] = []nat.PortBinding{
+			{
+				HostIP:   "0.0.0.0",
+				HostPort: strconv.Itoa(pkg.OpenPort()),
+			},
+		}
+	}
+}
+
+// WithStaticHostPortBinding binds the container port to the fixed host port,
+// bindings of the other container ports are kept.
+func WithStaticHostPortBinding(containerPort nat.Port, hostPort string) HostOption {
+	return func(hc *container.HostConfig) {
+		if hc.PortBindings == nil {
+			hc.PortBindings = nat.PortMap{}
+		}
+		hc.PortBindings[containerPort] = []nat.PortBinding{
+			{
+				HostIP:   "0.0.0.0",
+				HostPort: hostPort,
+			},
+		}
+	}
+}
+
+// WithNetwork connects the container to the user-defined network
+// where the components address each other by the container names.
+func WithNetwork(network string) HostOption {
+	return func(hc *container.HostConfig) {
+		hc.NetworkMode = container.NetworkMode(network)
+	}
+}
+
 func WithExtraHost() HostOption {
 	return func(hc *container.HostConfig) {
 		hc.ExtraHosts = []string{"host.docker.internal:host-gateway"}
 	}
 }
 
+// WithRestartPolicy sets the container restart policy,
+// one of "no", "on-failure", "always" or "unless-stopped".
+func WithRestartPolicy(policy string) HostOption {
+	return func(hc *container.HostConfig) {
+		hc.RestartPolicy = container.RestartPolicy{Name: policy}
+	}
+}
+
+// WithHealthCheck sets the command that Docker runs
+// inside the container to check its health.
+func WithHealthCheck(test []string, interval, timeout time.Duration, retries int) ContainerOption {
+	return func(cc *container.Config) {
+		cc.Healthcheck = &container.HealthConfig{
+			Test:     append([]string{"CMD"}, test...),
+			Interval: interval,
+			Timeout:  timeout,
+			Retries:  retries,
+		}
+	}
+}
+
 func WithErrorLoggingLevel() ContainerOption {
 	return func(cc *container.Config) {
 		cc.Env = append(cc.Env, errorLoggingLevel)
@@ -18,6 +18,7 @@ package docker
 
 import (
 	"testing"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/strslice"
@@ -51,6 +52,14 @@ func TestWithPort(t *testing.T) {
 	assert.Equal(t, portSet, cc.ExposedPorts)
 }
 
+func TestWithExposedPort(t *testing.T) {
+	cc := &container.Config{}
+	WithPort("8080/tcp")(cc)
+	WithExposedPort("9092/tcp")(cc)
+	assert.Len(t, cc.ExposedPorts, 2)
+	assert.Contains(t, cc.ExposedPorts, nat.Port("9092/tcp"))
+}
+
 func TestWithEntrypoint(t *testing.T) {
 	entrypoint := []string{"/bin/triggermesh", "start"}
 	cc := &container.Config{}
@@ -58,11 +67,20 @@ func TestWithEntrypoint(t *testing.T) {
 	assert.Equal(t, strslice.StrSlice(entrypoint), cc.Entrypoint)
 }
 
+func TestWithCmd(t *testing.T) {
+	cmd := []string{"redis-server", "--appendonly", "yes"}
+	cc := &container.Config{}
+	WithCmd(cmd)(cc)
+	assert.Equal(t, strslice.StrSlice(cmd), cc.Cmd)
+}
+
 func TestWithVolumeBind(t *testing.T) {
 	bind := "foo:bar"
 	hc := &container.HostConfig{}
 	WithVolumeBind(bind)(hc)
 	assert.Equal(t, []string{bind}, hc.Binds)
+	WithVolumeBind("baz:qux")(hc)
+	assert.Equal(t, []string{bind, "baz:qux"}, hc.Binds)
 }
 
 func TestWithHostPortBinding(t *testing.T) {
@@ -74,6 +92,23 @@ func TestWithHostPortBinding(t *testing.T) {
 	assert.Equal(t, "0.0.0.0", hc.PortBindings[port][0].HostIP)
 }
 
+func TestWithAdditionalHostPortBinding(t *testing.T) {
+	hc := &container.HostConfig{}
+	WithHostPortBinding("8080/tcp")(hc)
+	WithAdditionalHostPortBinding("9092/tcp")(hc)
+	assert.Len(t, hc.PortBindings, 2)
+	assert.Len(t, hc.PortBindings["9092/tcp"], 1)
+}
+
+func TestWithStaticHostPortBinding(t *testing.T) {
+	port, err := nat.NewPort("TCP", "6379")
+	assert.NoError(t, err)
+	hc := &container.HostConfig{}
+	WithStaticHostPortBinding(port, "16379")(hc)
+	assert.Len(t, hc.PortBindings[port], 1)
+	assert.Equal(t, "16379", hc.PortBindings[port][0].HostPort)
+}
+
 func TestWithExtraHost(t *testing.T) {
 	hc := &container.HostConfig{}
 	WithExtraHost()(hc)
@@ -81,8 +116,39 @@ func TestWithExtraHost(t *testing.T) {
 	assert.Equal(t, "host.docker.internal:host-gateway", hc.ExtraHosts[0])
 }
 
+func TestWithRestartPolicy(t *testing.T) {
+	hc := &container.HostConfig{}
+	WithRestartPolicy("on-failure")(hc)
+	assert.True(t, hc.RestartPolicy.IsOnFailure())
+}
+
+func TestWithHealthCheck(t *testing.T) {
+	cc := &container.Config{}
+	WithHealthCheck([]string{"redis-cli", "ping"}, time.Second, 2*time.Second, 3)(cc)
+	assert.Equal(t, []string{"CMD", "redis-cli", "ping"}, cc.Healthcheck.Test)
+	assert.Equal(t, time.Second, cc.Healthcheck.Interval)
+	assert.Equal(t, 2*time.Second, cc.Healthcheck.Timeout)
+	assert.Equal(t, 3, cc.Healthcheck.Retries)
+}
+
 func TestWithErrorLoggingLevel(t *testing.T) {
 	cc := &container.Config{}
 	WithErrorLoggingLevel()(cc)
 	assert.Contains(t, cc.Env, errorLoggingLevel)
 }
+
+func TestWithNetwork(t *testing.T) {
+	hc := &container.HostConfig{}
+	WithNetwork(NetworkName("foo"))(hc)
+	assert.Equal(t, container.NetworkMode("tmctl-foo"), hc.NetworkMode)
+	assert.True(t, hc.NetworkMode.IsUserDefined())
+}
+
+func TestWithStaticHostPortBindingKeepsOtherPorts(t *testing.T) {
+	hc := &container.HostConfig{}
+	WithHostPortBinding("8080/tcp")(hc)
+	WithAdditionalHostPortBinding("9092/tcp")(hc)
+	WithStaticHostPortBinding("8080/tcp", "18080")(hc)
+	assert.Len(t, hc.PortBindings, 2)
+	assert.Equal(t, "18080", hc.PortBindings["8080/tcp"][0].HostPort)
+}
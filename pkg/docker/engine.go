@@ -0,0 +1,234 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+)
+
+var _ Runtime = (*dockerRuntime)(nil)
+
+// dockerRuntime is the Docker Engine API runtime.
+type dockerRuntime struct {
+	client *client.Client
+}
+
+type imagePullEvent struct {
+	Status         string `json:"status"`
+	Error          string `json:"error"`
+	Progress       string `json:"progress"`
+	ProgressDetail struct {
+		Current int `json:"current"`
+		Total   int `json:"total"`
+	} `json:"progressDetail"`
+}
+
+func newDockerRuntime(socket string) (*dockerRuntime, error) {
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	if socket != "" {
+		opts = append(opts, client.WithHost("unix://"+strings.TrimPrefix(socket, "unix://")))
+	}
+	c, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("docker client: %w", err)
+	}
+	return &dockerRuntime{client: c}, nil
+}
+
+func (d *dockerRuntime) Version(ctx context.Context) (string, error) {
+	ver, err := d.client.ServerVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+	return ver.Platform.Name, nil
+}
+
+func (d *dockerRuntime) PullImage(ctx context.Context, image string) error {
+	reader, err := d.client.ImagePull(ctx, image, types.ImagePullOptions{})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	decoder := json.NewDecoder(reader)
+	var e *imagePullEvent
+	var downloading bool
+	for {
+		if err := decoder.Decode(&e); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if e.Status == "Downloading" {
+			downloading = true
+			fmt.Printf("\r%s", e.Progress)
+		}
+	}
+	if downloading {
+		fmt.Printf("\n")
+	}
+	return nil
+}
+
+func (d *dockerRuntime) CreateContainer(ctx context.Context, name string, cc *container.Config, hc *container.HostConfig) (string, error) {
+	resp, err := d.client.ContainerCreate(ctx, cc, hc, nil, nil, name)
+	if err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+func (d *dockerRuntime) StartContainer(ctx context.Context, id string) error {
+	return d.client.ContainerStart(ctx, id, types.ContainerStartOptions{})
+}
+
+func (d *dockerRuntime) InspectContainer(ctx context.Context, name string) (*ContainerInfo, error) {
+	id, err := d.nameToID(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		// container ID or missing container
+		id = name
+	}
+	jsn, err := d.client.ContainerInspect(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	info := &ContainerInfo{
+		ID:           jsn.ID,
+		Status:       jsn.State.Status,
+		Running:      jsn.State.Running,
+		Restarting:   jsn.State.Restarting,
+		RestartCount: jsn.RestartCount,
+		Config:       *jsn.Config,
+		HostConfig:   *jsn.HostConfig,
+	}
+	if health := jsn.State.Health; health != nil {
+		info.Health = health.Status
+		if len(health.Log) != 0 {
+			info.HealthLog = strings.TrimSpace(health.Log[len(health.Log)-1].Output)
+		}
+	}
+	return info, nil
+}
+
+func (d *dockerRuntime) RemoveContainer(ctx context.Context, name string) error {
+	id, err := d.nameToID(ctx, name)
+	if err != nil {
+		return err
+	}
+	if id == "" {
+		return nil
+	}
+	return d.client.ContainerRemove(ctx, id, types.ContainerRemoveOptions{
+		RemoveVolumes: true,
+		Force:         true,
+	})
+}
+
+func (d *dockerRuntime) ContainerLogs(ctx context.Context, id string, since time.Time, follow bool) (io.ReadCloser, error) {
+	options := types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     follow,
+		Since:      since.Format("2006-01-02T15:04:05.999999999Z07:00")}
+	return d.client.ContainerLogs(ctx, id, options)
+}
+
+func (d *dockerRuntime) EnsureNetwork(ctx context.Context, name string) error {
+	_, err := d.client.NetworkInspect(ctx, name, types.NetworkInspectOptions{})
+	if err == nil {
+		return nil
+	}
+	if !errdefs.IsNotFound(err) {
+		return err
+	}
+	_, err = d.client.NetworkCreate(ctx, name, types.NetworkCreate{
+		CheckDuplicate: true,
+		Driver:         "bridge",
+	})
+	if errdefs.IsConflict(err) {
+		// created concurrently
+		return nil
+	}
+	return err
+}
+
+func (d *dockerRuntime) RemoveNetwork(ctx context.Context, name string) error {
+	if err := d.client.NetworkRemove(ctx, name); err != nil && !errdefs.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (d *dockerRuntime) RemoveVolume(ctx context.Context, name string) error {
+	if err := d.client.VolumeRemove(ctx, name, true); err != nil && !errdefs.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (d *dockerRuntime) HostGateway(ctx context.Context) (string, error) {
+	info, err := d.client.Info(ctx)
+	if err != nil {
+		return "", err
+	}
+	// Docker Desktop VM forwards host.docker.internal to the host loopback
+	if strings.Contains(info.OperatingSystem, "Docker Desktop") {
+		return loopbackAddress, nil
+	}
+	// host-gateway is the default bridge gateway
+	bridge, err := d.client.NetworkInspect(ctx, "bridge", types.NetworkInspectOptions{})
+	if err != nil {
+		return "", err
+	}
+	for _, c := range bridge.IPAM.Config {
+		if c.Gateway != "" {
+			return c.Gateway, nil
+		}
+	}
+	return "", fmt.Errorf("bridge network does not have the gateway")
+}
+
+func (d *dockerRuntime) nameToID(ctx context.Context, name string) (string, error) {
+	containers, err := d.client.ContainerList(ctx, types.ContainerListOptions{
+		All: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, container := range containers {
+		for _, cName := range container.Names {
+			if cName == "/"+name {
+				return container.ID, nil
+			}
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"os"
+	"testing"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+)
+
+// Setup points the home directory of the test to the temporary directory
+// with the CLI configuration and makes the components use the returned
+// in-memory runtime until the end of the test. The running containers
+// answer HTTP requests on their host ports.
+func Setup(t *testing.T) (*Runtime, *config.Config) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	c := &config.Config{
+		ConfigHome: config.HomeAbsPath(),
+		Docker: config.Docker{
+			StartTimeout:  "1s",
+			RestartPolicy: "on-failure",
+		},
+		Triggermesh: config.TmConfig{
+			ComponentsVersion: "v1.23.0",
+			Broker: config.BrokerConfig{
+				Version: "v1.1.0",
+				Memory: &config.InMemoryBrokerConfig{
+					BufferSize:     "100",
+					ProduceTimeout: "1s",
+				},
+			},
+		},
+	}
+	if err := os.MkdirAll(c.ConfigHome, os.ModePerm); err != nil {
+		t.Fatalf("config home: %v", err)
+	}
+	if err := c.Save(); err != nil {
+		t.Fatalf("config write: %v", err)
+	}
+	runtime := New()
+	runtime.Serve = true
+	t.Cleanup(runtime.Close)
+	t.Cleanup(docker.UseRuntime(runtime))
+	return runtime, c
+}
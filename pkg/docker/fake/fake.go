@@ -0,0 +1,301 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake provides the in-memory container runtime that records
+// the containers specs and lifecycle calls instead of running them.
+package fake
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+
+	"github.com/triggermesh/tmctl/pkg/docker"
+)
+
+var _ docker.Runtime = (*Runtime)(nil)
+
+// Runtime is the in-memory container runtime.
+type Runtime struct {
+	mu sync.Mutex
+
+	// Containers are the created containers by their names.
+	Containers map[string]*Container
+	// Networks are the existing networks.
+	Networks map[string]bool
+	// Images are the pulled images in the pull order.
+	Images []string
+	// Calls are the runtime calls in the "<method> <name>" form,
+	// e.g. "create foo-broker" or "remove-network tmctl-foo".
+	Calls []string
+	// Logs are the output lines of the containers by their names.
+	Logs map[string][]string
+	// StartErrors are returned on the start of the named containers.
+	StartErrors map[string]error
+	// Serve makes the running containers answer HTTP requests on their host ports.
+	Serve bool
+}
+
+// Container is the container created in the runtime.
+type Container struct {
+	ID         string
+	Name       string
+	Config     container.Config
+	HostConfig container.HostConfig
+	Running    bool
+	// Starts is the number of the container starts.
+	Starts int
+
+	servers []*http.Server
+}
+
+// New returns the empty runtime.
+func New() *Runtime {
+	return &Runtime{
+		Containers:  make(map[string]*Container),
+		Networks:    make(map[string]bool),
+		Logs:        make(map[string][]string),
+		StartErrors: make(map[string]error),
+	}
+}
+
+// Container returns the created container by its name, nil if it does not exist.
+func (r *Runtime) Container(name string) *Container {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Containers[name]
+}
+
+// CallsOf returns the recorded calls of the method.
+func (r *Runtime) CallsOf(method string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var calls []string
+	for _, call := range r.Calls {
+		if strings.HasPrefix(call, method+" ") {
+			calls = append(calls, strings.TrimPrefix(call, method+" "))
+		}
+	}
+	return calls
+}
+
+// Env returns the container environment as a map.
+func (c *Container) Env() map[string]string {
+	env := make(map[string]string, len(c.Config.Env))
+	for _, v := range c.Config.Env {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) == 2 {
+			env[kv[0]] = kv[1]
+		}
+	}
+	return env
+}
+
+// Hang stops answering HTTP requests as a hung process would,
+// the container keeps running.
+func (c *Container) Hang() {
+	for _, server := range c.servers {
+		server.Close()
+	}
+	c.servers = nil
+}
+
+// serve answers HTTP requests on the free host ports of the container.
+func (c *Container) serve() {
+	for _, bindings := range c.HostConfig.PortBindings {
+		for _, binding := range bindings {
+			l, err := net.Listen("tcp", "127.0.0.1:"+binding.HostPort)
+			if err != nil {
+				continue
+			}
+			server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})}
+			c.servers = append(c.servers, server)
+			go server.Serve(l)
+		}
+	}
+}
+
+// Close stops answering HTTP requests on the host ports of all containers.
+func (r *Runtime) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.Containers {
+		c.Hang()
+	}
+}
+
+// HostPort returns the host port bound to the container port, e.g. "8080/tcp".
+func (c *Container) HostPort(port string) string {
+	for _, binding := range c.HostConfig.PortBindings[nat.Port(port)] {
+		return binding.HostPort
+	}
+	return ""
+}
+
+func (r *Runtime) Version(ctx context.Context) (string, error) {
+	return "Fake Engine", nil
+}
+
+func (r *Runtime) PullImage(ctx context.Context, image string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.record("pull", image)
+	r.Images = append(r.Images, image)
+	return nil
+}
+
+func (r *Runtime) CreateContainer(ctx context.Context, name string, cc *container.Config, hc *container.HostConfig) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.record("create", name)
+	if _, exists := r.Containers[name]; exists {
+		return "", fmt.Errorf("container name %q is already in use", name)
+	}
+	if hc.NetworkMode != "" && hc.NetworkMode.IsUserDefined() && !r.Networks[string(hc.NetworkMode)] {
+		return "", fmt.Errorf("network %s not found", hc.NetworkMode)
+	}
+	c := &Container{
+		ID:         fmt.Sprintf("%s-%d", name, len(r.Calls)),
+		Name:       name,
+		Config:     *cc,
+		HostConfig: *hc,
+	}
+	r.Containers[name] = c
+	return c.ID, nil
+}
+
+func (r *Runtime) StartContainer(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := r.lookup(id)
+	if c == nil {
+		return fmt.Errorf("no such container: %s", id)
+	}
+	r.record("start", c.Name)
+	if err := r.StartErrors[c.Name]; err != nil {
+		return err
+	}
+	c.Running = true
+	c.Starts++
+	if r.Serve && c.servers == nil {
+		c.serve()
+	}
+	return nil
+}
+
+func (r *Runtime) InspectContainer(ctx context.Context, name string) (*docker.ContainerInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := r.lookup(name)
+	if c == nil {
+		return nil, fmt.Errorf("no such container: %s", name)
+	}
+	status := "created"
+	if c.Running {
+		status = "running"
+	}
+	return &docker.ContainerInfo{
+		ID:         c.ID,
+		Status:     status,
+		Running:    c.Running,
+		Config:     c.Config,
+		HostConfig: c.HostConfig,
+	}, nil
+}
+
+func (r *Runtime) RemoveContainer(ctx context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.record("remove", name)
+	if c := r.lookup(name); c != nil {
+		c.Hang()
+		delete(r.Containers, c.Name)
+	}
+	return nil
+}
+
+func (r *Runtime) ContainerLogs(ctx context.Context, id string, since time.Time, follow bool) (io.ReadCloser, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := r.lookup(id)
+	if c == nil {
+		return nil, fmt.Errorf("no such container: %s", id)
+	}
+	var buf bytes.Buffer
+	stdout := stdcopy.NewStdWriter(&buf, stdcopy.Stdout)
+	for _, line := range r.Logs[c.Name] {
+		if _, err := stdout.Write([]byte(line + "\n")); err != nil {
+			return nil, err
+		}
+	}
+	return io.NopCloser(&buf), nil
+}
+
+func (r *Runtime) EnsureNetwork(ctx context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.Networks[name] {
+		r.record("network", name)
+		r.Networks[name] = true
+	}
+	return nil
+}
+
+func (r *Runtime) RemoveNetwork(ctx context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.record("remove-network", name)
+	delete(r.Networks, name)
+	return nil
+}
+
+func (r *Runtime) RemoveVolume(ctx context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.record("remove-volume", name)
+	return nil
+}
+
+func (r *Runtime) HostGateway(ctx context.Context) (string, error) {
+	return "127.0.0.1", nil
+}
+
+func (r *Runtime) record(method, name string) {
+	r.Calls = append(r.Calls, method+" "+name)
+}
+
+// lookup returns the container by its name or ID.
+func (r *Runtime) lookup(nameOrID string) *Container {
+	if c, ok := r.Containers[nameOrID]; ok {
+		return c
+	}
+	for _, c := range r.Containers {
+		if c.ID == nameOrID {
+			return c
+		}
+	}
+	return nil
+}
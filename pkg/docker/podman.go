@@ -0,0 +1,500 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+var _ Runtime = (*podmanRuntime)(nil)
+
+const (
+	// libpodAPI is the versioned prefix of the libpod REST API paths.
+	libpodAPI = "http://podman/v4.0.0/libpod"
+
+	rootfulPodmanSocket  = "/run/podman/podman.sock"
+	defaultPodmanNetwork = "podman"
+)
+
+// podmanRuntime is the Podman runtime that talks to the libpod REST API
+// served on the unix socket, e.g. by "podman system service".
+type podmanRuntime struct {
+	client *http.Client
+}
+
+// podmanSpec is the subset of the libpod container specification.
+type podmanSpec struct {
+	Name          string                       `json:"name"`
+	Image         string                       `json:"image"`
+	Env           map[string]string            `json:"env,omitempty"`
+	Command       []string                     `json:"command,omitempty"`
+	Entrypoint    []string                     `json:"entrypoint,omitempty"`
+	Expose        map[uint16]string            `json:"expose,omitempty"`
+	PortMappings  []podmanPortMapping          `json:"portmappings,omitempty"`
+	NetNS         *podmanNamespace             `json:"netns,omitempty"`
+	Networks      map[string]map[string]string `json:"Networks,omitempty"`
+	Volumes       []podmanVolume               `json:"volumes,omitempty"`
+	Mounts        []podmanMount                `json:"mounts,omitempty"`
+	HostAdd       []string                     `json:"hostadd,omitempty"`
+	RestartPolicy string                       `json:"restart_policy,omitempty"`
+	RestartTries  *uint                        `json:"restart_tries,omitempty"`
+	HealthConfig  *podmanHealthConfig          `json:"healthconfig,omitempty"`
+}
+
+type podmanPortMapping struct {
+	ContainerPort uint16 `json:"container_port"`
+	HostPort      uint16 `json:"host_port"`
+	HostIP        string `json:"host_ip,omitempty"`
+	Protocol      string `json:"protocol,omitempty"`
+}
+
+type podmanNamespace struct {
+	NSMode string `json:"nsmode"`
+}
+
+type podmanVolume struct {
+	Name string
+	Dest string
+}
+
+type podmanMount struct {
+	Destination string   `json:"destination"`
+	Type        string   `json:"type"`
+	Source      string   `json:"source"`
+	Options     []string `json:"options,omitempty"`
+}
+
+type podmanHealthConfig struct {
+	Test     []string
+	Interval time.Duration
+	Timeout  time.Duration
+	Retries  int
+}
+
+type podmanHealth struct {
+	Status string
+	Log    []struct {
+		Output string
+	}
+}
+
+// podmanInspect is the subset of the libpod container inspect response.
+type podmanInspect struct {
+	ID           string `json:"Id"`
+	RestartCount int
+	State        struct {
+		Status     string
+		Running    bool
+		Restarting bool
+		Health     *podmanHealth
+		// podman 3 name of the health state
+		Healthcheck *podmanHealth
+	}
+	Config struct {
+		Image string
+		Env   []string
+	}
+	HostConfig struct {
+		NetworkMode   string
+		PortBindings  map[string][]nat.PortBinding
+		RestartPolicy container.RestartPolicy
+	}
+	NetworkSettings struct {
+		Networks map[string]json.RawMessage
+	}
+}
+
+type podmanError struct {
+	Message  string `json:"message"`
+	Response int    `json:"response"`
+}
+
+func newPodmanRuntime(socket string) (*podmanRuntime, error) {
+	if socket == "" {
+		socket = podmanSocket()
+	}
+	socket = strings.TrimPrefix(socket, "unix://")
+	if _, err := os.Stat(socket); err != nil {
+		return nil, fmt.Errorf("podman socket: %w", err)
+	}
+	return &podmanRuntime{
+		client: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}, nil
+}
+
+// podmanSocket returns the default Podman API socket, rootless
+// socket is preferred if the runtime directory is set.
+func podmanSocket() string {
+	if host := os.Getenv("CONTAINER_HOST"); strings.HasPrefix(host, "unix://") {
+		return host
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && os.Getuid() != 0 {
+		return filepath.Join(dir, "podman", "podman.sock")
+	}
+	return rootfulPodmanSocket
+}
+
+func (p *podmanRuntime) Version(ctx context.Context) (string, error) {
+	var version struct {
+		Version string
+	}
+	if err := p.do(ctx, http.MethodGet, "/version", nil, nil, &version); err != nil {
+		return "", err
+	}
+	return "Podman Engine " + version.Version, nil
+}
+
+func (p *podmanRuntime) PullImage(ctx context.Context, image string) error {
+	resp, err := p.request(ctx, http.MethodPost, "/images/pull", url.Values{"reference": {image}}, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var report struct {
+			Error string `json:"error"`
+		}
+		if err := decoder.Decode(&report); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if report.Error != "" {
+			return fmt.Errorf("%s", report.Error)
+		}
+	}
+}
+
+func (p *podmanRuntime) CreateContainer(ctx context.Context, name string, cc *container.Config, hc *container.HostConfig) (string, error) {
+	spec, err := podmanSpecFrom(name, cc, hc)
+	if err != nil {
+		return "", err
+	}
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := p.do(ctx, http.MethodPost, "/containers/create", nil, spec, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+func (p *podmanRuntime) StartContainer(ctx context.Context, id string) error {
+	return p.do(ctx, http.MethodPost, "/containers/"+id+"/start", nil, nil, nil)
+}
+
+func (p *podmanRuntime) InspectContainer(ctx context.Context, name string) (*ContainerInfo, error) {
+	var inspect podmanInspect
+	if err := p.do(ctx, http.MethodGet, "/containers/"+name+"/json", nil, nil, &inspect); err != nil {
+		return nil, err
+	}
+	return inspect.info(), nil
+}
+
+func (p *podmanRuntime) RemoveContainer(ctx context.Context, name string) error {
+	err := p.do(ctx, http.MethodDelete, "/containers/"+name, url.Values{"force": {"true"}, "v": {"true"}}, nil, nil)
+	if isPodmanStatus(err, http.StatusNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (p *podmanRuntime) ContainerLogs(ctx context.Context, id string, since time.Time, follow bool) (io.ReadCloser, error) {
+	query := url.Values{
+		"stdout": {"true"},
+		"stderr": {"true"},
+		"follow": {strconv.FormatBool(follow)},
+		"since":  {strconv.FormatInt(since.Unix(), 10)},
+	}
+	resp, err := p.request(ctx, http.MethodGet, "/containers/"+id+"/logs", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (p *podmanRuntime) EnsureNetwork(ctx context.Context, name string) error {
+	err := p.do(ctx, http.MethodGet, "/networks/"+name+"/json", nil, nil, nil)
+	if err == nil {
+		return nil
+	}
+	if !isPodmanStatus(err, http.StatusNotFound) {
+		return err
+	}
+	network := map[string]string{"name": name, "driver": "bridge"}
+	err = p.do(ctx, http.MethodPost, "/networks/create", nil, network, nil)
+	if isPodmanStatus(err, http.StatusConflict) {
+		// created concurrently
+		return nil
+	}
+	return err
+}
+
+func (p *podmanRuntime) RemoveNetwork(ctx context.Context, name string) error {
+	err := p.do(ctx, http.MethodDelete, "/networks/"+name, nil, nil, nil)
+	if isPodmanStatus(err, http.StatusNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (p *podmanRuntime) RemoveVolume(ctx context.Context, name string) error {
+	err := p.do(ctx, http.MethodDelete, "/volumes/"+name, url.Values{"force": {"true"}}, nil, nil)
+	if isPodmanStatus(err, http.StatusNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (p *podmanRuntime) HostGateway(ctx context.Context) (string, error) {
+	var info struct {
+		Host struct {
+			Security struct {
+				Rootless bool `json:"rootless"`
+			} `json:"security"`
+		} `json:"host"`
+	}
+	if err := p.do(ctx, http.MethodGet, "/info", nil, nil, &info); err != nil {
+		return "", err
+	}
+	// rootless network stack forwards host.containers.internal to the host loopback
+	if info.Host.Security.Rootless {
+		return loopbackAddress, nil
+	}
+	var network struct {
+		Subnets []struct {
+			Gateway string `json:"gateway"`
+		} `json:"subnets"`
+	}
+	if err := p.do(ctx, http.MethodGet, "/networks/podman/json", nil, nil, &network); err != nil {
+		return "", err
+	}
+	for _, subnet := range network.Subnets {
+		if subnet.Gateway != "" {
+			return subnet.Gateway, nil
+		}
+	}
+	return "", fmt.Errorf("podman network does not have the gateway")
+}
+
+// do sends the request and decodes the response into the result if it is not nil.
+func (p *podmanRuntime) do(ctx context.Context, method, path string, query url.Values, body, result interface{}) error {
+	resp, err := p.request(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// request sends the request to the libpod API, non successful
+// responses are returned as podmanError.
+func (p *podmanRuntime) request(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	u := libpodAPI + path
+	if len(query) != 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < http.StatusBadRequest {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	apiErr := &podmanError{Response: resp.StatusCode}
+	data, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(data, apiErr); err != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	return nil, apiErr
+}
+
+func (e *podmanError) Error() string {
+	return fmt.Sprintf("podman: %s", e.Message)
+}
+
+func isPodmanStatus(err error, status int) bool {
+	apiErr, ok := err.(*podmanError)
+	return ok && apiErr.Response == status
+}
+
+// podmanSpecFrom converts the Docker API container configuration
+// into the libpod container specification.
+func podmanSpecFrom(name string, cc *container.Config, hc *container.HostConfig) (*podmanSpec, error) {
+	spec := &podmanSpec{
+		Name:       name,
+		Image:      cc.Image,
+		Command:    cc.Cmd,
+		Entrypoint: cc.Entrypoint,
+		Env:        make(map[string]string, len(cc.Env)),
+	}
+	for _, env := range cc.Env {
+		kv := strings.SplitN(env, "=", 2)
+		if len(kv) == 2 {
+			spec.Env[kv[0]] = kv[1]
+		}
+	}
+	for port := range cc.ExposedPorts {
+		if spec.Expose == nil {
+			spec.Expose = make(map[uint16]string)
+		}
+		spec.Expose[uint16(port.Int())] = port.Proto()
+	}
+	for port, bindings := range hc.PortBindings {
+		for _, binding := range bindings {
+			hostPort, err := strconv.ParseUint(binding.HostPort, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("host port %q: %w", binding.HostPort, err)
+			}
+			spec.PortMappings = append(spec.PortMappings, podmanPortMapping{
+				ContainerPort: uint16(port.Int()),
+				HostPort:      uint16(hostPort),
+				HostIP:        binding.HostIP,
+				Protocol:      port.Proto(),
+			})
+		}
+	}
+	if hc.NetworkMode.IsUserDefined() {
+		spec.NetNS = &podmanNamespace{NSMode: "bridge"}
+		spec.Networks = map[string]map[string]string{string(hc.NetworkMode): {}}
+	}
+	for _, bind := range hc.Binds {
+		parts := strings.Split(bind, ":")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("malformed bind %q", bind)
+		}
+		if filepath.IsAbs(parts[0]) {
+			spec.Mounts = append(spec.Mounts, podmanMount{
+				Destination: parts[1],
+				Type:        "bind",
+				Source:      parts[0],
+				Options:     parts[2:],
+			})
+			continue
+		}
+		spec.Volumes = append(spec.Volumes, podmanVolume{Name: parts[0], Dest: parts[1]})
+	}
+	for _, host := range hc.ExtraHosts {
+		// Podman adds the host.containers.internal and
+		// host.docker.internal entries itself
+		if strings.HasSuffix(host, ":host-gateway") {
+			continue
+		}
+		spec.HostAdd = append(spec.HostAdd, host)
+	}
+	if policy := hc.RestartPolicy.Name; policy != "" && policy != "no" {
+		spec.RestartPolicy = policy
+		if hc.RestartPolicy.MaximumRetryCount != 0 {
+			tries := uint(hc.RestartPolicy.MaximumRetryCount)
+			spec.RestartTries = &tries
+		}
+	}
+	if hcheck := cc.Healthcheck; hcheck != nil {
+		spec.HealthConfig = &podmanHealthConfig{
+			Test:     hcheck.Test,
+			Interval: hcheck.Interval,
+			Timeout:  hcheck.Timeout,
+			Retries:  hcheck.Retries,
+		}
+	}
+	return spec, nil
+}
+
+// info converts the libpod inspect response into the runtime container state.
+func (i podmanInspect) info() *ContainerInfo {
+	info := &ContainerInfo{
+		ID:           i.ID,
+		Status:       i.State.Status,
+		Running:      i.State.Running,
+		Restarting:   i.State.Restarting,
+		RestartCount: i.RestartCount,
+		Config: container.Config{
+			Image: i.Config.Image,
+			Env:   i.Config.Env,
+		},
+		HostConfig: container.HostConfig{
+			NetworkMode:   container.NetworkMode(i.HostConfig.NetworkMode),
+			RestartPolicy: i.HostConfig.RestartPolicy,
+			PortBindings:  make(nat.PortMap, len(i.HostConfig.PortBindings)),
+		},
+	}
+	for port, bindings := range i.HostConfig.PortBindings {
+		info.HostConfig.PortBindings[nat.Port(port)] = bindings
+	}
+	// Podman reports the bridge mode for the containers in the named networks
+	if len(i.NetworkSettings.Networks) == 1 {
+		for network := range i.NetworkSettings.Networks {
+			if network != defaultPodmanNetwork {
+				info.HostConfig.NetworkMode = container.NetworkMode(network)
+			}
+		}
+	}
+	health := i.State.Health
+	if health == nil {
+		health = i.State.Healthcheck
+	}
+	if health != nil && health.Status != "" {
+		info.Health = health.Status
+		if len(health.Log) != 0 {
+			info.HealthLog = strings.TrimSpace(health.Log[len(health.Log)-1].Output)
+		}
+	}
+	return info
+}
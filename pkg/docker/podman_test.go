@@ -0,0 +1,161 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPodmanSpecFrom(t *testing.T) {
+	cc := &container.Config{}
+	for _, opt := range []ContainerOption{
+		WithImage("foo/bar:v1"),
+		WithEnv([]string{"K_SINK=http://foo-broker:8080", "EMPTY="}),
+		WithPort("8080/tcp"),
+		WithEntrypoint([]string{"/broker"}),
+		WithCmd([]string{"start"}),
+		WithHealthCheck([]string{"redis-cli", "ping"}, time.Second, 2*time.Second, 3),
+	} {
+		opt(cc)
+	}
+	hc := &container.HostConfig{}
+	for _, opt := range []HostOption{
+		WithStaticHostPortBinding("8080/tcp", "18080"),
+		WithNetwork(NetworkName("foo")),
+		WithExtraHost(),
+		WithRestartPolicy("on-failure"),
+	} {
+		opt(hc)
+	}
+	hc.Binds = []string{"/home/foo/broker.conf:/etc/triggermesh/broker.conf", "foo-redis-data:/data"}
+
+	spec, err := podmanSpecFrom("foo-broker", cc, hc)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo-broker", spec.Name)
+	assert.Equal(t, "foo/bar:v1", spec.Image)
+	assert.Equal(t, map[string]string{"K_SINK": "http://foo-broker:8080", "EMPTY": ""}, spec.Env)
+	assert.Equal(t, []string{"/broker"}, spec.Entrypoint)
+	assert.Equal(t, []string{"start"}, spec.Command)
+	assert.Equal(t, map[uint16]string{8080: "tcp"}, spec.Expose)
+	assert.Equal(t, []podmanPortMapping{{ContainerPort: 8080, HostPort: 18080, HostIP: "0.0.0.0", Protocol: "tcp"}}, spec.PortMappings)
+	assert.Equal(t, "bridge", spec.NetNS.NSMode)
+	assert.Contains(t, spec.Networks, "tmctl-foo")
+	assert.Equal(t, []podmanMount{{Destination: "/etc/triggermesh/broker.conf", Type: "bind", Source: "/home/foo/broker.conf", Options: []string{}}}, spec.Mounts)
+	assert.Equal(t, []podmanVolume{{Name: "foo-redis-data", Dest: "/data"}}, spec.Volumes)
+	assert.Empty(t, spec.HostAdd)
+	assert.Equal(t, "on-failure", spec.RestartPolicy)
+	assert.Equal(t, []string{"CMD", "redis-cli", "ping"}, spec.HealthConfig.Test)
+	assert.Equal(t, 3, spec.HealthConfig.Retries)
+
+	hc.PortBindings["8080/tcp"][0].HostPort = "foo"
+	_, err = podmanSpecFrom("foo-broker", cc, hc)
+	assert.Error(t, err)
+}
+
+func TestPodmanRuntime(t *testing.T) {
+	var networkCreated bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/version", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"Version":"4.4.1"}`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/containers/foo-broker/json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{
+			"Id": "abc",
+			"RestartCount": 2,
+			"State": {"Status": "running", "Running": true, "Health": {"Status": "healthy", "Log": [{"Output": "PONG\n"}]}},
+			"Config": {"Image": "foo/bar:v1"},
+			"HostConfig": {"NetworkMode": "bridge", "PortBindings": {"8080/tcp": [{"HostIp": "0.0.0.0", "HostPort": "18080"}]}},
+			"NetworkSettings": {"Networks": {"tmctl-foo": {}}}
+		}`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/containers/missing", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		assert.Equal(t, "true", r.URL.Query().Get("force"))
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"cause":"no such container","message":"no container with name or ID \"missing\" found","response":404}`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/networks/tmctl-foo/json", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/v4.0.0/libpod/networks/create", func(w http.ResponseWriter, r *http.Request) {
+		var network map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&network))
+		assert.Equal(t, map[string]string{"name": "tmctl-foo", "driver": "bridge"}, network)
+		networkCreated = true
+	})
+	mux.HandleFunc("/v4.0.0/libpod/info", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"host":{"security":{"rootless":false}}}`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/networks/podman/json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"podman","subnets":[{"subnet":"10.88.0.0/16","gateway":"10.88.0.1"}]}`))
+	})
+	mux.HandleFunc("/v4.0.0/libpod/containers/create", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"message":"image not known","response":500}`))
+	})
+
+	socket := filepath.Join(t.TempDir(), "podman.sock")
+	listener, err := net.Listen("unix", socket)
+	assert.NoError(t, err)
+	server := httptest.NewUnstartedServer(mux)
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	runtime, err := newPodmanRuntime("unix://" + socket)
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	version, err := runtime.Version(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "Podman Engine 4.4.1", version)
+
+	info, err := runtime.InspectContainer(ctx, "foo-broker")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", info.ID)
+	assert.True(t, info.Running)
+	assert.Equal(t, 2, info.RestartCount)
+	assert.Equal(t, "healthy", info.Health)
+	assert.Equal(t, "PONG", info.HealthLog)
+	assert.Equal(t, container.NetworkMode("tmctl-foo"), info.HostConfig.NetworkMode)
+	assert.Equal(t, "18080", info.HostConfig.PortBindings[nat.Port("8080/tcp")][0].HostPort)
+
+	assert.NoError(t, runtime.RemoveContainer(ctx, "missing"))
+	assert.NoError(t, runtime.EnsureNetwork(ctx, "tmctl-foo"))
+	assert.True(t, networkCreated)
+
+	gateway, err := runtime.HostGateway(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.88.0.1", gateway)
+
+	_, err = runtime.CreateContainer(ctx, "foo", &container.Config{Image: "foo"}, &container.HostConfig{})
+	assert.EqualError(t, err, "podman: image not known")
+
+	_, err = newPodmanRuntime(filepath.Join(t.TempDir(), "missing.sock"))
+	assert.Error(t, err)
+}
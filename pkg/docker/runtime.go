@@ -0,0 +1,134 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+)
+
+const (
+	// RuntimeDocker is the Docker Engine runtime.
+	RuntimeDocker = "docker"
+	// RuntimePodman is the Podman runtime served over the libpod REST API.
+	RuntimePodman = "podman"
+)
+
+// Runtime is the container engine that runs the components containers.
+// Containers are described with the Docker API types regardless of the runtime.
+type Runtime interface {
+	// Version returns the runtime name and version.
+	Version(ctx context.Context) (string, error)
+	// PullImage downloads the container image.
+	PullImage(ctx context.Context, image string) error
+	// CreateContainer creates the named container and returns its ID.
+	CreateContainer(ctx context.Context, name string, cc *container.Config, hc *container.HostConfig) (string, error)
+	// StartContainer starts the created container.
+	StartContainer(ctx context.Context, id string) error
+	// InspectContainer returns the state of the container by its name or ID.
+	InspectContainer(ctx context.Context, name string) (*ContainerInfo, error)
+	// RemoveContainer force removes the container, missing container is not an error.
+	RemoveContainer(ctx context.Context, name string) error
+	// ContainerLogs returns the container output stream in the Docker multiplexed format.
+	ContainerLogs(ctx context.Context, id string, since time.Time, follow bool) (io.ReadCloser, error)
+	// EnsureNetwork creates the bridge network if it does not exist.
+	EnsureNetwork(ctx context.Context, name string) error
+	// RemoveNetwork deletes the network, missing network is not an error.
+	RemoveNetwork(ctx context.Context, name string) error
+	// RemoveVolume deletes the named volume, missing volume is not an error.
+	RemoveVolume(ctx context.Context, name string) error
+	// HostGateway returns the host address that the containers
+	// reach through the host.docker.internal name.
+	HostGateway(ctx context.Context) (string, error)
+}
+
+// loopbackAddress is the host gateway of the runtimes
+// that forward host.docker.internal to the host loopback.
+const loopbackAddress = "127.0.0.1"
+
+// ContainerInfo is the container state reported by the runtime.
+type ContainerInfo struct {
+	ID           string
+	Status       string
+	Running      bool
+	Restarting   bool
+	RestartCount int
+	// Health is the health check status, empty if
+	// the container does not have the health check.
+	Health    string
+	HealthLog string
+
+	Config     container.Config
+	HostConfig container.HostConfig
+}
+
+// runtimeOverride replaces the configured runtime, see UseRuntime.
+var runtimeOverride Runtime
+
+// UseRuntime makes NewRuntime return the runtime instead of the configured
+// one, e.g. the in-memory runtime in tests, and disables the waiting periods
+// of the container start. The returned function restores the defaults.
+func UseRuntime(runtime Runtime) func() {
+	logsWait, pollPeriod := initLogsWaitPeriod, statePollPeriod
+	runtimeOverride = runtime
+	initLogsWaitPeriod, statePollPeriod = 0, time.Millisecond
+	return func() {
+		runtimeOverride = nil
+		initLogsWaitPeriod, statePollPeriod = logsWait, pollPeriod
+	}
+}
+
+// NewRuntime returns the container runtime selected in the configuration,
+// Docker is used if the configuration does not exist yet.
+func NewRuntime() (Runtime, error) {
+	if runtimeOverride != nil {
+		return runtimeOverride, nil
+	}
+	name, err := config.Get("docker.runtime")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("config read: %w", err)
+	}
+	socket, err := config.Get("docker.socket")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("config read: %w", err)
+	}
+	switch name {
+	case "", RuntimeDocker:
+		return newDockerRuntime(socket)
+	case RuntimePodman:
+		return newPodmanRuntime(socket)
+	}
+	return nil, fmt.Errorf("unsupported container runtime %q, expected %q or %q", name, RuntimeDocker, RuntimePodman)
+}
+
+// CheckDaemon verifies that the container runtime is available.
+func CheckDaemon() error {
+	runtime, err := NewRuntime()
+	if err != nil {
+		return err
+	}
+	_, err = runtime.Version(context.Background())
+	return err
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package graph builds the event flow graph of the broker components
+// and derives the order in which the components must be started.
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/service"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+// Node is the broker component in the event flow graph.
+type Node struct {
+	Component triggermesh.Component
+
+	// EventTypes is the list of event types produced by the component,
+	// empty list means that produced types are unknown.
+	EventTypes []string
+	// ConsumedEventTypes is the list of event types accepted by the consumer.
+	ConsumedEventTypes []string
+	// Filters, CESQL and Target are set for the trigger nodes.
+	Filters []eventingbroker.Filter
+	CESQL   string
+	Target  string
+
+	producer bool
+	consumer bool
+}
+
+// Graph is the event flow graph of the broker components.
+type Graph struct {
+	Broker *Node
+	Nodes  []*Node
+
+	// dependencies holds the names of the nodes
+	// that must be started before the key node.
+	dependencies map[string][]string
+}
+
+// IsProducer returns true if the node sends events to the broker.
+func (n *Node) IsProducer() bool {
+	return n.producer
+}
+
+// IsConsumer returns true if the node receives events from the broker.
+func (n *Node) IsConsumer() bool {
+	return n.consumer
+}
+
+// IsTrigger returns true if the node is the broker trigger.
+func (n *Node) IsTrigger() bool {
+	return n.Component.GetKind() == tmbroker.TriggerKind
+}
+
+// New creates the graph of the manifest components. The secrets are not included.
+func New(m *manifest.Manifest, config *config.Config, crds map[string]crd.CRD) (*Graph, error) {
+	g := &Graph{
+		dependencies: make(map[string][]string),
+	}
+	for _, object := range m.Objects {
+		if object.Kind == "Secret" {
+			continue
+		}
+		c, err := components.GetObject(object.Metadata.Name, config, m, crds)
+		if err != nil {
+			return nil, fmt.Errorf("%q component: %w", object.Metadata.Name, err)
+		}
+		if c == nil {
+			continue
+		}
+		node := &Node{Component: c}
+		switch {
+		case object.Kind == tmbroker.BrokerKind:
+			g.Broker = node
+			continue
+		case object.Kind == tmbroker.TriggerKind:
+			trigger := c.(*tmbroker.Trigger)
+			// broker configuration has the actual filters
+			trigger.LookupTarget()
+			node.Filters = trigger.Filters
+			node.CESQL = trigger.CESQL
+			if trigger.Target.Ref != nil {
+				node.Target = trigger.Target.Ref.Name
+			}
+		default:
+			svc, isService := c.(*service.Service)
+			if producer, ok := c.(triggermesh.Producer); ok && (!isService || svc.IsSource()) {
+				node.producer = true
+				node.EventTypes, _ = producer.GetEventTypes()
+			}
+			if consumer, ok := c.(triggermesh.Consumer); ok && (!isService || svc.IsTarget()) {
+				node.consumer = true
+				node.ConsumedEventTypes, _ = consumer.ConsumedEventTypes()
+			}
+		}
+		g.Nodes = append(g.Nodes, node)
+	}
+
+	for _, node := range g.Nodes {
+		if node.IsTrigger() {
+			if node.Target != "" {
+				g.dependencies[node.Component.GetName()] = []string{node.Target}
+			}
+			continue
+		}
+		if !node.producer {
+			continue
+		}
+		// producer must not emit events until the triggers that receive them are ready
+		for _, trigger := range g.Nodes {
+			if trigger.IsTrigger() && trigger.Target != node.Component.GetName() && receives(trigger.Filters, node.EventTypes) {
+				g.dependencies[node.Component.GetName()] = append(g.dependencies[node.Component.GetName()], trigger.Component.GetName())
+			}
+		}
+	}
+	return g, nil
+}
+
+// Node returns the graph node by the component name.
+func (g *Graph) Node(name string) *Node {
+	if g.Broker != nil && g.Broker.Component.GetName() == name {
+		return g.Broker
+	}
+	for _, node := range g.Nodes {
+		if node.Component.GetName() == name {
+			return node
+		}
+	}
+	return nil
+}
+
+// Dependencies returns the names of the components
+// that must be started before the named component.
+func (g *Graph) Dependencies(name string) []string {
+	return g.dependencies[name]
+}
+
+// Levels returns the graph nodes grouped in the start order: every node depends only
+// on the nodes from the previous levels, nodes of the same level are independent.
+// Targets come before the triggers and the triggers before the sources. Broker
+// is not included as it must be started before anything else. Circular
+// dependencies are resolved by the order of the components in the manifest.
+func (g *Graph) Levels() [][]*Node {
+	var levels [][]*Node
+	started := make(map[string]bool, len(g.Nodes))
+	for len(started) != len(g.Nodes) {
+		var level []*Node
+		for _, node := range g.Nodes {
+			if started[node.Component.GetName()] {
+				continue
+			}
+			ready := true
+			for _, dependency := range g.dependencies[node.Component.GetName()] {
+				if !started[dependency] && g.Node(dependency) != nil {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				level = append(level, node)
+			}
+		}
+		if len(level) == 0 {
+			// dependency cycle, take the first node in the manifest
+			for _, node := range g.Nodes {
+				if !started[node.Component.GetName()] {
+					level = append(level, node)
+					break
+				}
+			}
+		}
+		for _, node := range level {
+			started[node.Component.GetName()] = true
+		}
+		levels = append(levels, level)
+	}
+	return levels
+}
+
+// StartOrder returns the flat list of nodes in the order they must be started,
+// broker is the first node in the list.
+func (g *Graph) StartOrder() []*Node {
+	var result []*Node
+	if g.Broker != nil {
+		result = append(result, g.Broker)
+	}
+	for _, level := range g.Levels() {
+		result = append(result, level...)
+	}
+	return result
+}
+
+// StopOrder returns the nodes in the reverse start order, broker is the last node in the list.
+func (g *Graph) StopOrder() []*Node {
+	order := g.StartOrder()
+	result := make([]*Node, 0, len(order))
+	for i := len(order) - 1; i >= 0; i-- {
+		result = append(result, order[i])
+	}
+	return result
+}
+
+// receives returns true if the trigger filters may pass any of the event types.
+// Unknown event types are considered to pass any filter.
+func receives(filters []eventingbroker.Filter, eventTypes []string) bool {
+	if len(eventTypes) == 0 {
+		return true
+	}
+	for _, eventType := range eventTypes {
+		passed := true
+		for _, filter := range filters {
+			if !passesType(filter, eventType) {
+				passed = false
+				break
+			}
+		}
+		if passed {
+			return true
+		}
+	}
+	return false
+}
+
+// passesType evaluates filter against the event type. Expressions
+// on other attributes can not be evaluated and are considered true.
+func passesType(filter eventingbroker.Filter, eventType string) bool {
+	if value, set := filter.Exact["type"]; set && value != eventType {
+		return false
+	}
+	if value, set := filter.Prefix["type"]; set && !strings.HasPrefix(eventType, value) {
+		return false
+	}
+	if value, set := filter.Suffix["type"]; set && !strings.HasSuffix(eventType, value) {
+		return false
+	}
+	for _, f := range filter.All {
+		if !passesType(f, eventType) {
+			return false
+		}
+	}
+	if len(filter.Any) != 0 {
+		passed := false
+		for _, f := range filter.Any {
+			if passesType(f, eventType) {
+				passed = true
+				break
+			}
+		}
+		if !passed {
+			return false
+		}
+	}
+	if filter.Not != nil && typeOnly(*filter.Not) && passesType(*filter.Not, eventType) {
+		return false
+	}
+	return true
+}
+
+// typeOnly returns true if the filter expressions use the type attribute only.
+func typeOnly(filter eventingbroker.Filter) bool {
+	for _, attributes := range []map[string]string{filter.Exact, filter.Prefix, filter.Suffix} {
+		for attribute := range attributes {
+			if attribute != "type" {
+				return false
+			}
+		}
+	}
+	for _, f := range append(filter.All, filter.Any...) {
+		if !typeOnly(f) {
+			return false
+		}
+	}
+	if filter.Not != nil {
+		return typeOnly(*filter.Not)
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/test"
+)
+
+func TestStartOrder(t *testing.T) {
+	m := manifest.New(test.Manifest())
+	assert.NoError(t, m.Read())
+	c := &config.Config{
+		Triggermesh: config.TmConfig{ComponentsVersion: "v1.21.1"},
+	}
+	g, err := New(m, c, test.CRD())
+	assert.NoError(t, err)
+
+	names := func(nodes []*Node) []string {
+		var result []string
+		for _, n := range nodes {
+			result = append(result, n.Component.GetName())
+		}
+		return result
+	}
+
+	expected := []string{
+		"foo",
+		"sockeye",
+		"foo-trigger-9dad7875",
+		"foo-transformation",
+		"foo-trigger-6ada801c",
+		"foo-awss3source",
+	}
+	assert.Equal(t, expected, names(g.StartOrder()))
+
+	for i, j := 0, len(expected)-1; i < j; i, j = i+1, j-1 {
+		expected[i], expected[j] = expected[j], expected[i]
+	}
+	assert.Equal(t, expected, names(g.StopOrder()))
+}
+
+func TestReceives(t *testing.T) {
+	cases := map[string]struct {
+		filters    []eventingbroker.Filter
+		eventTypes []string
+		expected   bool
+	}{
+		"no filters": {
+			eventTypes: []string{"foo"},
+			expected:   true,
+		},
+		"unknown types": {
+			filters:  []eventingbroker.Filter{{Exact: map[string]string{"type": "foo"}}},
+			expected: true,
+		},
+		"exact type": {
+			filters:    []eventingbroker.Filter{{Exact: map[string]string{"type": "foo"}}},
+			eventTypes: []string{"bar", "foo"},
+			expected:   true,
+		},
+		"type mismatch": {
+			filters:    []eventingbroker.Filter{{Prefix: map[string]string{"type": "foo"}}},
+			eventTypes: []string{"bar"},
+			expected:   false,
+		},
+		"other attributes": {
+			filters:    []eventingbroker.Filter{{Exact: map[string]string{"source": "foo"}}},
+			eventTypes: []string{"bar"},
+			expected:   true,
+		},
+		"negation": {
+			filters:    []eventingbroker.Filter{{Not: &eventingbroker.Filter{Suffix: map[string]string{"type": "bar"}}}},
+			eventTypes: []string{"foo.bar"},
+			expected:   false,
+		},
+		"any": {
+			filters: []eventingbroker.Filter{{Any: []eventingbroker.Filter{
+				{Exact: map[string]string{"type": "foo"}},
+				{Exact: map[string]string{"type": "bar"}},
+			}}},
+			eventTypes: []string{"bar"},
+			expected:   true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, receives(tc.filters, tc.eventTypes))
+		})
+	}
+}
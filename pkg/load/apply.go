@@ -0,0 +1,246 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package load
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/triggermesh/tmctl/cmd/delete"
+	"github.com/triggermesh/tmctl/cmd/describe"
+	"github.com/triggermesh/tmctl/cmd/start"
+	cliconfig "github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/graph"
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+// Apply reconciles the broker described in the manifest with its local state.
+// Objects missing in the manifest are removed, new and modified components
+// are (re)started, unchanged components are started only if they are not running.
+func Apply(from string, config *cliconfig.Config, crd map[string]crd.CRD) error {
+	desired, err := Manifest(from)
+	if err != nil {
+		return fmt.Errorf("manifest %q: %w", from, err)
+	}
+
+	contextName := ""
+	for _, object := range desired.Objects {
+		if object.Kind == tmbroker.BrokerKind {
+			contextName = object.Metadata.Name
+			break
+		}
+	}
+	if contextName == "" {
+		return fmt.Errorf("manifest %q does not contain the broker", from)
+	}
+	if _, err := tmbroker.CreateBrokerConfig(config.ConfigHome, contextName); err != nil {
+		return fmt.Errorf("creating broker object: %w", err)
+	}
+	config.Context = contextName
+
+	path := filepath.Join(config.ConfigHome, contextName, triggermesh.ManifestFile)
+	current := manifest.New(path)
+	// manifest may not exist if the broker is new
+	_ = current.Read()
+	for i := range current.Objects {
+		current.Objects[i].Metadata.Namespace = ""
+	}
+	desired.Path = path
+
+	// fill in user input, keeping the values that were provided earlier
+	for i, object := range desired.Objects {
+		component, err := components.GetObject(object.Metadata.Name, config, desired, crd)
+		if err != nil {
+			return err
+		}
+		if component == nil {
+			return fmt.Errorf("unsupported object %q of kind %q", object.Metadata.Name, object.Kind)
+		}
+		spec := component.GetSpec()
+		if existing, _ := components.GetObject(object.Metadata.Name, config, current, crd); existing != nil {
+			spec = keepUserInput(spec, existing.GetSpec())
+		}
+		filledSpec, err := parseUserInputTags(component.GetName(), component.GetKind(), spec)
+		if err != nil {
+			return err
+		}
+		component.SetSpec(filledSpec)
+		newObj, err := component.AsK8sObject()
+		if err != nil {
+			return err
+		}
+		newObj.Metadata.Namespace = "" // local manifest should not set namespace
+		keepExternalResources(&newObj, current)
+		desired.Objects[i] = newObj
+	}
+
+	diff := manifest.Diff(current.Objects, desired.Objects)
+	if len(diff) == 0 {
+		log.Printf("Broker %q is up to date", contextName)
+	}
+
+	var removed []string
+	restart := make(map[string]bool, len(diff))
+	for _, d := range diff {
+		name := d.Object.Metadata.Name
+		log.Printf("%s %s %q", operationVerb(d.Operation), strings.ToLower(d.Object.Kind), name)
+		switch {
+		case d.Operation == manifest.Delete:
+			removed = append(removed, name)
+		case d.Object.Kind == "Secret":
+			restart[strings.TrimSuffix(name, "-secret")] = true
+		case d.Object.Kind == tmbroker.TriggerKind:
+			trigger, err := components.GetObject(name, config, desired, crd)
+			if err != nil {
+				return fmt.Errorf("trigger %q: %w", name, err)
+			}
+			if err := trigger.(*tmbroker.Trigger).WriteLocalConfig(); err != nil {
+				return fmt.Errorf("updating broker config: %w", err)
+			}
+		default:
+			restart[name] = true
+		}
+	}
+
+	if len(removed) != 0 {
+		if err := (&delete.CliOptions{
+			Config:   config,
+			Manifest: current,
+			CRD:      crd,
+		}).DeleteComponents(removed); err != nil {
+			return fmt.Errorf("removing components: %w", err)
+		}
+	}
+
+	if err := desired.Write(); err != nil {
+		return err
+	}
+
+	if err := startComponents(config, desired, crd, restart); err != nil {
+		return err
+	}
+
+	_ = (&describe.CliOptions{
+		Config:   config,
+		Manifest: desired,
+		CRD:      crd,
+	}).Describe()
+
+	log.Printf("Done. Switching context to %q", contextName)
+	return cliconfig.Set("context", contextName)
+}
+
+// startComponents restarts changed components and starts the ones that are not running.
+func startComponents(config *cliconfig.Config, m *manifest.Manifest, crd map[string]crd.CRD, restart map[string]bool) error {
+	ctx := context.Background()
+	s := &start.CliOptions{
+		Config:   config,
+		Manifest: m,
+		CRD:      crd,
+
+		Parallel: start.DefaultParallel,
+	}
+	g, err := graph.New(m, config, crd)
+	if err != nil {
+		return fmt.Errorf("components graph: %w", err)
+	}
+	if err := s.StartBroker(ctx, restart[config.Context]); err != nil {
+		return err
+	}
+	var levels [][]*graph.Node
+	for _, level := range g.Levels() {
+		var nodes []*graph.Node
+		for _, node := range level {
+			runnable, ok := node.Component.(triggermesh.Runnable)
+			if !ok {
+				continue
+			}
+			if !restart[node.Component.GetName()] {
+				if container, err := runnable.Info(ctx); err == nil && container.Online {
+					continue
+				}
+			}
+			nodes = append(nodes, node)
+		}
+		if len(nodes) != 0 {
+			levels = append(levels, nodes)
+		}
+	}
+	return s.StartComponents(ctx, g, levels, func(name string) bool { return restart[name] })
+}
+
+// keepUserInput replaces user input tags in the spec
+// with the values from the existing spec of the same object.
+func keepUserInput(spec, existing map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(spec))
+	for key, value := range spec {
+		result[key] = value
+		switch v := value.(type) {
+		case string:
+			if v != triggermesh.UserInputTag {
+				continue
+			}
+			if e, ok := existing[key].(string); ok && e != triggermesh.UserInputTag {
+				result[key] = e
+			}
+		case map[string]interface{}:
+			if e, ok := existing[key].(map[string]interface{}); ok {
+				result[key] = keepUserInput(v, e)
+			}
+		}
+	}
+	return result
+}
+
+// keepExternalResources preserves the annotation with the external resources
+// created for the object so that they can be finalized later.
+func keepExternalResources(object *kubernetes.Object, m *manifest.Manifest) {
+	if _, set := object.Metadata.Annotations[triggermesh.ExternalResourcesAnnotation]; set {
+		return
+	}
+	for _, o := range m.Objects {
+		if o.Metadata.Name != object.Metadata.Name || o.Kind != object.Kind {
+			continue
+		}
+		if resources, set := o.Metadata.Annotations[triggermesh.ExternalResourcesAnnotation]; set {
+			if object.Metadata.Annotations == nil {
+				object.Metadata.Annotations = make(map[string]string, 1)
+			}
+			object.Metadata.Annotations[triggermesh.ExternalResourcesAnnotation] = resources
+		}
+	}
+}
+
+func operationVerb(o manifest.Operation) string {
+	switch o {
+	case manifest.Create:
+		return "Creating"
+	case manifest.Update:
+		return "Updating"
+	case manifest.Delete:
+		return "Removing"
+	}
+	return string(o)
+}
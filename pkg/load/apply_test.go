@@ -0,0 +1,149 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package load
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/docker/fake"
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/test"
+)
+
+// fixtureManifest writes the fixture manifest without the AWS source
+// to the temporary file and returns it.
+func fixtureManifest(t *testing.T) *manifest.Manifest {
+	data, err := os.ReadFile(test.Manifest())
+	assert.NoError(t, err)
+	m := manifest.New(filepath.Join(t.TempDir(), "manifest.yaml"))
+	assert.NoError(t, os.WriteFile(m.Path, data, 0644))
+	assert.NoError(t, m.Read())
+	assert.NoError(t, m.Remove("foo-awss3source", "AWSS3Source"))
+	assert.NoError(t, m.Remove("foo-awss3source-secret", "Secret"))
+	return m
+}
+
+func sockeyeSecret(value string) kubernetes.Object {
+	return kubernetes.Object{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata: kubernetes.Metadata{
+			Name:   "sockeye-secret",
+			Labels: map[string]string{"triggermesh.io/context": "foo"},
+		},
+		Data: map[string]string{"TOKEN": base64.StdEncoding.EncodeToString([]byte(value))},
+		Type: "Opaque",
+	}
+}
+
+func TestApply(t *testing.T) {
+	runtime, c := fake.Setup(t)
+	crds := test.CRD()
+	m := fixtureManifest(t)
+	assert.NoError(t, m.Write())
+
+	assert.NoError(t, Apply(m.Path, c, crds))
+	assert.ElementsMatch(t, []string{"foo-broker", "sockeye", "foo-transformation"}, runtime.CallsOf("create"))
+	local := manifest.New(filepath.Join(c.ConfigHome, "foo", triggermesh.ManifestFile))
+	assert.NoError(t, local.Read())
+	assert.Len(t, local.Objects, len(m.Objects))
+
+	// unchanged running components are not recreated
+	assert.NoError(t, Apply(m.Path, c, crds))
+	assert.Len(t, runtime.CallsOf("create"), 3)
+
+	// only the modified component is recreated
+	for i, object := range m.Objects {
+		if object.Metadata.Name == "foo-transformation" {
+			m.Objects[i].Spec["data"] = []interface{}{}
+		}
+	}
+	assert.NoError(t, m.Write())
+	assert.NoError(t, Apply(m.Path, c, crds))
+	assert.Equal(t, []string{"foo-broker", "sockeye", "foo-transformation", "foo-transformation"}, runtime.CallsOf("create"))
+
+	// removed component is deleted
+	assert.NoError(t, m.Remove("foo-transformation", "Transformation"))
+	assert.NoError(t, m.Remove("foo-trigger-6ada801c", "Trigger"))
+	assert.NoError(t, m.Write())
+	assert.NoError(t, Apply(m.Path, c, crds))
+	assert.Nil(t, runtime.Container("foo-transformation"))
+	assert.Len(t, runtime.CallsOf("create"), 4)
+}
+
+func TestApplySecretRestartsComponent(t *testing.T) {
+	runtime, c := fake.Setup(t)
+	crds := test.CRD()
+	m := fixtureManifest(t)
+	m.Objects = append(m.Objects, sockeyeSecret("foo"))
+	assert.NoError(t, m.Write())
+	assert.NoError(t, Apply(m.Path, c, crds))
+
+	m.Objects[len(m.Objects)-1] = sockeyeSecret("bar")
+	assert.NoError(t, m.Write())
+	assert.NoError(t, Apply(m.Path, c, crds))
+
+	// secret changes restart the component that owns the secret
+	assert.Equal(t, []string{"foo-broker", "sockeye", "foo-transformation", "sockeye"}, runtime.CallsOf("create"))
+}
+
+func TestStartComponentsRestartsBroker(t *testing.T) {
+	runtime, c := fake.Setup(t)
+	crds := test.CRD()
+	m := fixtureManifest(t)
+	assert.NoError(t, m.Write())
+	assert.NoError(t, Apply(m.Path, c, crds))
+
+	assert.NoError(t, startComponents(c, m, crds, map[string]bool{"foo": true}))
+	assert.Equal(t, []string{"foo-broker", "sockeye", "foo-transformation", "foo-broker"}, runtime.CallsOf("create"))
+}
+
+func TestKeepUserInput(t *testing.T) {
+	spec := map[string]interface{}{
+		"arn":   triggermesh.UserInputTag,
+		"topic": triggermesh.UserInputTag,
+		"plain": "value",
+		"auth": map[string]interface{}{
+			"token":  triggermesh.UserInputTag,
+			"region": "us-east-1",
+		},
+	}
+	existing := map[string]interface{}{
+		"arn":   "arn:aws:s3:::dev",
+		"topic": triggermesh.UserInputTag,
+		"plain": "old",
+		"auth": map[string]interface{}{
+			"token": "secret",
+		},
+	}
+	assert.Equal(t, map[string]interface{}{
+		"arn":   "arn:aws:s3:::dev",
+		"topic": triggermesh.UserInputTag,
+		"plain": "value",
+		"auth": map[string]interface{}{
+			"token":  "secret",
+			"region": "us-east-1",
+		},
+	}, keepUserInput(spec, existing))
+}
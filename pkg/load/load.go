@@ -36,7 +36,7 @@ import (
 )
 
 func Import(from string, config *cliconfig.Config, crd map[string]crd.CRD) error {
-	m, err := getManifest(from)
+	m, err := Manifest(from)
 	if err != nil {
 		return fmt.Errorf("manifest %q: %w", from, err)
 	}
@@ -51,7 +51,7 @@ func Import(from string, config *cliconfig.Config, crd map[string]crd.CRD) error
 		if _, err := tmbroker.CreateBrokerConfig(config.ConfigHome, contextName); err != nil {
 			return fmt.Errorf("creating broker object: %w", err)
 		}
-		if _, err := tmbroker.New(contextName, config.Triggermesh.Broker); err != nil {
+		if _, err := tmbroker.New(config.ConfigHome, contextName, config.Triggermesh.Broker, config.Images); err != nil {
 			return fmt.Errorf("creating broker object: %w", err)
 		}
 		break
@@ -98,9 +98,13 @@ func Import(from string, config *cliconfig.Config, crd map[string]crd.CRD) error
 	return cliconfig.Set("context", contextName)
 }
 
-func getManifest(from string) (*manifest.Manifest, error) {
+// Manifest reads the manifest from the file or URL.
+func Manifest(from string) (*manifest.Manifest, error) {
 	_, err := os.Stat(from)
 	if os.IsNotExist(err) {
+		if cliconfig.Offline() {
+			return nil, fmt.Errorf("manifest %q does not exist, remote manifests are not fetched in the offline mode", from)
+		}
 		tempPath, err := fetch(from)
 		if err != nil {
 			return nil, err
@@ -168,19 +172,20 @@ func parseUserInputTags(name, kind string, spec map[string]interface{}) (map[str
 						return nil, err
 					}
 					items = append(items, filled)
-				} else if itemString, ok := item.(string); ok {
-					if itemString != triggermesh.UserInputTag {
-						continue
-					}
+				} else if itemString, ok := item.(string); ok && itemString == triggermesh.UserInputTag {
 					fmt.Printf("%s/%s: ", name, key)
 					input, err := readStdin()
 					if err != nil {
 						return nil, err
 					}
 					items = append(items, input)
+				} else {
+					items = append(items, item)
 				}
-				filledSpec[key] = items
 			}
+			filledSpec[key] = items
+		default:
+			filledSpec[key] = v
 		}
 	}
 	return filledSpec, nil
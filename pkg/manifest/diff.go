@@ -0,0 +1,181 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	kyaml "sigs.k8s.io/yaml"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+)
+
+// Operation is the change required to turn one manifest object into another.
+type Operation string
+
+const (
+	Create Operation = "create"
+	Update Operation = "update"
+	Delete Operation = "delete"
+)
+
+// ObjectDiff is the difference between the current and the desired
+// state of a single manifest object.
+type ObjectDiff struct {
+	Operation Operation
+	Object    kubernetes.Object
+	Fields    []FieldDiff
+}
+
+// FieldDiff is the difference in the value of a single object field.
+// Path is the dot-separated field path, e.g. "spec.sink.ref.name".
+type FieldDiff struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// Key returns the object identifier used to match objects in manifests.
+func (d ObjectDiff) Key() string {
+	return fmt.Sprintf("%s/%s/%s", d.Object.APIVersion, d.Object.Kind, d.Object.Metadata.Name)
+}
+
+// Diff compares two sets of objects and returns the operations needed to
+// bring the current objects to the desired state. Created and updated objects
+// follow the desired objects order, deleted objects are appended at the end.
+func Diff(current, desired []kubernetes.Object) []ObjectDiff {
+	var result []ObjectDiff
+	for _, d := range desired {
+		diff := ObjectDiff{Operation: Create, Object: d}
+		for _, c := range current {
+			if matchObjects(c, d) {
+				if equalObjects(c, d) {
+					diff.Operation = ""
+					break
+				}
+				diff.Operation = Update
+				diff.Fields = diffFields(c, d)
+				break
+			}
+		}
+		if diff.Operation != "" {
+			result = append(result, diff)
+		}
+	}
+	for _, c := range current {
+		found := false
+		for _, d := range desired {
+			if matchObjects(c, d) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, ObjectDiff{Operation: Delete, Object: c})
+		}
+	}
+	return result
+}
+
+// equalObjects compares objects by their serialized form
+// so that typed and unstructured specs can be matched.
+func equalObjects(a, b kubernetes.Object) bool {
+	ab, err := kyaml.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bb, err := kyaml.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ab, bb)
+}
+
+// diffFields returns the list of fields that differ in two objects.
+func diffFields(a, b kubernetes.Object) []FieldDiff {
+	am, err := asMap(a)
+	if err != nil {
+		return nil
+	}
+	bm, err := asMap(b)
+	if err != nil {
+		return nil
+	}
+	return diffValues("", am, bm)
+}
+
+func diffValues(path string, a, b interface{}) []FieldDiff {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make(map[string]struct{}, len(av)+len(bv))
+		for k := range av {
+			keys[k] = struct{}{}
+		}
+		for k := range bv {
+			keys[k] = struct{}{}
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		var result []FieldDiff
+		for _, k := range sorted {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			result = append(result, diffValues(p, av[k], bv[k])...)
+		}
+		return result
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			break
+		}
+		var result []FieldDiff
+		for i := range av {
+			result = append(result, diffValues(fmt.Sprintf("%s[%d]", path, i), av[i], bv[i])...)
+		}
+		return result
+	}
+	if reflect.DeepEqual(a, b) {
+		return nil
+	}
+	return []FieldDiff{{Path: path, Old: a, New: b}}
+}
+
+// asMap converts the object into its unstructured representation.
+func asMap(o kubernetes.Object) (map[string]interface{}, error) {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
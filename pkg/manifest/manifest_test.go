@@ -17,10 +17,12 @@ limitations under the License.
 package manifest
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components/service"
 	"github.com/triggermesh/tmctl/test"
 )
@@ -59,3 +61,58 @@ func TestRead(t *testing.T) {
 
 	assert.Lenf(t, m.Objects, 7, "Test manifest %q objects len differs after test", test.Manifest())
 }
+
+func TestDiff(t *testing.T) {
+	m := New(test.Manifest())
+	assert.NoError(t, m.Read())
+
+	assert.Empty(t, Diff(m.Objects, m.Objects), "Same objects produced the diff")
+
+	desired := make([]kubernetes.Object, 0, len(m.Objects))
+	for _, o := range m.Objects {
+		if o.Kind == "Transformation" {
+			continue
+		}
+		desired = append(desired, o)
+	}
+	updated, err := service.New("sockeye", "docker.io/n3wscott/sockeye:v0.7.0", "foo", service.Consumer, map[string]string{
+		"new-env-var": "new-env-value",
+	}).AsK8sObject()
+	assert.NoError(t, err)
+	updated.Metadata.Namespace = ""
+	created, err := service.New("test-service", "triggermesh/image", "foo", service.Consumer, nil).AsK8sObject()
+	assert.NoError(t, err)
+	for i, o := range desired {
+		if o.Metadata.Name == updated.Metadata.Name {
+			desired[i] = updated
+		}
+	}
+	desired = append(desired, created)
+
+	diff := Diff(m.Objects, desired)
+	assert.Len(t, diff, 3)
+	assert.Equal(t, Update, diff[0].Operation)
+	assert.Equal(t, "serving.knative.dev/v1/Service/sockeye", diff[0].Key())
+	assert.Equal(t, []FieldDiff{{
+		Path: "spec.template.spec.containers[0].env",
+		Old:  []interface{}{},
+		New: []interface{}{map[string]interface{}{
+			"name":  "NEW-ENV-VAR",
+			"value": "new-env-value",
+		}},
+	}}, diff[0].Fields)
+	assert.Equal(t, Create, diff[1].Operation)
+	assert.Equal(t, "test-service", diff[1].Object.Metadata.Name)
+	assert.Equal(t, Delete, diff[2].Operation)
+	assert.Equal(t, "foo-transformation", diff[2].Object.Metadata.Name)
+}
+
+func TestFieldDiffJSON(t *testing.T) {
+	data, err := json.Marshal(FieldDiff{Path: "spec.enabled", Old: true, New: false})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"path":"spec.enabled","old":true,"new":false}`, string(data))
+
+	data, err = json.Marshal(FieldDiff{Path: "spec.name", Old: "", New: "foo"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"path":"spec.name","old":"","new":"foo"}`, string(data))
+}
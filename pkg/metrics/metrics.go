@@ -0,0 +1,217 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics reads the Prometheus metrics exposed by
+// the broker and the adapter containers.
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Port is the container port of the Prometheus metrics endpoint.
+	Port = "9092"
+
+	// BrokerObservabilityConfig is the broker observability configuration file
+	// that enables the Prometheus metrics exporter. Brokers that read the
+	// broker configuration file do not accept the inline observability config.
+	BrokerObservabilityConfig = "metrics.backend-destination: prometheus\nmetrics.prometheus-port: " + Port + "\n"
+	// AdapterConfigEnv is the adapter metrics configuration
+	// that enables the Prometheus metrics exporter.
+	AdapterConfigEnv = `K_METRICS_CONFIG={"Domain":"triggermesh.io/adapter","Component":"adapter","PrometheusPort":` + Port + `,"ConfigMap":{"metrics.backend-destination":"prometheus"}}`
+
+	brokerIngestedMetric = "broker_ingest_event_count"
+	brokerRejectedMetric = "broker_ingest_rejected_count"
+	brokerTriggerMetric  = "broker_trigger_event_count"
+	adapterEventMetric   = "adapter_event_count"
+
+	scrapeTimeout = 2 * time.Second
+)
+
+// Sample is the single metric value with its labels.
+type Sample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// BrokerStats is the broker events counters.
+type BrokerStats struct {
+	Ingested int
+	Rejected int
+	Triggers map[string]TriggerStats
+}
+
+// TriggerStats is the trigger delivery counters.
+type TriggerStats struct {
+	Delivered int
+	Failed    int
+}
+
+// AdapterStats is the counters of the events sent by the adapter.
+type AdapterStats struct {
+	Sent   int
+	Failed int
+}
+
+// Scrape reads the metrics from the endpoint published on the host port.
+func Scrape(ctx context.Context, hostPort string) ([]Sample, error) {
+	ctx, cancel := context.WithTimeout(ctx, scrapeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://localhost:%s/metrics", hostPort), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+	return Parse(resp.Body)
+}
+
+// Parse reads the samples in the Prometheus text exposition format.
+func Parse(r io.Reader) ([]Sample, error) {
+	var samples []Sample
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		sample, err := parseSample(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		samples = append(samples, sample)
+	}
+	return samples, scanner.Err()
+}
+
+func parseSample(line string) (Sample, error) {
+	sample := Sample{Labels: map[string]string{}}
+	i := strings.IndexAny(line, "{ ")
+	if i == -1 {
+		return sample, fmt.Errorf("missing value")
+	}
+	sample.Name = line[:i]
+	rest := line[i:]
+	if strings.HasPrefix(rest, "{") {
+		var err error
+		if rest, err = parseLabels(rest[1:], sample.Labels); err != nil {
+			return sample, err
+		}
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return sample, fmt.Errorf("missing value")
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return sample, fmt.Errorf("value: %w", err)
+	}
+	sample.Value = value
+	return sample, nil
+}
+
+// parseLabels reads the label pairs up to the closing brace
+// and returns the rest of the line.
+func parseLabels(s string, labels map[string]string) (string, error) {
+	for {
+		s = strings.TrimLeft(s, " ,")
+		if strings.HasPrefix(s, "}") {
+			return s[1:], nil
+		}
+		eq := strings.Index(s, "=\"")
+		if eq == -1 {
+			return "", fmt.Errorf("malformed labels")
+		}
+		name := strings.TrimSpace(s[:eq])
+		var value strings.Builder
+		i := eq + 2
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(s[i])
+				}
+				continue
+			}
+			value.WriteByte(s[i])
+		}
+		if i == len(s) {
+			return "", fmt.Errorf("unterminated label %q value", name)
+		}
+		labels[name] = value.String()
+		s = s[i+1:]
+	}
+}
+
+// Broker returns the broker counters from the samples.
+func Broker(samples []Sample) BrokerStats {
+	stats := BrokerStats{Triggers: map[string]TriggerStats{}}
+	for _, s := range samples {
+		switch s.Name {
+		case brokerIngestedMetric:
+			if s.Labels["ingested"] == "true" {
+				stats.Ingested += int(s.Value)
+			} else {
+				stats.Rejected += int(s.Value)
+			}
+		case brokerRejectedMetric:
+			stats.Rejected += int(s.Value)
+		case brokerTriggerMetric:
+			trigger := stats.Triggers[s.Labels["trigger_name"]]
+			if s.Labels["delivered"] == "true" {
+				trigger.Delivered += int(s.Value)
+			} else {
+				trigger.Failed += int(s.Value)
+			}
+			stats.Triggers[s.Labels["trigger_name"]] = trigger
+		}
+	}
+	return stats
+}
+
+// Adapter returns the adapter counters from the samples.
+func Adapter(samples []Sample) AdapterStats {
+	var stats AdapterStats
+	for _, s := range samples {
+		if s.Name != adapterEventMetric {
+			continue
+		}
+		if s.Labels["response_code_class"] == "2xx" {
+			stats.Sent += int(s.Value)
+		} else {
+			stats.Failed += int(s.Value)
+		}
+	}
+	return stats
+}
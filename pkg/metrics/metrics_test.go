@@ -0,0 +1,96 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const brokerMetrics = `# HELP broker_ingest_event_count Number of events received by a Broker ingestion.
+# TYPE broker_ingest_event_count counter
+broker_ingest_event_count{broker_name="foo",ingested="true",received_type="com.example.created"} 12
+broker_ingest_event_count{broker_name="foo",ingested="false",received_type="com.example.created"} 1
+broker_ingest_rejected_count{broker_name="foo"} 2
+# TYPE broker_trigger_event_count counter
+broker_trigger_event_count{broker_name="foo",delivered="true",sent_type="",trigger_name="foo-trigger-1"} 10
+broker_trigger_event_count{broker_name="foo",delivered="false",sent_type="",trigger_name="foo-trigger-1"} 3
+broker_trigger_event_count{broker_name="foo",delivered="true",sent_type="",trigger_name="foo-trigger-2"} 2
+broker_trigger_event_latency_bucket{broker_name="foo",le="+Inf"} 15
+go_memstats_alloc_bytes 1.234e+06
+`
+
+func TestParse(t *testing.T) {
+	samples, err := Parse(strings.NewReader(`metric_a{label="with \"quotes\", and comma",other="b"} 1.5 1675000000000
+metric_b 2
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, []Sample{
+		{Name: "metric_a", Labels: map[string]string{"label": `with "quotes", and comma`, "other": "b"}, Value: 1.5},
+		{Name: "metric_b", Labels: map[string]string{}, Value: 2},
+	}, samples)
+
+	_, err = Parse(strings.NewReader(`metric_c{label="unterminated} 1`))
+	assert.Error(t, err)
+	_, err = Parse(strings.NewReader(`metric_d`))
+	assert.Error(t, err)
+}
+
+func TestBroker(t *testing.T) {
+	samples, err := Parse(strings.NewReader(brokerMetrics))
+	assert.NoError(t, err)
+	assert.Equal(t, BrokerStats{
+		Ingested: 12,
+		Rejected: 3,
+		Triggers: map[string]TriggerStats{
+			"foo-trigger-1": {Delivered: 10, Failed: 3},
+			"foo-trigger-2": {Delivered: 2},
+		},
+	}, Broker(samples))
+}
+
+func TestAdapter(t *testing.T) {
+	samples, err := Parse(strings.NewReader(`adapter_event_count{event_type="a",response_code="202",response_code_class="2xx"} 5
+adapter_event_count{event_type="a",response_code="500",response_code_class="5xx"} 2
+adapter_event_count{event_type="b",response_code="200",response_code_class="2xx"} 1
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, AdapterStats{Sent: 6, Failed: 2}, Adapter(samples))
+}
+
+func TestScrape(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(brokerMetrics))
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	assert.NoError(t, err)
+
+	samples, err := Scrape(context.Background(), u.Port())
+	assert.NoError(t, err)
+	assert.Equal(t, 12, Broker(samples).Ingested)
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/triggermesh/tmctl/pkg/graph"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+)
+
+const (
+	FormatDOT     = "dot"
+	FormatMermaid = "mermaid"
+	FormatTree    = "tree"
+)
+
+// Draw renders the source→broker→trigger→target event flow
+// graph as Graphviz DOT, Mermaid flowchart or a terminal tree.
+func Draw(g *graph.Graph, format string) (string, error) {
+	if g.Broker == nil {
+		return "", fmt.Errorf("graph does not have the broker")
+	}
+	switch format {
+	case FormatDOT:
+		return drawDOT(g), nil
+	case FormatMermaid:
+		return drawMermaid(g), nil
+	case FormatTree:
+		return drawTree(g), nil
+	}
+	return "", fmt.Errorf("format %q is not supported", format)
+}
+
+func drawDOT(g *graph.Graph) string {
+	broker := g.Broker.Component.GetName()
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", broker)
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	fmt.Fprintf(&b, "  %q [label=%q, shape=ellipse];\n", broker, nodeLabel(g.Broker, "\n"))
+	for _, node := range g.Nodes {
+		name := node.Component.GetName()
+		if node.IsTrigger() {
+			fmt.Fprintf(&b, "  %q [label=%q, shape=diamond];\n", name, name+"\n"+filters(node))
+			fmt.Fprintf(&b, "  %q -> %q;\n", broker, name)
+			if node.Target != "" {
+				fmt.Fprintf(&b, "  %q -> %q;\n", name, node.Target)
+			}
+			continue
+		}
+		fmt.Fprintf(&b, "  %q [label=%q];\n", name, nodeLabel(node, "\n"))
+		if node.IsProducer() {
+			fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", name, broker, strings.Join(eventTypes(node.EventTypes), "\n"))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func drawMermaid(g *graph.Graph) string {
+	// component names may differ only in the characters
+	// that are not allowed in IDs, use generated IDs instead
+	ids := make(map[string]string, len(g.Nodes)+1)
+	id := func(name string) string {
+		if _, set := ids[name]; !set {
+			ids[name] = fmt.Sprintf("n%d", len(ids))
+		}
+		return ids[name]
+	}
+	broker := id(g.Broker.Component.GetName())
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	fmt.Fprintf(&b, "  %s((%s))\n", broker, mermaidLabel(nodeLabel(g.Broker, "<br/>")))
+	for _, node := range g.Nodes {
+		id(node.Component.GetName())
+	}
+	for _, node := range g.Nodes {
+		name := node.Component.GetName()
+		if node.IsTrigger() {
+			fmt.Fprintf(&b, "  %s{%s}\n", id(name), mermaidLabel(name+"<br/>"+filters(node)))
+			fmt.Fprintf(&b, "  %s --> %s\n", broker, id(name))
+			if node.Target == "" {
+				continue
+			}
+			if g.Node(node.Target) == nil {
+				fmt.Fprintf(&b, "  %s[%s]\n", id(node.Target), mermaidLabel(node.Target))
+			}
+			fmt.Fprintf(&b, "  %s --> %s\n", id(name), id(node.Target))
+			continue
+		}
+		fmt.Fprintf(&b, "  %s[%s]\n", id(name), mermaidLabel(nodeLabel(node, "<br/>")))
+		if node.IsProducer() {
+			fmt.Fprintf(&b, "  %s -->|%s| %s\n", id(name), mermaidLabel(strings.Join(eventTypes(node.EventTypes), "<br/>")), broker)
+		}
+	}
+	return b.String()
+}
+
+// mermaidLabel returns the quoted label with the quotes escaped as entity codes.
+func mermaidLabel(label string) string {
+	return `"` + strings.ReplaceAll(label, `"`, "#quot;") + `"`
+}
+
+func drawTree(g *graph.Graph) string {
+	var producers, triggers []*graph.Node
+	for _, node := range g.Nodes {
+		switch {
+		case node.IsTrigger():
+			triggers = append(triggers, node)
+		case node.IsProducer():
+			producers = append(producers, node)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(nodeLabel(g.Broker, " ") + "\n")
+	branches := []struct {
+		title string
+		nodes []*graph.Node
+	}{
+		{"producers", producers},
+		{"triggers", triggers},
+	}
+	for i, branch := range branches {
+		last := i == len(branches)-1
+		b.WriteString(treeLine("", last) + branch.title + "\n")
+		indent := treeIndent("", last)
+		for j, node := range branch.nodes {
+			lastNode := j == len(branch.nodes)-1
+			if node.IsTrigger() {
+				fmt.Fprintf(&b, "%s%s [%s]\n", treeLine(indent, lastNode), node.Component.GetName(), filters(node))
+				if target := g.Node(node.Target); target != nil {
+					fmt.Fprintf(&b, "%s%s\n", treeLine(treeIndent(indent, lastNode), true), nodeLabel(target, " "))
+				} else if node.Target != "" {
+					fmt.Fprintf(&b, "%s%s (not found)\n", treeLine(treeIndent(indent, lastNode), true), node.Target)
+				}
+				continue
+			}
+			fmt.Fprintf(&b, "%s%s: %s\n", treeLine(indent, lastNode), nodeLabel(node, " "), strings.Join(eventTypes(node.EventTypes), ", "))
+		}
+	}
+	return b.String()
+}
+
+func treeLine(indent string, last bool) string {
+	if last {
+		return indent + "└── "
+	}
+	return indent + "├── "
+}
+
+func treeIndent(indent string, last bool) string {
+	if last {
+		return indent + "    "
+	}
+	return indent + "│   "
+}
+
+func nodeLabel(node *graph.Node, separator string) string {
+	label := fmt.Sprintf("%s%s(%s)", node.Component.GetName(), separator, node.Component.GetKind())
+	if len(node.ConsumedEventTypes) != 0 {
+		label = fmt.Sprintf("%s%saccepts: %s", label, separator, strings.Join(node.ConsumedEventTypes, ", "))
+	}
+	return label
+}
+
+func filters(node *graph.Node) string {
+	return tmbroker.TriggerFiltersToString(node.Filters, node.CESQL)
+}
+
+func eventTypes(types []string) []string {
+	if len(types) == 0 {
+		return []string{"*"}
+	}
+	return types
+}
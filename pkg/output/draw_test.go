@@ -0,0 +1,81 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package output
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/graph"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/test"
+)
+
+func TestDraw(t *testing.T) {
+	m := manifest.New(test.Manifest())
+	assert.NoError(t, m.Read())
+	c := &config.Config{
+		Triggermesh: config.TmConfig{ComponentsVersion: "v1.21.1"},
+	}
+	g, err := graph.New(m, c, test.CRD())
+	assert.NoError(t, err)
+
+	dot, err := Draw(g, FormatDOT)
+	assert.NoError(t, err)
+	assert.Contains(t, dot, `"foo-transformation" -> "foo" [label="foo-transformation.output"];`)
+	assert.Contains(t, dot, `"foo" -> "foo-trigger-9dad7875";`)
+	assert.Contains(t, dot, `"foo-trigger-9dad7875" -> "sockeye";`)
+
+	mermaid, err := Draw(g, FormatMermaid)
+	assert.NoError(t, err)
+	assert.Contains(t, mermaid, `n3 -->|"foo-transformation.output"| n0`)
+	assert.Contains(t, mermaid, "n5 --> n3")
+
+	tree, err := Draw(g, FormatTree)
+	assert.NoError(t, err)
+	assert.Contains(t, tree, "foo-trigger-6ada801c [type is com.amazon.s3.objectcreated]")
+
+	_, err = Draw(g, "svg")
+	assert.Error(t, err)
+}
+
+func TestDrawMermaidIDs(t *testing.T) {
+	m := manifest.New(test.Manifest())
+	assert.NoError(t, m.Read())
+	for _, object := range m.Objects {
+		if object.Metadata.Name == "foo-transformation" {
+			object.Metadata.Name = "foo_transformation"
+			m.Objects = append(m.Objects, object)
+			break
+		}
+	}
+	c := &config.Config{
+		Triggermesh: config.TmConfig{ComponentsVersion: "v1.21.1"},
+	}
+	g, err := graph.New(m, c, test.CRD())
+	assert.NoError(t, err)
+	g.Node("foo-trigger-9dad7875").CESQL = `source = "foo"`
+
+	mermaid, err := Draw(g, FormatMermaid)
+	assert.NoError(t, err)
+	assert.Contains(t, mermaid, `n3["foo-transformation<br/>(transformation)"]`)
+	assert.Contains(t, mermaid, `n6["foo_transformation<br/>(transformation)"]`)
+	assert.Contains(t, mermaid, `source = #quot;foo#quot;`)
+	assert.NotContains(t, mermaid, `\"`)
+}
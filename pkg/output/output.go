@@ -24,11 +24,13 @@ import (
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 )
 
-const (
-	delimeter = "---------------"
+const delimeter = "---------------"
 
-	successColorCode = "\033[92m"
-	defaultColorCode = "\033[39m"
+// Terminal color codes of the command output.
+const (
+	SuccessColorCode = "\033[92m"
+	DefaultColorCode = "\033[39m"
+	OfflineColorCode = "\033[31m"
 )
 
 func PrintStatus(kind string, object triggermesh.Component, eventSourcesFilter, eventTypesFilter []string) {
@@ -39,7 +41,7 @@ func PrintStatus(kind string, object triggermesh.Component, eventSourcesFilter,
 	case "broker":
 		result = fmt.Sprintf("%s\nCurrent broker is set to %q", result, object.GetName())
 		result = fmt.Sprintf("%s\nTo change the current broker use \"tmctl brokers --set <broker name>\"", result)
-		result = fmt.Sprintf("%s%s\n%s", successColorCode, result, defaultColorCode)
+		result = fmt.Sprintf("%s%s\n%s", SuccessColorCode, result, DefaultColorCode)
 		// result = fmt.Sprintf("%s\nNext steps:", result)
 		// result = fmt.Sprintf("%s\n\ttmctl create source\t - create source that will produce events", result)
 	case "producer":
@@ -47,7 +49,7 @@ func PrintStatus(kind string, object triggermesh.Component, eventSourcesFilter,
 		if len(et) != 0 {
 			result = fmt.Sprintf("%s\nComponent produces:\t%s", result, strings.Join(et, ", "))
 		}
-		result = fmt.Sprintf("%s%s\n%s", successColorCode, result, defaultColorCode)
+		result = fmt.Sprintf("%s%s\n%s", SuccessColorCode, result, DefaultColorCode)
 		// result = fmt.Sprintf("%s\nNext steps:", result)
 		// result = fmt.Sprintf("%s\n\ttmctl create target <kind> --source %s [--eventTypes <types>]\t - create target that will consume events from this source", result, object.GetName())
 		// result = fmt.Sprintf("%s\n\ttmctl watch\t\t\t\t\t\t\t\t\t - show events flowing through the broker in the real time", result)
@@ -67,7 +69,7 @@ func PrintStatus(kind string, object triggermesh.Component, eventSourcesFilter,
 			result = fmt.Sprintf("%s\nListening on:\t\thttp://localhost:%s", result, port)
 		}
 
-		result = fmt.Sprintf("%s%s\n%s", successColorCode, result, defaultColorCode)
+		result = fmt.Sprintf("%s%s\n%s", SuccessColorCode, result, DefaultColorCode)
 		// result = fmt.Sprintf("%s\nNext steps:", result)
 		// result = fmt.Sprintf("%s\n\ttmctl create transformation --target %s\t - create event transformation component", result, object.GetName())
 		// result = fmt.Sprintf("%s\n\ttmctl create trigger --target %s\t - create trigger to send events from source to target", result, object.GetName())
@@ -76,5 +78,4 @@ func PrintStatus(kind string, object triggermesh.Component, eventSourcesFilter,
 	fmt.Print(result)
 }
 
-// func Draw() {}
 // func Dump() {}
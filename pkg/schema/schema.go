@@ -0,0 +1,179 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package schema infers JSON Schema of the event payloads from the observed samples.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Draft is the JSON Schema version of the inferred schemas.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Inferrer accumulates JSON payloads of the single event type.
+type Inferrer struct {
+	EventType string
+	Samples   int
+
+	root *node
+}
+
+// node is the accumulated shape of the value at the single location in the payloads.
+type node struct {
+	types map[string]struct{}
+	// objects is the number of times the value was an object,
+	// used to decide if the property is required.
+	objects    int
+	properties map[string]*node
+	seen       map[string]int
+	items      *node
+}
+
+// New returns the schema inferrer for the event type.
+func New(eventType string) *Inferrer {
+	return &Inferrer{
+		EventType: eventType,
+		root:      newNode(),
+	}
+}
+
+func newNode() *node {
+	return &node{
+		types:      make(map[string]struct{}),
+		properties: make(map[string]*node),
+		seen:       make(map[string]int),
+	}
+}
+
+// Add merges the payload into the schema. Payload must be a valid JSON.
+func (i *Inferrer) Add(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("decoding payload: %w", err)
+	}
+	i.root.add(value)
+	i.Samples++
+	return nil
+}
+
+// Schema returns the JSON Schema that is valid for all accumulated payloads.
+func (i *Inferrer) Schema() map[string]interface{} {
+	result := i.root.schema()
+	result["$schema"] = Draft
+	result["title"] = i.EventType
+	return result
+}
+
+func (n *node) add(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		n.types["object"] = struct{}{}
+		n.objects++
+		for key, property := range v {
+			if _, exists := n.properties[key]; !exists {
+				n.properties[key] = newNode()
+			}
+			n.properties[key].add(property)
+			n.seen[key]++
+		}
+	case []interface{}:
+		n.types["array"] = struct{}{}
+		if n.items == nil {
+			n.items = newNode()
+		}
+		for _, item := range v {
+			n.items.add(item)
+		}
+	case string:
+		n.types["string"] = struct{}{}
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			n.types["integer"] = struct{}{}
+		} else {
+			n.types["number"] = struct{}{}
+		}
+	case bool:
+		n.types["boolean"] = struct{}{}
+	case nil:
+		n.types["null"] = struct{}{}
+	}
+}
+
+func (n *node) schema() map[string]interface{} {
+	result := make(map[string]interface{})
+	_, number := n.types["number"]
+	types := make([]string, 0, len(n.types))
+	for t := range n.types {
+		// integer is a subset of number
+		if t == "integer" && number {
+			continue
+		}
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	switch len(types) {
+	case 0:
+	case 1:
+		result["type"] = types[0]
+	default:
+		result["type"] = types
+	}
+
+	if len(n.properties) != 0 {
+		properties := make(map[string]interface{}, len(n.properties))
+		var required []string
+		for key, property := range n.properties {
+			properties[key] = property.schema()
+			if n.seen[key] == n.objects {
+				required = append(required, key)
+			}
+		}
+		sort.Strings(required)
+		result["properties"] = properties
+		if len(required) != 0 {
+			result["required"] = required
+		}
+	}
+	if n.items != nil && len(n.items.types) != 0 {
+		result["items"] = n.items.schema()
+	}
+	return result
+}
+
+// FileName returns the schema file name of the event type. Characters that are
+// not safe in file names, e.g. path separators, are percent-encoded, as well
+// as the leading dot, so that the file stays in the schemas directory.
+func FileName(eventType string) string {
+	var b strings.Builder
+	for i := 0; i < len(eventType); i++ {
+		c := eventType[i]
+		switch {
+		case c == '.' && i == 0:
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '.', c == '-', c == '_':
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String() + ".json"
+}
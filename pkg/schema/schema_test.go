@@ -0,0 +1,72 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfer(t *testing.T) {
+	i := New("com.example.order")
+	assert.NoError(t, i.Add([]byte(`{"id": 1, "item": "book", "tags": ["a"], "price": 10}`)))
+	assert.NoError(t, i.Add([]byte(`{"id": 2, "item": "pen", "price": 1.5, "customer": {"name": "foo", "vip": true}}`)))
+	assert.NoError(t, i.Add([]byte(`{"id": 3, "item": null, "price": 3, "tags": []}`)))
+	assert.Error(t, i.Add([]byte(`not json`)))
+	assert.Equal(t, 3, i.Samples)
+
+	expected := `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "com.example.order",
+		"type": "object",
+		"properties": {
+			"id": {"type": "integer"},
+			"item": {"type": ["null", "string"]},
+			"price": {"type": "number"},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"customer": {
+				"type": "object",
+				"properties": {
+					"name": {"type": "string"},
+					"vip": {"type": "boolean"}
+				},
+				"required": ["name", "vip"]
+			}
+		},
+		"required": ["id", "item", "price"]
+	}`
+	schema, err := json.Marshal(i.Schema())
+	assert.NoError(t, err)
+	assert.JSONEq(t, expected, string(schema))
+}
+
+func TestFileName(t *testing.T) {
+	cases := map[string]string{
+		"com.example.order":                  "com.example.order.json",
+		"io.triggermesh/orders/created":      "io.triggermesh%2Forders%2Fcreated.json",
+		"../../config":                       "%2E.%2F..%2Fconfig.json",
+		"..":                                 "%2E..json",
+		`s3:ObjectCreated:*`:                 "s3%3AObjectCreated%3A%2A.json",
+		"dev.knative.apiserver.resource.add": "dev.knative.apiserver.resource.add.json",
+		`C:\Windows`:                         "C%3A%5CWindows.json",
+	}
+	for eventType, expected := range cases {
+		assert.Equal(t, expected, FileName(eventType), eventType)
+	}
+}
@@ -21,11 +21,15 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/digitalocean/godo"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
+	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/metrics"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter/ce"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter/custom"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter/env"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter/reconciler"
 )
@@ -33,11 +37,35 @@ import (
 const (
 	registry    = "gcr.io/triggermesh"
 	adapterPort = "8080/tcp"
+	metricsPort = metrics.Port + "/tcp"
 )
 
-func Image(object unstructured.Unstructured, version string) string {
+// Image returns the adapter image of the component
+// with the config image overrides and mirror applied.
+func Image(images config.Images, object unstructured.Unstructured, version string) string {
+	return componentImage(images, object, version)
+}
+
+// PublicImage returns the adapter image of the component with the config
+// image overrides applied, default images are not pulled through the mirror.
+func PublicImage(images config.Images, object unstructured.Unstructured, version string) string {
+	return componentImage(images.WithoutMirror(), object, version)
+}
+
+func componentImage(images config.Images, object unstructured.Unstructured, version string) string {
+	if adapter, ok := custom.Lookup(object.GetKind()); ok {
+		// user-defined images are not mirrored
+		if image, ok := images.Override(object.GetKind()); ok {
+			return image
+		}
+		return adapter.Image
+	}
+	return images.Image(object.GetKind(), defaultImage(object.GetKind(), version))
+}
+
+func defaultImage(kind, version string) string {
 	// components with custom images
-	switch object.GetKind() {
+	switch kind {
 	case "AWSS3Source",
 		"AWSEventBridgeSource":
 		return fmt.Sprintf("%s/awssqssource-adapter:%s", registry, version)
@@ -51,7 +79,49 @@ func Image(object unstructured.Unstructured, version string) string {
 		"GoogleCloudSourceRepositoriesSource":
 		return fmt.Sprintf("%s/googlecloudpubsubsource-adapter:%s", registry, version)
 	}
-	return fmt.Sprintf("%s/%s-adapter:%s", registry, strings.ToLower(object.GetKind()), version)
+	return fmt.Sprintf("%s/%s-adapter:%s", registry, strings.ToLower(kind), version)
+}
+
+// DigitalOceanImage returns the App Platform source of the image. App Platform
+// pulls images from Docker Hub and DigitalOcean Container Registry only,
+// default TriggerMesh images are pulled from their Docker Hub copies.
+// The registry mirror is not reachable from App Platform, use PublicImage
+// to get the component image without the mirror.
+func DigitalOceanImage(image string) (*godo.ImageSourceSpec, error) {
+	repository, tag := image, "latest"
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		repository, tag = image[:i], image[i+1:]
+	}
+	path := strings.Split(repository, "/")
+	switch {
+	case strings.HasPrefix(repository, registry+"/"):
+		return &godo.ImageSourceSpec{
+			RegistryType: godo.ImageSourceSpecRegistryType_DockerHub,
+			Registry:     config.DockerRegistry,
+			Repository:   path[len(path)-1],
+			Tag:          tag,
+		}, nil
+	case path[0] == "registry.digitalocean.com" && len(path) > 2:
+		return &godo.ImageSourceSpec{
+			RegistryType: godo.ImageSourceSpecRegistryType_DOCR,
+			Repository:   strings.Join(path[2:], "/"),
+			Tag:          tag,
+		}, nil
+	case path[0] == "docker.io":
+		path = path[1:]
+		fallthrough
+	case !strings.ContainsAny(path[0], ".:") && path[0] != "localhost":
+		if len(path) == 1 {
+			path = append([]string{"library"}, path...)
+		}
+		return &godo.ImageSourceSpec{
+			RegistryType: godo.ImageSourceSpecRegistryType_DockerHub,
+			Registry:     path[0],
+			Repository:   strings.Join(path[1:], "/"),
+			Tag:          tag,
+		}, nil
+	}
+	return nil, fmt.Errorf("image %q: only Docker Hub and DigitalOcean registries are supported", image)
 }
 
 func RuntimeParams(object unstructured.Unstructured, image string, additionalEnvs map[string]string) ([]docker.ContainerOption, []docker.HostOption, error) {
@@ -96,6 +166,17 @@ func RuntimeParams(object unstructured.Unstructured, image string, additionalEnv
 		finalEnv = append(finalEnv, corev1.EnvVar{Name: "K_SINK", Value: sinkURI})
 	}
 	co = append(co, docker.WithEnv(envsToString(finalEnv)))
+
+	// user services may not expose the metrics
+	if object.GetKind() != "Service" {
+		// broker reads the metrics settings from the observability config file
+		if object.GetKind() != "RedisBroker" {
+			co = append(co, docker.WithEnv([]string{metrics.AdapterConfigEnv}))
+		}
+		co = append(co, docker.WithExposedPort(metricsPort))
+		// components replace the random binding with their assigned host port on start
+		ho = append(ho, docker.WithAdditionalHostPortBinding(metricsPort))
+	}
 	return co, ho, nil
 }
 
@@ -116,6 +197,10 @@ func Finalize(ctx context.Context, object unstructured.Unstructured, secrets map
 }
 
 func EventAttributes(object unstructured.Unstructured) (ce.EventAttributes, error) {
+	if _, ok := custom.Lookup(object.GetKind()); ok {
+		// user-defined kinds declare event types in the CRD annotations only
+		return ce.EventAttributes{}, nil
+	}
 	attributes, err := ce.Attributes(object)
 	if err != nil {
 		return ce.EventAttributes{}, err
@@ -19,9 +19,14 @@ package adapter
 import (
 	"testing"
 
+	"github.com/digitalocean/godo"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
 	"github.com/stretchr/testify/assert"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/metrics"
 )
 
 // var testObjects = map[string]struct {
@@ -61,19 +66,26 @@ func newUnstructured(t *testing.T, name, kind, api string, spec map[string]inter
 
 func TestRuntimeParams(t *testing.T) {
 	testObjects := map[string]struct {
-		object unstructured.Unstructured
+		object     unstructured.Unstructured
+		metricsEnv string
 	}{
 		"source": {
 			object: newUnstructured(t, "test-source", "AWSS3Source", "sources.triggermesh.io/v1alpha1", map[string]interface{}{
 				"arn":         "arn:aws:s3:::dev",
 				"accessKeyID": "test",
 			}),
+			metricsEnv: metrics.AdapterConfigEnv,
 		},
 		"target": {
-			object: newUnstructured(t, "test-target", "CloudEventsTarget", "targets.triggermesh.io/v1alpha1", map[string]interface{}{}),
+			object:     newUnstructured(t, "test-target", "CloudEventsTarget", "targets.triggermesh.io/v1alpha1", map[string]interface{}{}),
+			metricsEnv: metrics.AdapterConfigEnv,
 		},
 		"transformation": {
-			object: newUnstructured(t, "test-transformation", "Transformation", "flow.triggermesh.io/v1alpha1", map[string]interface{}{}),
+			object:     newUnstructured(t, "test-transformation", "Transformation", "flow.triggermesh.io/v1alpha1", map[string]interface{}{}),
+			metricsEnv: metrics.AdapterConfigEnv,
+		},
+		"broker": {
+			object: newUnstructured(t, "test-broker", "RedisBroker", "eventing.triggermesh.io/v1alpha1", nil),
 		},
 		"service": {
 			object: newUnstructured(t, "test-service", "Service", "flow.triggermesh.io/v1alpha1", map[string]interface{}{}),
@@ -99,6 +111,15 @@ func TestRuntimeParams(t *testing.T) {
 			assert.Contains(t, cc.Env, "additional-env=value")
 			assert.Equal(t, "host.docker.internal:host-gateway", hc.ExtraHosts[0])
 			assert.Len(t, hc.PortBindings["8080/tcp"], 1)
+			if test.object.GetKind() == "Service" {
+				assert.NotContains(t, hc.PortBindings, nat.Port(metricsPort))
+				return
+			}
+			if test.metricsEnv != "" {
+				assert.Contains(t, cc.Env, test.metricsEnv)
+			}
+			assert.Contains(t, cc.ExposedPorts, nat.Port(metricsPort))
+			assert.Len(t, hc.PortBindings[metricsPort], 1)
 		})
 	}
 }
@@ -113,3 +134,81 @@ func TestEventAttributes(t *testing.T) {
 	assert.Equal(t, "arn:aws:s3:::dev", attributes.ProducedEventSource)
 	assert.Equal(t, "com.amazon.s3.testevent", attributes.ProducedEventTypes[0])
 }
+
+func TestImage(t *testing.T) {
+	source := newUnstructured(t, "test-source", "AWSS3Source", "sources.triggermesh.io/v1alpha1", nil)
+	target := newUnstructured(t, "test-target", "CloudEventsTarget", "targets.triggermesh.io/v1alpha1", nil)
+	assert.Equal(t, "gcr.io/triggermesh/awssqssource-adapter:v1.23.2", Image(config.Images{}, source, "v1.23.2"))
+
+	images := config.Images{
+		Mirror:    "registry.example.com/mirror",
+		Overrides: map[string]string{"cloudeventstarget": "registry.example.com/cloudevents:dev"},
+	}
+	assert.Equal(t, "registry.example.com/mirror/triggermesh/awssqssource-adapter:v1.23.2", Image(images, source, "v1.23.2"))
+	assert.Equal(t, "registry.example.com/cloudevents:dev", Image(images, target, "v1.23.2"))
+
+	// public images skip the mirror, overrides are still applied
+	assert.Equal(t, "gcr.io/triggermesh/awssqssource-adapter:v1.23.2", PublicImage(images, source, "v1.23.2"))
+	assert.Equal(t, "registry.example.com/cloudevents:dev", PublicImage(images, target, "v1.23.2"))
+	_, err := DigitalOceanImage(PublicImage(images, source, "v1.23.2"))
+	assert.NoError(t, err)
+}
+
+func TestDigitalOceanImage(t *testing.T) {
+	cases := map[string]struct {
+		image    string
+		expected *godo.ImageSourceSpec
+		err      bool
+	}{
+		"default image": {
+			image: "gcr.io/triggermesh/memory-broker:v1.1.0",
+			expected: &godo.ImageSourceSpec{
+				RegistryType: godo.ImageSourceSpecRegistryType_DockerHub,
+				Registry:     "triggermesh",
+				Repository:   "memory-broker",
+				Tag:          "v1.1.0",
+			},
+		},
+		"docker hub official image": {
+			image: "redis:7.0-alpine",
+			expected: &godo.ImageSourceSpec{
+				RegistryType: godo.ImageSourceSpecRegistryType_DockerHub,
+				Registry:     "library",
+				Repository:   "redis",
+				Tag:          "7.0-alpine",
+			},
+		},
+		"docker hub image without tag": {
+			image: "docker.io/example/inventory-adapter",
+			expected: &godo.ImageSourceSpec{
+				RegistryType: godo.ImageSourceSpecRegistryType_DockerHub,
+				Registry:     "example",
+				Repository:   "inventory-adapter",
+				Tag:          "latest",
+			},
+		},
+		"digitalocean registry": {
+			image: "registry.digitalocean.com/example/awssqssource-adapter:v1.23.2",
+			expected: &godo.ImageSourceSpec{
+				RegistryType: godo.ImageSourceSpecRegistryType_DOCR,
+				Repository:   "awssqssource-adapter",
+				Tag:          "v1.23.2",
+			},
+		},
+		"unsupported registry": {
+			image: "registry.example.com:5000/mirror/triggermesh/awssqssource-adapter:v1.23.2",
+			err:   true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			spec, err := DigitalOceanImage(tc.image)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, spec)
+		})
+	}
+}
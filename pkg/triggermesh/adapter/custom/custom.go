@@ -0,0 +1,136 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package custom keeps the adapters of the user-defined component kinds
+// registered in the config.
+package custom
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+)
+
+var kinds = make(map[string]config.CustomKind)
+
+// Register replaces the user-defined kinds adapters.
+func Register(adapters map[string]config.CustomKind) {
+	kinds = make(map[string]config.CustomKind, len(adapters))
+	for kind, adapter := range adapters {
+		kinds[strings.ToLower(kind)] = adapter
+	}
+}
+
+// Lookup returns the adapter of the user-defined kind.
+func Lookup(kind string) (config.CustomKind, bool) {
+	adapter, exists := kinds[strings.ToLower(kind)]
+	return adapter, exists
+}
+
+// Env builds the adapter environment from the object spec.
+func Env(object unstructured.Unstructured, adapter config.CustomKind) ([]corev1.EnvVar, error) {
+	spec, _, err := unstructured.NestedMap(object.Object, "spec")
+	if err != nil {
+		return nil, fmt.Errorf("object spec: %w", err)
+	}
+	mapping := adapter.Env
+	if len(mapping) == 0 {
+		mapping = make(map[string]string, len(spec))
+		for field := range spec {
+			if field == "sink" {
+				// passed as K_SINK
+				continue
+			}
+			mapping[envName(field)] = field
+		}
+	}
+	var result []corev1.EnvVar
+	for name, path := range mapping {
+		value, found, err := unstructured.NestedFieldNoCopy(spec, strings.Split(path, ".")...)
+		if err != nil {
+			return nil, fmt.Errorf("%q spec field: %w", path, err)
+		}
+		if !found {
+			continue
+		}
+		env, err := envVar(name, value)
+		if err != nil {
+			return nil, fmt.Errorf("%q spec field: %w", path, err)
+		}
+		result = append(result, env)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+func envVar(name string, value interface{}) (corev1.EnvVar, error) {
+	switch v := value.(type) {
+	case string:
+		return corev1.EnvVar{Name: name, Value: v}, nil
+	case map[string]interface{}:
+		// secret references are resolved on the container start
+		for _, key := range []string{"valueFromSecret", "secretKeyRef"} {
+			if ref, ok := v[key].(map[string]interface{}); ok {
+				secretName, _ := ref["name"].(string)
+				secretKey, _ := ref["key"].(string)
+				return corev1.EnvVar{Name: name, ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+						Key:                  secretKey,
+					},
+				}}, nil
+			}
+		}
+		if plain, ok := v["value"]; ok && len(v) == 1 {
+			return envVar(name, plain)
+		}
+	case []interface{}:
+	default:
+		return corev1.EnvVar{Name: name, Value: fmt.Sprint(v)}, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return corev1.EnvVar{}, err
+	}
+	return corev1.EnvVar{Name: name, Value: string(data)}, nil
+}
+
+// envName converts the camel case field name to the upper snake case,
+// e.g. "queueARN" to "QUEUE_ARN".
+func envName(field string) string {
+	runes := []rune(field)
+	var name strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(prev) || nextLower {
+				name.WriteRune('_')
+			}
+		}
+		name.WriteRune(unicode.ToUpper(r))
+	}
+	return name.String()
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package custom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+)
+
+func TestEnvName(t *testing.T) {
+	cases := map[string]string{
+		"endpoint":      "ENDPOINT",
+		"eventType":     "EVENT_TYPE",
+		"queueARN":      "QUEUE_ARN",
+		"ARNQueue":      "ARN_QUEUE",
+		"pollInterval1": "POLL_INTERVAL1",
+	}
+	for field, expected := range cases {
+		t.Run(field, func(t *testing.T) {
+			assert.Equal(t, expected, envName(field))
+		})
+	}
+}
+
+func TestEnv(t *testing.T) {
+	object := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "sources.example.com/v1alpha1",
+		"kind":       "InventorySource",
+		"spec": map[string]interface{}{
+			"endpoint": "https://inventory.example.com",
+			"interval": int64(30),
+			"auth": map[string]interface{}{
+				"token": map[string]interface{}{
+					"valueFromSecret": map[string]interface{}{
+						"name": "foo-inventorysource-secret",
+						"key":  "auth.token",
+					},
+				},
+			},
+			"warehouses": []interface{}{"north", "south"},
+			"sink": map[string]interface{}{
+				"uri": "http://host.docker.internal:8080",
+			},
+		},
+	}}
+
+	env, err := Env(object, config.CustomKind{Image: "example/inventory-adapter"})
+	assert.NoError(t, err)
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: "AUTH", Value: `{"token":{"valueFromSecret":{"key":"auth.token","name":"foo-inventorysource-secret"}}}`},
+		{Name: "ENDPOINT", Value: "https://inventory.example.com"},
+		{Name: "INTERVAL", Value: "30"},
+		{Name: "WAREHOUSES", Value: `["north","south"]`},
+	}, env)
+
+	env, err = Env(object, config.CustomKind{
+		Image: "example/inventory-adapter",
+		Env: map[string]string{
+			"INVENTORY_URL":   "endpoint",
+			"INVENTORY_TOKEN": "auth.token",
+			"MISSING":         "auth.user",
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: "INVENTORY_TOKEN", ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "foo-inventorysource-secret"},
+				Key:                  "auth.token",
+			},
+		}},
+		{Name: "INVENTORY_URL", Value: "https://inventory.example.com"},
+	}, env)
+}
+
+func TestLookup(t *testing.T) {
+	Register(map[string]config.CustomKind{"InventorySource": {Image: "example/inventory-adapter"}})
+	defer Register(nil)
+
+	adapter, ok := Lookup("inventorysource")
+	assert.True(t, ok)
+	assert.Equal(t, "example/inventory-adapter", adapter.Image)
+	_, ok = Lookup("HTTPPollerSource")
+	assert.False(t, ok)
+}
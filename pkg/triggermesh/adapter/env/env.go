@@ -21,9 +21,14 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter/custom"
 )
 
 func Build(o unstructured.Unstructured) ([]corev1.EnvVar, error) {
+	if adapter, ok := custom.Lookup(o.GetKind()); ok {
+		return custom.Env(o, adapter)
+	}
 	switch o.GetAPIVersion() {
 	case "sources.triggermesh.io/v1alpha1":
 		return sources(o)
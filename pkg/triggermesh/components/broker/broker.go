@@ -22,7 +22,6 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
@@ -32,9 +31,9 @@ import (
 	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/docker"
 	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/metrics"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter"
-	"github.com/triggermesh/tmctl/pkg/triggermesh/pkg"
 )
 
 var (
@@ -48,14 +47,25 @@ const (
 	BrokerKind  = "RedisBroker"
 	TriggerKind = "Trigger"
 	APIVersion  = "eventing.triggermesh.io/v1alpha1"
+
+	// brokerRedisPasswordEnv is the broker environment variable of the Redis password.
+	brokerRedisPasswordEnv = "REDIS_PASSWORD"
+
+	configDir               = "/etc/triggermesh"
+	brokerConfigPath        = configDir + "/" + triggermesh.BrokerConfigFile
+	observabilityConfigPath = configDir + "/" + triggermesh.ObservabilityConfigFile
 )
 
 type Broker struct {
 	Name string
 
-	image      string
-	entrypoint []string
-	spec       map[string]interface{}
+	configHome  string
+	image       string
+	publicImage string // image without the registry mirror
+	entrypoint  []string
+	env         map[string]string // settings hidden from the command line
+	spec        map[string]interface{}
+	redis       *ManagedRedis
 }
 
 func (b *Broker) asUnstructured() (unstructured.Unstructured, error) {
@@ -83,38 +93,34 @@ func (b *Broker) AsK8sObject() (kubernetes.Object, error) {
 
 func (b *Broker) AsDockerComposeObject(additionalEnvs map[string]string) (interface{}, error) {
 	var env []string
-	for k, v := range additionalEnvs {
+	for k, v := range b.withEnv(additionalEnvs) {
 		env = append(env, fmt.Sprintf("%s=%s", k, v))
 	}
 	return &docker.ComposeService{
 		ContainerName: b.Name,
 		Image:         b.image,
 		Entrypoint:    b.entrypoint,
-		Ports:         []string{strconv.Itoa(pkg.OpenPort()) + ":8080"},
+		Ports:         []string{ComposePort(b.Name, ContainerName(b.Name))},
 		Environment:   env,
 	}, nil
 }
 
 func (b *Broker) AsDigitalOceanObject(additionalEnvs map[string]string) (interface{}, error) {
-	// Get the image and tag
-	imageSplit := strings.Split(b.image, "/")[2]
-	image := strings.Split(imageSplit, ":")
+	image, err := adapter.DigitalOceanImage(b.publicImage)
+	if err != nil {
+		return nil, fmt.Errorf("broker image: %w", err)
+	}
 
 	var env []*godo.AppVariableDefinition
-	for k, v := range additionalEnvs {
+	for k, v := range b.withEnv(additionalEnvs) {
 		env = append(env, &godo.AppVariableDefinition{
 			Key:   k,
 			Value: v,
 		})
 	}
 	return godo.AppServiceSpec{
-		Name: b.Name,
-		Image: &godo.ImageSourceSpec{
-			RegistryType: godo.ImageSourceSpecRegistryType_DockerHub,
-			Registry:     config.DockerRegistry,
-			Repository:   image[0],
-			Tag:          image[1],
-		},
+		Name:             b.Name,
+		Image:            image,
 		RunCommand:       strings.Join(b.entrypoint, " "),
 		InternalPorts:    []int64{8080},
 		Envs:             env,
@@ -128,20 +134,24 @@ func (b *Broker) asContainer(additionalEnvs map[string]string) (*docker.Containe
 	if err != nil {
 		return nil, fmt.Errorf("creating object: %w", err)
 	}
-	co, ho, err := adapter.RuntimeParams(o, b.image, additionalEnvs)
+	co, ho, err := adapter.RuntimeParams(o, b.image, b.withEnv(additionalEnvs))
 	if err != nil {
 		return nil, fmt.Errorf("creating adapter params: %w", err)
 	}
 
-	co = append(co, docker.WithEntrypoint(b.entrypoint))
+	entrypoint := append([]string{}, b.entrypoint...)
+	entrypoint = append(entrypoint, "--observability-config-path", observabilityConfigPath)
+	co = append(co, docker.WithEntrypoint(entrypoint))
 
-	bind := fmt.Sprintf("%s:/etc/triggermesh/broker.conf",
-		filepath.Join(config.HomeAbsPath(), b.Name, triggermesh.BrokerConfigFile))
-	ho = append(ho, docker.WithVolumeBind(bind))
+	// configuration files are replaced on update, the bind of the single
+	// file would keep the container on the replaced copy
+	ho = append(ho, docker.WithVolumeBind(filepath.Join(b.configHome, b.Name)+":"+configDir+":ro"))
+
+	ho = append(ho, docker.WithNetwork(docker.NetworkName(b.Name)))
 
 	name := o.GetName()
 	if !strings.HasSuffix(name, "-broker") {
-		name = name + "-broker"
+		name = ContainerName(name)
 	}
 	return &docker.Container{
 		Name:                   name,
@@ -151,6 +161,28 @@ func (b *Broker) asContainer(additionalEnvs map[string]string) (*docker.Containe
 	}, nil
 }
 
+// withEnv returns the additional environment merged with the broker settings.
+func (b *Broker) withEnv(additionalEnvs map[string]string) map[string]string {
+	env := make(map[string]string, len(additionalEnvs)+len(b.env))
+	for k, v := range additionalEnvs {
+		env[k] = v
+	}
+	for k, v := range b.env {
+		env[k] = v
+	}
+	return env
+}
+
+// ContainerName returns the name of the broker container.
+func ContainerName(broker string) string {
+	return broker + "-broker"
+}
+
+// LocalURL returns the broker address in the broker components network.
+func LocalURL(broker string) string {
+	return fmt.Sprintf("http://%s:8080", ContainerName(broker))
+}
+
 func (b *Broker) GetKind() string {
 	return BrokerKind
 }
@@ -184,54 +216,84 @@ func (b *Broker) ConsumedEventTypes() ([]string, error) {
 }
 
 func (b *Broker) Start(ctx context.Context, additionalEnvs map[string]string, restart bool) (*docker.Container, error) {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return nil, fmt.Errorf("docker client: %w", err)
+		return nil, fmt.Errorf("container runtime: %w", err)
+	}
+	if b.redis != nil {
+		if _, err := b.redis.Start(ctx, runtime); err != nil {
+			return nil, fmt.Errorf("starting redis: %w", err)
+		}
+	}
+	observabilityConfig := filepath.Join(b.configHome, b.Name, triggermesh.ObservabilityConfigFile)
+	if err := os.WriteFile(observabilityConfig, []byte(metrics.BrokerObservabilityConfig), 0644); err != nil {
+		return nil, fmt.Errorf("writing observability config: %w", err)
 	}
 	container, err := b.asContainer(additionalEnvs)
 	if err != nil {
 		return nil, fmt.Errorf("container object: %w", err)
 	}
-	return container.Start(ctx, client, restart)
+	hostPort, err := WithHostPort(b.Name, container.Name)
+	if err != nil {
+		return nil, err
+	}
+	metricsHostPort, err := WithMetricsHostPort(b.Name, container.Name)
+	if err != nil {
+		return nil, err
+	}
+	container.CreateHostOptions = append(container.CreateHostOptions, hostPort, metricsHostPort)
+	return container.Start(ctx, runtime, restart)
 }
 
 func (b *Broker) Stop(ctx context.Context) error {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return fmt.Errorf("docker client: %w", err)
+		return fmt.Errorf("container runtime: %w", err)
 	}
 	container, err := b.asContainer(nil)
 	if err != nil {
 		return fmt.Errorf("container object: %w", err)
 	}
-	return container.Remove(ctx, client)
+	if err := container.Remove(ctx, runtime); err != nil {
+		return err
+	}
+	if b.redis != nil {
+		return b.redis.Stop(ctx, runtime)
+	}
+	return nil
+}
+
+// ManagedRedis returns the Redis container managed along with the broker
+// or nil if the broker uses the in-memory or the external Redis backend.
+func (b *Broker) ManagedRedis() *ManagedRedis {
+	return b.redis
 }
 
 func (b *Broker) Info(ctx context.Context) (*docker.Container, error) {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return nil, fmt.Errorf("docker client: %w", err)
+		return nil, fmt.Errorf("container runtime: %w", err)
 	}
 	container, err := b.asContainer(nil)
 	if err != nil {
 		return nil, fmt.Errorf("container object: %w", err)
 	}
-	return container.LookupHostConfig(ctx, client)
+	return container.LookupHostConfig(ctx, runtime)
 }
 
 func (b *Broker) Logs(ctx context.Context, since time.Time, follow bool) (io.ReadCloser, error) {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return nil, fmt.Errorf("docker client: %w", err)
+		return nil, fmt.Errorf("container runtime: %w", err)
 	}
 	container, err := b.asContainer(nil)
 	if err != nil {
 		return nil, fmt.Errorf("container object: %w", err)
 	}
-	if _, err := container.LookupHostConfig(ctx, client); err != nil {
+	if _, err := container.LookupHostConfig(ctx, runtime); err != nil {
 		return nil, fmt.Errorf("container config: %w", err)
 	}
-	return container.Logs(ctx, client, since, follow)
+	return container.Logs(ctx, runtime, since, follow)
 }
 
 func CreateBrokerConfig(configHome, broker string) (string, error) {
@@ -260,25 +322,53 @@ func CreateBrokerConfig(configHome, broker string) (string, error) {
 	return brokerConfigPath, nil
 }
 
-func New(name string, brokerConfig config.BrokerConfig) (triggermesh.Component, error) {
+// New creates the broker component with the global configuration
+// overridden by the broker settings and the managed Redis backend.
+func New(configHome, name string, brokerConfig config.BrokerConfig, images config.Images) (triggermesh.Component, error) {
+	local, err := config.LoadLocalBrokerConfig(configHome, name)
+	if err != nil {
+		return nil, fmt.Errorf("broker settings: %w", err)
+	}
+	brokerConfig = local.Merge(brokerConfig)
+	redis, err := LoadManagedRedis(configHome, name)
+	if err != nil {
+		return nil, fmt.Errorf("managed redis: %w", err)
+	}
+	if redis != nil {
+		redis.images = images
+		brokerConfig = redis.BrokerConfig(brokerConfig)
+	}
 	return &Broker{
 		Name: name,
 
-		image:      image(brokerConfig),
-		entrypoint: brokerEntrypoint(brokerConfig),
+		configHome:  configHome,
+		image:       image(images, brokerConfig),
+		publicImage: image(images.WithoutMirror(), brokerConfig),
+		entrypoint:  brokerEntrypoint(brokerConfig),
+		env:         brokerEnv(brokerConfig),
+		redis:       redis,
 	}, nil
 }
 
-func image(c config.BrokerConfig) string {
+func image(images config.Images, c config.BrokerConfig) string {
 	switch {
 	case c.Memory != nil:
-		return config.MemoryBrokerImage + ":" + c.Version
+		return images.Image("MemoryBroker", config.MemoryBrokerImage+":"+c.Version)
 	case c.Redis != nil:
-		return config.RedisBrokerImage + ":" + c.Version
+		return images.Image("RedisBroker", config.RedisBrokerImage+":"+c.Version)
 	}
 	return ""
 }
 
+// brokerEnv returns the broker settings passed in the environment,
+// so that the Redis password is not exposed in the process list.
+func brokerEnv(c config.BrokerConfig) map[string]string {
+	if c.Redis == nil || c.Redis.Password == "" {
+		return nil
+	}
+	return map[string]string{brokerRedisPasswordEnv: c.Redis.Password}
+}
+
 func brokerEntrypoint(c config.BrokerConfig) []string {
 	var entrypoint []string
 	switch {
@@ -297,8 +387,6 @@ func brokerEntrypoint(c config.BrokerConfig) []string {
 			"start",
 			"--redis.username",
 			c.Redis.Username,
-			"--redis.password",
-			c.Redis.Password,
 			"--redis.address",
 			c.Redis.Address,
 		}
@@ -312,5 +400,5 @@ func brokerEntrypoint(c config.BrokerConfig) []string {
 			entrypoint = append(entrypoint, []string{"--config-polling-period", c.ConfigPollingPeriod}...)
 		}
 	}
-	return append(entrypoint, []string{"--broker-config-path", "/etc/triggermesh/broker.conf"}...)
+	return append(entrypoint, []string{"--broker-config-path", brokerConfigPath}...)
 }
@@ -0,0 +1,110 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	brokercmd "github.com/triggermesh/brokers/pkg/broker/cmd"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+// TestBrokerContainerConfig validates the broker container arguments and
+// environment with the configuration rules of the broker itself.
+func TestBrokerContainerConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for name, brokerConfig := range map[string]config.BrokerConfig{
+		"memory": {Version: "v1.1.0", Memory: &config.InMemoryBrokerConfig{BufferSize: "100", ProduceTimeout: "1s"}},
+		"redis":  {Version: "v1.1.0", Redis: &config.RedisBrokerConfig{Address: "redis:6379", Password: "s3cr3t"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			component, err := New(config.HomeAbsPath(), "foo", brokerConfig, config.Images{})
+			assert.NoError(t, err)
+			c, err := component.(*Broker).asContainer(map[string]string{})
+			assert.NoError(t, err)
+			cc, hc := &container.Config{}, &container.HostConfig{}
+			for _, option := range c.CreateContainerOptions {
+				option(cc)
+			}
+			for _, option := range c.CreateHostOptions {
+				option(hc)
+			}
+
+			// password is passed in the environment only
+			assert.NotContains(t, strings.Join(append(cc.Entrypoint, cc.Cmd...), " "), "s3cr3t")
+			if brokerConfig.Redis != nil {
+				assert.Contains(t, cc.Env, "REDIS_PASSWORD=s3cr3t")
+			}
+
+			globals := brokerGlobals(cc.Entrypoint, cc.Env)
+			assert.NoError(t, globals.Validate())
+			assert.Equal(t, brokercmd.ConfigMethod(brokercmd.ConfigMethodFileWatcher), globals.ConfigMethod)
+			assert.Equal(t, observabilityConfigPath, globals.ObservabilityConfigPath)
+			assert.Empty(t, globals.ObservabilityConfig)
+
+			// broker directory is bound to watch the replaced configuration files
+			assert.Equal(t, []string{filepath.Join(config.HomeAbsPath(), "foo") + ":/etc/triggermesh:ro"}, hc.Binds)
+			assert.Equal(t, "/etc/triggermesh/"+triggermesh.BrokerConfigFile, globals.BrokerConfigPath)
+			assert.Equal(t, "/etc/triggermesh/"+triggermesh.ObservabilityConfigFile, globals.ObservabilityConfigPath)
+		})
+	}
+}
+
+func TestBrokerDigitalOceanImage(t *testing.T) {
+	images := config.Images{Mirror: "registry.example.com/mirror"}
+	component, err := New(t.TempDir(), "foo", config.BrokerConfig{Version: "v1.1.0", Memory: &config.InMemoryBrokerConfig{}}, images)
+	assert.NoError(t, err)
+	assert.Equal(t, "registry.example.com/mirror/triggermesh/memory-broker:v1.1.0", component.(*Broker).image)
+
+	// App Platform pulls the default image from Docker Hub, not from the mirror
+	object, err := component.(*Broker).AsDigitalOceanObject(nil)
+	assert.NoError(t, err)
+	spec := object.(godo.AppServiceSpec)
+	assert.Equal(t, "triggermesh", spec.Image.Registry)
+	assert.Equal(t, "memory-broker", spec.Image.Repository)
+}
+
+// brokerGlobals returns the broker configuration settings
+// read from the container entrypoint flags and environment.
+func brokerGlobals(entrypoint, env []string) *brokercmd.Globals {
+	values := map[string]string{
+		"BROKER_CONFIG_PATH": "/etc/triggermesh/broker.conf",
+	}
+	for _, e := range env {
+		if k, v, found := strings.Cut(e, "="); found {
+			values[k] = v
+		}
+	}
+	for i := 0; i < len(entrypoint)-1; i++ {
+		if flag := strings.TrimPrefix(entrypoint[i], "--"); flag != entrypoint[i] {
+			values[strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))] = entrypoint[i+1]
+		}
+	}
+	return &brokercmd.Globals{
+		BrokerConfigPath:        values["BROKER_CONFIG_PATH"],
+		ObservabilityConfigPath: values["OBSERVABILITY_CONFIG_PATH"],
+		BrokerConfig:            values["BROKER_CONFIG"],
+		ObservabilityConfig:     values["OBSERVABILITY_CONFIG"],
+	}
+}
@@ -20,27 +20,35 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"gopkg.in/yaml.v3"
 
 	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
 
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/log"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 )
 
+// configMutex serializes broker configuration updates
+// made by the concurrently started components.
+var configMutex sync.Mutex
+
 type Configuration struct {
 	Triggers map[string]LocalTriggerSpec `yaml:"triggers" json:"triggers"`
 }
 
 type LocalTriggerSpec struct {
-	Filters []eventingbroker.Filter `yaml:"filters,omitempty" json:"filters,omitempty"`
-	Target  LocalTarget             `yaml:"target" json:"target"`
+	Filters []TriggerFilter `yaml:"filters,omitempty" json:"filters,omitempty"`
+	Target  LocalTarget     `yaml:"target" json:"target"`
 }
 
 type LocalTarget struct {
-	URL             string                          `yaml:"url,omitempty" json:"url,omitempty"`
-	Component       string                          `yaml:"component,omitempty" json:"component,omitempty"`
-	DeliveryOptions *eventingbroker.DeliveryOptions `yaml:"deliveryOptions,omitempty" json:"deliveryOptions,omitempty"`
+	URL                 string                          `yaml:"url,omitempty" json:"url,omitempty"`
+	Component           string                          `yaml:"component,omitempty" json:"component,omitempty"`
+	DeadLetterComponent string                          `yaml:"deadLetterComponent,omitempty" json:"deadLetterComponent,omitempty"`
+	DeliveryOptions     *eventingbroker.DeliveryOptions `yaml:"deliveryOptions,omitempty" json:"deliveryOptions,omitempty"`
 }
 
 func readBrokerConfig(path string) (Configuration, error) {
@@ -57,47 +65,120 @@ func writeBrokerConfig(path string, configuration *Configuration) error {
 	if err != nil {
 		return fmt.Errorf("marshal broker configuration: %w", err)
 	}
-	return os.WriteFile(path, out, os.ModePerm)
+	return writeFile(path, out)
 }
 
-func (t *Trigger) WriteLocalConfig() error {
-	configFile := filepath.Join(t.ConfigBase, t.Broker.Name, triggermesh.BrokerConfigFile)
+// writeFile replaces the file with the new one so that the readers
+// never see the file truncated or partially written.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// updateBrokerConfig reads, modifies and writes the broker configuration
+// under the lock so that the concurrent updates are not lost.
+func updateBrokerConfig(broker, configBase string, update func(*Configuration)) error {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	configFile := filepath.Join(configBase, broker, triggermesh.BrokerConfigFile)
 	configuration, err := readBrokerConfig(configFile)
 	if err != nil {
 		return fmt.Errorf("broker config: %w", err)
 	}
+	update(&configuration)
+	return writeBrokerConfig(configFile, &configuration)
+}
 
-	trigger, exists := configuration.Triggers[t.Name]
-	if exists {
-		trigger.Filters = t.Filters
-		trigger.Target = LocalTarget{
-			URL:       t.LocalURL.String(),
-			Component: t.Target.Ref.Name,
+// UpdateTriggers applies the update to every trigger of the broker configuration.
+func UpdateTriggers(broker, configBase string, update func(name string, spec *LocalTriggerSpec)) error {
+	return updateBrokerConfig(broker, configBase, func(configuration *Configuration) {
+		for name, spec := range configuration.Triggers {
+			update(name, &spec)
+			configuration.Triggers[name] = spec
 		}
-		configuration.Triggers[t.Name] = trigger
-	} else {
-		if configuration.Triggers == nil {
-			configuration.Triggers = make(map[string]LocalTriggerSpec, 1)
+	})
+}
+
+// UpdateComponentTriggers points the triggers that deliver events to the component,
+// as the target or as the dead letter sink, to the component address.
+func UpdateComponentTriggers(broker, configBase string, component triggermesh.Component) error {
+	if _, ok := component.(triggermesh.Consumer); !ok {
+		return nil
+	}
+	url, err := componentURL(component.GetName())
+	if err != nil {
+		return fmt.Errorf("component address: %w", err)
+	}
+	return UpdateTriggers(broker, configBase, func(_ string, spec *LocalTriggerSpec) {
+		if spec.Target.Component == component.GetName() {
+			spec.Target.URL = url.String()
 		}
-		configuration.Triggers[t.Name] = LocalTriggerSpec{
-			Filters: t.Filters,
-			Target: LocalTarget{
-				URL:       t.LocalURL.String(),
-				Component: t.Target.Ref.Name,
-			},
+		if spec.Target.DeadLetterComponent == component.GetName() {
+			if spec.Target.DeliveryOptions == nil {
+				spec.Target.DeliveryOptions = &eventingbroker.DeliveryOptions{}
+			}
+			deadLetterURL := url.String()
+			spec.Target.DeliveryOptions.DeadLetterURL = &deadLetterURL
+		}
+	})
+}
+
+func (t *Trigger) WriteLocalConfig() error {
+	if t.CESQL != "" {
+		brokerVersion, err := config.BrokerVersion(t.ConfigBase, t.Broker.Name)
+		if err != nil {
+			return fmt.Errorf("broker version: %w", err)
+		}
+		// older brokers skip the unknown filter and pass the events through
+		if err := CheckCESQLSupport(brokerVersion); err != nil {
+			log.Printf("WARNING: trigger %q: %v, the expression is not evaluated", t.Name, err)
 		}
 	}
-	return writeBrokerConfig(configFile, &configuration)
+	spec := LocalTriggerSpec{
+		Filters: JoinFilters(t.Filters, t.CESQL),
+		Target: LocalTarget{
+			URL:                 t.LocalURL.String(),
+			Component:           t.Target.Ref.Name,
+			DeadLetterComponent: t.deadLetterComponent(),
+			DeliveryOptions:     t.deliveryOptions(),
+		},
+	}
+	return updateBrokerConfig(t.Broker.Name, t.ConfigBase, func(configuration *Configuration) {
+		if configuration.Triggers == nil {
+			configuration.Triggers = make(map[string]LocalTriggerSpec, 1)
+		}
+		configuration.Triggers[t.Name] = spec
+	})
 }
 
 func (t *Trigger) RemoveFromLocalConfig() error {
-	configFile := filepath.Join(t.ConfigBase, t.Broker.Name, triggermesh.BrokerConfigFile)
-	configuration, err := readBrokerConfig(configFile)
+	return updateBrokerConfig(t.Broker.Name, t.ConfigBase, func(configuration *Configuration) {
+		delete(configuration.Triggers, t.Name)
+	})
+}
+
+// LocalTriggers returns the triggers from the broker configuration file.
+func LocalTriggers(broker, configBase string) (map[string]LocalTriggerSpec, error) {
+	config, err := readBrokerConfig(filepath.Join(configBase, broker, triggermesh.BrokerConfigFile))
 	if err != nil {
-		return fmt.Errorf("broker config: %w", err)
+		return nil, fmt.Errorf("read broker config: %w", err)
 	}
-	delete(configuration.Triggers, t.Name)
-	return writeBrokerConfig(configFile, &configuration)
+	return config.Triggers, nil
 }
 
 func GetTargetTriggers(target, broker, configBase string) ([]triggermesh.Component, error) {
@@ -0,0 +1,125 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+// SetDeadLetter sets the component as the trigger dead letter sink.
+func (t *Trigger) SetDeadLetter(sink triggermesh.Component) {
+	if t.Delivery == nil {
+		t.Delivery = &eventingduckv1.DeliverySpec{}
+	}
+	t.Delivery.DeadLetterSink = &duckv1.Destination{
+		Ref: &duckv1.KReference{
+			Kind:       sink.GetKind(),
+			Name:       sink.GetName(),
+			APIVersion: sink.GetAPIVersion(),
+		},
+	}
+	if _, ok := sink.(triggermesh.Consumer); ok {
+		if url, err := componentURL(sink.GetName()); err == nil {
+			t.DeadLetterURL = url
+		}
+	}
+}
+
+// deadLetterComponent returns the name of the dead letter sink component.
+func (t *Trigger) deadLetterComponent() string {
+	if t.Delivery == nil || t.Delivery.DeadLetterSink == nil || t.Delivery.DeadLetterSink.Ref == nil {
+		return ""
+	}
+	return t.Delivery.DeadLetterSink.Ref.Name
+}
+
+// deliveryOptions converts the trigger delivery spec into the broker configuration.
+func (t *Trigger) deliveryOptions() *eventingbroker.DeliveryOptions {
+	if t.Delivery == nil {
+		return nil
+	}
+	options := &eventingbroker.DeliveryOptions{
+		Retry:        t.Delivery.Retry,
+		BackoffDelay: t.Delivery.BackoffDelay,
+	}
+	if t.Delivery.BackoffPolicy != nil {
+		policy := eventingbroker.BackoffPolicyType(*t.Delivery.BackoffPolicy)
+		options.BackoffPolicy = &policy
+	}
+	if t.DeadLetterURL != nil {
+		url := t.DeadLetterURL.String()
+		options.DeadLetterURL = &url
+	}
+	return options
+}
+
+// lookupDelivery restores the delivery spec from the broker configuration.
+func (t *Trigger) lookupDelivery(target LocalTarget) {
+	if target.DeliveryOptions == nil {
+		return
+	}
+	t.Delivery = &eventingduckv1.DeliverySpec{
+		Retry:        target.DeliveryOptions.Retry,
+		BackoffDelay: target.DeliveryOptions.BackoffDelay,
+	}
+	if target.DeliveryOptions.BackoffPolicy != nil {
+		policy := eventingduckv1.BackoffPolicyType(*target.DeliveryOptions.BackoffPolicy)
+		t.Delivery.BackoffPolicy = &policy
+	}
+	if target.DeadLetterComponent != "" {
+		t.Delivery.DeadLetterSink = &duckv1.Destination{
+			Ref: &duckv1.KReference{
+				Name: target.DeadLetterComponent,
+			},
+		}
+	}
+	if target.DeliveryOptions.DeadLetterURL != nil {
+		if url, _ := apis.ParseURL(*target.DeliveryOptions.DeadLetterURL); url != nil {
+			t.DeadLetterURL = url
+		}
+	}
+}
+
+// GetDeadLetterTriggers returns the triggers that use the component as the dead letter sink.
+func GetDeadLetterTriggers(sink, broker, configBase string) ([]triggermesh.Component, error) {
+	config, err := readBrokerConfig(filepath.Join(configBase, broker, triggermesh.BrokerConfigFile))
+	if err != nil {
+		return nil, fmt.Errorf("read broker config: %w", err)
+	}
+	var triggers []triggermesh.Component
+	for name, trigger := range config.Triggers {
+		if trigger.Target.DeadLetterComponent != sink {
+			continue
+		}
+		trigger, err := NewTrigger(name, broker, configBase, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("creating trigger: %w", err)
+		}
+		trigger.(*Trigger).LookupTarget()
+		triggers = append(triggers, trigger)
+	}
+	return triggers, nil
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+func TestDeliveryLocalConfig(t *testing.T) {
+	configBase := t.TempDir()
+	_, err := CreateBrokerConfig(configBase, "foo")
+	assert.NoError(t, err)
+
+	retry := int32(3)
+	policy := eventingduckv1.BackoffPolicyExponential
+	delay := "PT0.5S"
+
+	trigger, err := NewTrigger("foo-trigger", "foo", configBase, nil, nil)
+	assert.NoError(t, err)
+	tr := trigger.(*Trigger)
+	tr.Target = duckv1.Destination{Ref: &duckv1.KReference{Name: "sockeye"}}
+	tr.LocalURL, _ = apis.ParseURL("http://sockeye:8080")
+	tr.DeadLetterURL, _ = apis.ParseURL("http://dls:8080")
+	tr.Delivery = &eventingduckv1.DeliverySpec{
+		Retry:         &retry,
+		BackoffPolicy: &policy,
+		BackoffDelay:  &delay,
+		DeadLetterSink: &duckv1.Destination{
+			Ref: &duckv1.KReference{Name: "dls"},
+		},
+	}
+	assert.NoError(t, tr.WriteLocalConfig())
+
+	triggers, err := LocalTriggers("foo", configBase)
+	assert.NoError(t, err)
+	options := triggers["foo-trigger"].Target.DeliveryOptions
+	assert.Equal(t, "dls", triggers["foo-trigger"].Target.DeadLetterComponent)
+	assert.Equal(t, retry, *options.Retry)
+	assert.EqualValues(t, policy, *options.BackoffPolicy)
+	assert.Equal(t, delay, *options.BackoffDelay)
+	assert.Equal(t, "http://dls:8080", *options.DeadLetterURL)
+
+	// triggers restored from the broker config keep the delivery options
+	dlsTriggers, err := GetDeadLetterTriggers("dls", "foo", configBase)
+	assert.NoError(t, err)
+	assert.Len(t, dlsTriggers, 1)
+	restored := dlsTriggers[0].(*Trigger)
+	assert.Equal(t, tr.Delivery, restored.Delivery)
+	assert.Equal(t, tr.DeadLetterURL, restored.DeadLetterURL)
+
+	k8sObject, err := restored.AsK8sObject()
+	assert.NoError(t, err)
+	assert.Equal(t, tr.Delivery, k8sObject.Spec["delivery"])
+}
+
+func TestUpdateComponentTriggersConcurrently(t *testing.T) {
+	configBase := t.TempDir()
+	_, err := CreateBrokerConfig(configBase, "foo")
+	assert.NoError(t, err)
+
+	var components []triggermesh.Component
+	for i := 0; i < 10; i++ {
+		target, sink := fmt.Sprintf("target-%d", i), fmt.Sprintf("dls-%d", i)
+		trigger, err := NewTrigger("trigger-"+target, "foo", configBase, nil, nil)
+		assert.NoError(t, err)
+		trigger.(*Trigger).Target = duckv1.Destination{Ref: &duckv1.KReference{Name: target}}
+		trigger.(*Trigger).Delivery = &eventingduckv1.DeliverySpec{
+			DeadLetterSink: &duckv1.Destination{Ref: &duckv1.KReference{Name: sink}},
+		}
+		assert.NoError(t, trigger.(*Trigger).WriteLocalConfig())
+		components = append(components, &Broker{Name: target}, &Broker{Name: sink})
+	}
+
+	var wg sync.WaitGroup
+	for _, component := range components {
+		wg.Add(1)
+		go func(component triggermesh.Component) {
+			defer wg.Done()
+			assert.NoError(t, UpdateComponentTriggers("foo", configBase, component))
+		}(component)
+	}
+	wg.Wait()
+
+	triggers, err := LocalTriggers("foo", configBase)
+	assert.NoError(t, err)
+	assert.Len(t, triggers, 10)
+	for name, trigger := range triggers {
+		assert.Equal(t, "http://"+trigger.Target.Component+":8080", trigger.Target.URL, name)
+		assert.Equal(t, "http://"+trigger.Target.DeadLetterComponent+":8080", *trigger.Target.DeliveryOptions.DeadLetterURL, name)
+	}
+}
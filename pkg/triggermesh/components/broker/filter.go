@@ -0,0 +1,288 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/types"
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/triggermesh/tmctl/pkg/cesql"
+)
+
+// TriggerFilter is the trigger filter expression as it is written in the broker
+// configuration and in the manifest. The brokers config package does not have the
+// CESQL dialect yet, it is written on the top level of the trigger filters for the
+// broker versions that support it, see CheckCESQLSupport.
+type TriggerFilter struct {
+	eventingbroker.Filter `yaml:",inline"`
+	CESQL                 string `yaml:"cesql,omitempty" json:"cesql,omitempty"`
+}
+
+// JoinFilters returns the trigger filters with the CESQL expression, if set.
+func JoinFilters(filters []eventingbroker.Filter, cesql string) []TriggerFilter {
+	var result []TriggerFilter
+	for _, filter := range filters {
+		result = append(result, TriggerFilter{Filter: filter})
+	}
+	if cesql != "" {
+		result = append(result, TriggerFilter{CESQL: cesql})
+	}
+	return result
+}
+
+// SplitFilters separates the CESQL expressions from the trigger filters.
+// Multiple CESQL expressions are combined with the AND operator.
+func SplitFilters(filters []TriggerFilter) ([]eventingbroker.Filter, string) {
+	var result []eventingbroker.Filter
+	var expressions []string
+	for _, filter := range filters {
+		if filter.CESQL != "" {
+			expressions = append(expressions, filter.CESQL)
+			continue
+		}
+		result = append(result, filter.Filter)
+	}
+	if len(expressions) > 1 {
+		for i := range expressions {
+			expressions[i] = "(" + expressions[i] + ")"
+		}
+	}
+	return result, strings.Join(expressions, " AND ")
+}
+
+// ValidateCESQL parses the CESQL expression and returns
+// the error with the position of the syntax error.
+func ValidateCESQL(expression string) error {
+	if _, err := cesql.Parse(expression); err != nil {
+		var syntaxErr *cesql.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return fmt.Errorf("invalid CESQL expression:\n  %s\n  %s^\n%w", expression, strings.Repeat(" ", syntaxErr.Position-1), err)
+		}
+		return fmt.Errorf("invalid CESQL expression: %w", err)
+	}
+	return nil
+}
+
+// lastBrokerWithoutCESQL is the latest broker release that skips
+// the "cesql" trigger filter and passes the events to the target.
+const lastBrokerWithoutCESQL = "v1.1.0"
+
+// CheckCESQLSupport returns an error if the broker version does not evaluate
+// the CESQL trigger filters. Such filters are still written to the broker
+// configuration, the broker skips them.
+func CheckCESQLSupport(brokerVersion string) error {
+	v, err := version.ParseSemantic(brokerVersion)
+	if err != nil {
+		return fmt.Errorf("CESQL filters are not supported by the broker version %q", brokerVersion)
+	}
+	if version.MustParseSemantic(lastBrokerWithoutCESQL).AtLeast(v) {
+		return fmt.Errorf("CESQL filters require the broker newer than %s, current version is %s", lastBrokerWithoutCESQL, brokerVersion)
+	}
+	return nil
+}
+
+// ParseFilterAttribute converts "attribute=value" expression into the filter.
+// Value may have the leading or the trailing wildcard, see FilterAttribute.
+func ParseFilterAttribute(expression string) (*eventingbroker.Filter, error) {
+	attribute, value, found := strings.Cut(expression, "=")
+	attribute = strings.TrimSpace(attribute)
+	if !found || attribute == "" || strings.TrimSpace(strings.Trim(value, "*")) == "" {
+		return nil, fmt.Errorf("expression %q does not match \"attribute=value\" format", expression)
+	}
+	return FilterAttribute(attribute, strings.TrimSpace(value)), nil
+}
+
+// Match logic of the attribute filter expressions.
+const (
+	MatchAll = "all"
+	MatchAny = "any"
+)
+
+// ComposeFilters builds the trigger filters from the "attribute=value" expressions
+// combined with the match logic, the expressions that must not match and the raw
+// JSON filters. Resulting filters are combined with the logical AND.
+func ComposeFilters(attributes []string, match string, exclude []string, raw []string) ([]eventingbroker.Filter, error) {
+	var result []eventingbroker.Filter
+	var matches []eventingbroker.Filter
+	for _, expression := range attributes {
+		filter, err := ParseFilterAttribute(expression)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, *filter)
+	}
+	switch {
+	case match == MatchAll:
+		result = append(result, matches...)
+	case match == MatchAny && len(matches) == 1:
+		result = append(result, matches[0])
+	case match == MatchAny && len(matches) > 1:
+		result = append(result, eventingbroker.Filter{Any: matches})
+	case match != MatchAny:
+		return nil, fmt.Errorf("match %q is not supported, must be one of %s, %s", match, MatchAll, MatchAny)
+	}
+
+	for _, expression := range exclude {
+		filter, err := ParseFilterAttribute(expression)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, eventingbroker.Filter{Not: filter})
+	}
+
+	for _, r := range raw {
+		var filter eventingbroker.Filter
+		if err := json.Unmarshal([]byte(r), &filter); err != nil {
+			return nil, fmt.Errorf("cannot decode filter JSON %q: %w", r, err)
+		}
+		result = append(result, filter)
+	}
+	return result, nil
+}
+
+// MatchFilters evaluates the trigger filters against the event and returns true
+// if the event passes all of them. CESQL evaluation errors fail the match.
+func MatchFilters(filters []TriggerFilter, event cloudevents.Event) (bool, error) {
+	for _, filter := range filters {
+		if filter.CESQL != "" {
+			if passed, err := cesql.Match(filter.CESQL, event); !passed {
+				return false, err
+			}
+			continue
+		}
+		if !matchFilter(filter.Filter, event) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func matchFilter(f eventingbroker.Filter, event cloudevents.Event) bool {
+	for attribute, expected := range f.Exact {
+		if value, set := attributeValue(event, attribute); !set || value != expected {
+			return false
+		}
+	}
+	for attribute, expected := range f.Prefix {
+		if value, set := attributeValue(event, attribute); !set || !strings.HasPrefix(value, expected) {
+			return false
+		}
+	}
+	for attribute, expected := range f.Suffix {
+		if value, set := attributeValue(event, attribute); !set || !strings.HasSuffix(value, expected) {
+			return false
+		}
+	}
+	for _, nested := range f.All {
+		if !matchFilter(nested, event) {
+			return false
+		}
+	}
+	if len(f.Any) != 0 {
+		passed := false
+		for _, nested := range f.Any {
+			if matchFilter(nested, event) {
+				passed = true
+				break
+			}
+		}
+		if !passed {
+			return false
+		}
+	}
+	if f.Not != nil && matchFilter(*f.Not, event) {
+		return false
+	}
+	return true
+}
+
+// attributeValue returns the string value of the event context attribute or extension.
+func attributeValue(event cloudevents.Event, attribute string) (string, bool) {
+	value, set := cesql.AttributeValue(event, attribute)
+	if !set {
+		return "", false
+	}
+	s, err := types.Format(value)
+	if err != nil {
+		return fmt.Sprint(value), true
+	}
+	return s, true
+}
+
+// FiltersToString returns human readable representation of the trigger filters,
+// e.g. "type is foo*, any(source is bar, not(subject is *baz))".
+func FiltersToString(filters []eventingbroker.Filter) string {
+	result := make([]string, 0, len(filters))
+	for _, f := range filters {
+		result = append(result, filterToString(f))
+	}
+	return strings.Join(result, ", ")
+}
+
+// TriggerFiltersToString returns human readable representation of the trigger
+// filters and the CESQL expression, "*" if the trigger passes all events.
+func TriggerFiltersToString(filters []eventingbroker.Filter, cesql string) string {
+	var result []string
+	if len(filters) != 0 {
+		result = append(result, FiltersToString(filters))
+	}
+	if cesql != "" {
+		result = append(result, fmt.Sprintf("cesql(%s)", cesql))
+	}
+	if len(result) == 0 {
+		return "*"
+	}
+	return strings.Join(result, ", ")
+}
+
+func filterToString(f eventingbroker.Filter) string {
+	var result []string
+	result = append(result, attributesToString(f.Exact, "%s is %s")...)
+	result = append(result, attributesToString(f.Prefix, "%s is %s*")...)
+	result = append(result, attributesToString(f.Suffix, "%s is *%s")...)
+	if len(f.All) != 0 {
+		result = append(result, fmt.Sprintf("all(%s)", FiltersToString(f.All)))
+	}
+	if len(f.Any) != 0 {
+		result = append(result, fmt.Sprintf("any(%s)", FiltersToString(f.Any)))
+	}
+	if f.Not != nil {
+		result = append(result, fmt.Sprintf("not(%s)", filterToString(*f.Not)))
+	}
+	return strings.Join(result, ", ")
+}
+
+func attributesToString(attributes map[string]string, format string) []string {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	result := make([]string, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, fmt.Sprintf(format, k, attributes[k]))
+	}
+	return result
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/stretchr/testify/assert"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+)
+
+func TestParseFilterAttribute(t *testing.T) {
+	cases := map[string]struct {
+		expected *eventingbroker.Filter
+		err      bool
+	}{
+		"type=foo": {
+			expected: &eventingbroker.Filter{Exact: map[string]string{"type": "foo"}},
+		},
+		"source=foo-*": {
+			expected: &eventingbroker.Filter{Prefix: map[string]string{"source": "foo-"}},
+		},
+		"subject = *.json": {
+			expected: &eventingbroker.Filter{Suffix: map[string]string{"subject": ".json"}},
+		},
+		"type":  {err: true},
+		"=foo":  {err: true},
+		"type=": {err: true},
+		"id=*":  {err: true},
+	}
+	for expression, tc := range cases {
+		t.Run(expression, func(t *testing.T) {
+			filter, err := ParseFilterAttribute(expression)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, filter)
+		})
+	}
+}
+
+func TestFiltersToString(t *testing.T) {
+	filters := []eventingbroker.Filter{
+		{Prefix: map[string]string{"type": "foo"}},
+		{Any: []eventingbroker.Filter{
+			{Exact: map[string]string{"source": "bar"}},
+			{Not: &eventingbroker.Filter{Suffix: map[string]string{"subject": "baz"}}},
+		}},
+	}
+	assert.Equal(t, "type is foo*, any(source is bar, not(subject is *baz))", FiltersToString(filters))
+	assert.Equal(t, "type is foo*, any(source is bar, not(subject is *baz)), cesql(EXISTS id)", TriggerFiltersToString(filters, "EXISTS id"))
+	assert.Equal(t, "*", TriggerFiltersToString(nil, ""))
+}
+
+func TestComposeFilters(t *testing.T) {
+	cases := map[string]struct {
+		attributes []string
+		match      string
+		exclude    []string
+		raw        []string
+		expected   []eventingbroker.Filter
+		err        bool
+	}{
+		"empty": {
+			match: MatchAll,
+		},
+		"match all": {
+			attributes: []string{"type=foo*", "subject=*.json"},
+			match:      MatchAll,
+			expected: []eventingbroker.Filter{
+				{Prefix: map[string]string{"type": "foo"}},
+				{Suffix: map[string]string{"subject": ".json"}},
+			},
+		},
+		"match any": {
+			attributes: []string{"type=foo", "type=bar"},
+			match:      MatchAny,
+			exclude:    []string{"source=test"},
+			expected: []eventingbroker.Filter{
+				{Any: []eventingbroker.Filter{
+					{Exact: map[string]string{"type": "foo"}},
+					{Exact: map[string]string{"type": "bar"}},
+				}},
+				{Not: &eventingbroker.Filter{Exact: map[string]string{"source": "test"}}},
+			},
+		},
+		"raw filter": {
+			attributes: []string{"type=foo"},
+			match:      MatchAny,
+			raw:        []string{`{"prefix":{"source":"bar"}}`},
+			expected: []eventingbroker.Filter{
+				{Exact: map[string]string{"type": "foo"}},
+				{Prefix: map[string]string{"source": "bar"}},
+			},
+		},
+		"unknown match": {
+			attributes: []string{"type=foo"},
+			match:      "some",
+			err:        true,
+		},
+		"invalid raw filter": {
+			match: MatchAll,
+			raw:   []string{`{"prefix":`},
+			err:   true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			filters, err := ComposeFilters(tc.attributes, tc.match, tc.exclude, tc.raw)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, filters)
+		})
+	}
+}
+
+func TestTriggerFilters(t *testing.T) {
+	configBase := t.TempDir()
+	_, err := CreateBrokerConfig(configBase, "foo")
+	assert.NoError(t, err)
+	assert.NoError(t, config.LocalBrokerConfig{Version: "v1.2.0"}.Save(configBase, "foo"))
+
+	filters := []eventingbroker.Filter{
+		{Exact: map[string]string{"type": "foo"}},
+		{Not: &eventingbroker.Filter{Prefix: map[string]string{"source": "test"}}},
+	}
+	trigger, err := NewTrigger("foo-trigger", "foo", configBase, nil, filters)
+	assert.NoError(t, err)
+	tr := trigger.(*Trigger)
+	tr.Target = duckv1.Destination{Ref: &duckv1.KReference{Name: "sockeye"}}
+	tr.CESQL = "subject LIKE '%.json'"
+	assert.NoError(t, tr.WriteLocalConfig())
+
+	triggers, err := LocalTriggers("foo", configBase)
+	assert.NoError(t, err)
+	localFilters, cesql := SplitFilters(triggers["foo-trigger"].Filters)
+	// broker config keeps empty expressions, compare filters semantics
+	assert.Equal(t, FiltersToString(filters), FiltersToString(localFilters))
+	assert.Equal(t, tr.CESQL, cesql)
+
+	restored, err := GetTargetTriggers("sockeye", "foo", configBase)
+	assert.NoError(t, err)
+	assert.Len(t, restored, 1)
+	assert.Equal(t, FiltersToString(filters), FiltersToString(restored[0].(*Trigger).Filters))
+	assert.Equal(t, tr.CESQL, restored[0].(*Trigger).CESQL)
+
+	k8sObject, err := restored[0].AsK8sObject()
+	assert.NoError(t, err)
+	assert.Len(t, k8sObject.Spec["filters"], 3)
+	assert.Equal(t, TriggerFilter{CESQL: tr.CESQL}, k8sObject.Spec["filters"].([]TriggerFilter)[2])
+}
+
+func TestSplitFilters(t *testing.T) {
+	filters := []TriggerFilter{
+		{CESQL: "type = 'foo'"},
+		{Filter: eventingbroker.Filter{Exact: map[string]string{"source": "bar"}}},
+		{CESQL: "EXISTS subject OR id = '1'"},
+	}
+	result, cesql := SplitFilters(filters)
+	assert.Equal(t, []eventingbroker.Filter{{Exact: map[string]string{"source": "bar"}}}, result)
+	assert.Equal(t, "(type = 'foo') AND (EXISTS subject OR id = '1')", cesql)
+
+	assert.Equal(t, []TriggerFilter{filters[1], {CESQL: cesql}}, JoinFilters(result, cesql))
+}
+
+func TestValidateCESQL(t *testing.T) {
+	assert.NoError(t, ValidateCESQL("type = 'foo'"))
+	err := ValidateCESQL("type = 'foo' AND")
+	assert.ErrorContains(t, err, "invalid CESQL expression:\n  type = 'foo' AND\n                  ^\nsyntax error at position 17: mismatched input '<EOF>'")
+	// keywords are case-insensitive
+	assert.NoError(t, ValidateCESQL("type = 'foo' and not exists subject"))
+}
+
+func TestCheckCESQLSupport(t *testing.T) {
+	assert.Error(t, CheckCESQLSupport("v1.0.0"))
+	assert.Error(t, CheckCESQLSupport("v1.1.0"))
+	assert.Error(t, CheckCESQLSupport("latest"))
+	assert.NoError(t, CheckCESQLSupport("v1.2.0"))
+}
+
+func TestWriteLocalConfigCESQLSupport(t *testing.T) {
+	configBase := t.TempDir()
+	_, err := CreateBrokerConfig(configBase, "foo")
+	assert.NoError(t, err)
+	assert.NoError(t, config.LocalBrokerConfig{Version: "v1.1.0"}.Save(configBase, "foo"))
+
+	trigger, err := NewTrigger("foo-trigger", "foo", configBase, nil, nil)
+	assert.NoError(t, err)
+	tr := trigger.(*Trigger)
+	tr.Target = duckv1.Destination{Ref: &duckv1.KReference{Name: "sockeye"}}
+	tr.CESQL = "subject LIKE '%.json'"
+	// broker skips the expression, the trigger is written with the warning
+	assert.NoError(t, tr.WriteLocalConfig())
+	triggers, err := LocalTriggers("foo", configBase)
+	assert.NoError(t, err)
+	_, cesql := SplitFilters(triggers["foo-trigger"].Filters)
+	assert.Equal(t, "subject LIKE '%.json'", cesql)
+}
+
+func TestMatchFilters(t *testing.T) {
+	event := cloudevents.NewEvent()
+	event.SetID("1")
+	event.SetSource("/shop/orders")
+	event.SetType("com.example.order.created")
+	event.SetExtension("tenant", "acme")
+
+	cases := map[string]struct {
+		filters  []TriggerFilter
+		expected bool
+		err      bool
+	}{
+		"no filters": {
+			expected: true,
+		},
+		"exact and prefix": {
+			filters: []TriggerFilter{
+				{Filter: eventingbroker.Filter{Exact: map[string]string{"type": "com.example.order.created"}}},
+				{Filter: eventingbroker.Filter{Prefix: map[string]string{"source": "/shop/"}}},
+			},
+			expected: true,
+		},
+		"extension suffix": {
+			filters:  []TriggerFilter{{Filter: eventingbroker.Filter{Suffix: map[string]string{"tenant": "me"}}}},
+			expected: true,
+		},
+		"missing attribute": {
+			filters:  []TriggerFilter{{Filter: eventingbroker.Filter{Exact: map[string]string{"subject": "foo"}}}},
+			expected: false,
+		},
+		"any and not": {
+			filters: []TriggerFilter{{Filter: eventingbroker.Filter{
+				Any: []eventingbroker.Filter{
+					{Exact: map[string]string{"type": "foo"}},
+					{Prefix: map[string]string{"type": "com.example."}},
+				},
+				Not: &eventingbroker.Filter{Exact: map[string]string{"tenant": "acme"}},
+			}}},
+			expected: false,
+		},
+		"cesql": {
+			filters:  []TriggerFilter{{CESQL: "source LIKE '%/orders' AND tenant = 'acme'"}},
+			expected: true,
+		},
+		"cesql error": {
+			filters: []TriggerFilter{{CESQL: "subject = 'foo'"}},
+			err:     true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			passed, err := MatchFilters(tc.filters, event)
+			if tc.err {
+				assert.Error(t, err)
+				assert.False(t, passed)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, passed)
+		})
+	}
+}
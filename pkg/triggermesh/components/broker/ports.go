@@ -0,0 +1,221 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/metrics"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/pkg"
+)
+
+// PortsFile is the host ports assigned to the broker components.
+const PortsFile = "ports.yaml"
+
+// componentPort is the container port published on the host.
+const componentPort = "8080/tcp"
+
+// metricsPort is the container port of the component metrics endpoint.
+const metricsPort = metrics.Port + "/tcp"
+
+// portsMux serializes the ports file updates of the components
+// started concurrently.
+var portsMux sync.Mutex
+
+// HostPorts is the component names mapped to their host ports.
+type HostPorts map[string]string
+
+// LoadHostPorts reads the host ports assigned to the broker components.
+func LoadHostPorts(configHome, broker string) (HostPorts, error) {
+	ports := HostPorts{}
+	data, err := os.ReadFile(filepath.Join(configHome, broker, PortsFile))
+	if os.IsNotExist(err) {
+		return ports, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read ports: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &ports); err != nil {
+		return nil, fmt.Errorf("decode ports: %w", err)
+	}
+	return ports, nil
+}
+
+// Save replaces the host ports file in the broker directory.
+func (p HostPorts) Save(configHome, broker string) error {
+	data, err := yaml.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(configHome, broker, PortsFile), data)
+}
+
+// HostPort returns the host port assigned to the component. New port
+// is allocated and stored if the component does not have one yet.
+func HostPort(configHome, broker, component string) (string, error) {
+	portsMux.Lock()
+	defer portsMux.Unlock()
+	ports, err := LoadHostPorts(configHome, broker)
+	if err != nil {
+		return "", err
+	}
+	if port, exists := ports[component]; exists {
+		return port, nil
+	}
+	assigned, err := assignedPorts(configHome)
+	if err != nil {
+		return "", err
+	}
+	port := strconv.Itoa(pkg.OpenPort())
+	for assigned[port] != "" {
+		// the port is free now but belongs to the stopped component
+		port = strconv.Itoa(pkg.OpenPort())
+	}
+	ports[component] = port
+	return port, ports.Save(configHome, broker)
+}
+
+// ReserveHostPort assigns the host port to the component. The port
+// must not be assigned to other components or be used on the host.
+func ReserveHostPort(configHome, broker, component, port string) error {
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	portsMux.Lock()
+	defer portsMux.Unlock()
+	ports, err := LoadHostPorts(configHome, broker)
+	if err != nil {
+		return err
+	}
+	if ports[component] == port {
+		return nil
+	}
+	assigned, err := assignedPorts(configHome)
+	if err != nil {
+		return err
+	}
+	if owner, taken := assigned[port]; taken {
+		return fmt.Errorf("port %s is already assigned to %q", port, owner)
+	}
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return fmt.Errorf("port %s is not available: %w", port, err)
+	}
+	listener.Close()
+	ports[component] = port
+	return ports.Save(configHome, broker)
+}
+
+// RestoreHostPort sets the component host port back to the previous
+// assignment, the assignment is removed if the previous port is empty.
+func RestoreHostPort(configHome, broker, component, previous string) error {
+	portsMux.Lock()
+	defer portsMux.Unlock()
+	ports, err := LoadHostPorts(configHome, broker)
+	if err != nil {
+		return err
+	}
+	if ports[component] == previous {
+		return nil
+	}
+	if previous == "" {
+		delete(ports, component)
+	} else {
+		ports[component] = previous
+	}
+	return ports.Save(configHome, broker)
+}
+
+// ReleaseHostPort removes the component host ports assignment.
+func ReleaseHostPort(configHome, broker, component string) error {
+	portsMux.Lock()
+	defer portsMux.Unlock()
+	ports, err := LoadHostPorts(configHome, broker)
+	if err != nil {
+		return err
+	}
+	_, main := ports[component]
+	_, metrics := ports[metricsComponent(component)]
+	if !main && !metrics {
+		return nil
+	}
+	delete(ports, component)
+	delete(ports, metricsComponent(component))
+	return ports.Save(configHome, broker)
+}
+
+// WithHostPort binds the component container port to its assigned host port.
+func WithHostPort(broker, component string) (docker.HostOption, error) {
+	port, err := HostPort(config.HomeAbsPath(), broker, component)
+	if err != nil {
+		return nil, fmt.Errorf("host port: %w", err)
+	}
+	return docker.WithStaticHostPortBinding(componentPort, port), nil
+}
+
+// WithMetricsHostPort binds the component metrics port to its assigned host port.
+func WithMetricsHostPort(broker, component string) (docker.HostOption, error) {
+	port, err := HostPort(config.HomeAbsPath(), broker, metricsComponent(component))
+	if err != nil {
+		return nil, fmt.Errorf("metrics host port: %w", err)
+	}
+	return docker.WithStaticHostPortBinding(metricsPort, port), nil
+}
+
+// metricsComponent is the key of the component metrics port in the ports file.
+func metricsComponent(component string) string {
+	return component + "/metrics"
+}
+
+// ComposePort returns the component ports mapping in the docker-compose
+// format, random host port is used if the component does not have one.
+func ComposePort(broker, component string) string {
+	if ports, err := LoadHostPorts(config.HomeAbsPath(), broker); err == nil && ports[component] != "" {
+		return ports[component] + ":8080"
+	}
+	return strconv.Itoa(pkg.OpenPort()) + ":8080"
+}
+
+// assignedPorts returns the host ports of all brokers mapped to their owners.
+func assignedPorts(configHome string) (map[string]string, error) {
+	assigned := make(map[string]string)
+	dirs, err := os.ReadDir(configHome)
+	if err != nil {
+		return nil, fmt.Errorf("list brokers: %w", err)
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		ports, err := LoadHostPorts(configHome, dir.Name())
+		if err != nil {
+			return nil, err
+		}
+		for component, port := range ports {
+			assigned[port] = dir.Name() + "/" + component
+		}
+	}
+	return assigned, nil
+}
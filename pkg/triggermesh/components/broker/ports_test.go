@@ -0,0 +1,137 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+)
+
+func TestHostPort(t *testing.T) {
+	configHome := t.TempDir()
+	_, err := CreateBrokerConfig(configHome, "foo")
+	assert.NoError(t, err)
+
+	port, err := HostPort(configHome, "foo", "sockeye")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, port)
+
+	again, err := HostPort(configHome, "foo", "sockeye")
+	assert.NoError(t, err)
+	assert.Equal(t, port, again, "assigned port must be reused")
+
+	other, err := HostPort(configHome, "foo", "httppoller")
+	assert.NoError(t, err)
+	assert.NotEqual(t, port, other)
+
+	metrics, err := HostPort(configHome, "foo", metricsComponent("sockeye"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, port, metrics)
+
+	assert.NoError(t, ReleaseHostPort(configHome, "foo", "sockeye"))
+	ports, err := LoadHostPorts(configHome, "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, HostPorts{"httppoller": other}, ports)
+}
+
+func TestRestoreHostPort(t *testing.T) {
+	configHome := t.TempDir()
+	_, err := CreateBrokerConfig(configHome, "foo")
+	assert.NoError(t, err)
+	assert.NoError(t, HostPorts{"sockeye": "8081", "httppoller": "8082"}.Save(configHome, "foo"))
+
+	assert.NoError(t, RestoreHostPort(configHome, "foo", "sockeye", "8083"))
+	assert.NoError(t, RestoreHostPort(configHome, "foo", "httppoller", ""))
+	ports, err := LoadHostPorts(configHome, "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, HostPorts{"sockeye": "8083"}, ports)
+}
+
+func TestComposePort(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_, err := CreateBrokerConfig(config.HomeAbsPath(), "foo")
+	assert.NoError(t, err)
+	assert.NoError(t, HostPorts{"sockeye": "8081"}.Save(config.HomeAbsPath(), "foo"))
+
+	assert.Equal(t, "8081:8080", ComposePort("foo", "sockeye"))
+	random := ComposePort("foo", "httppoller")
+	assert.Regexp(t, `^\d+:8080$`, random)
+	assert.NotEqual(t, "8081:8080", random)
+}
+
+func TestReserveHostPort(t *testing.T) {
+	configHome := t.TempDir()
+	_, err := CreateBrokerConfig(configHome, "foo")
+	assert.NoError(t, err)
+	_, err = CreateBrokerConfig(configHome, "bar")
+	assert.NoError(t, err)
+
+	taken, err := HostPort(configHome, "bar", "sockeye")
+	assert.NoError(t, err)
+
+	listener, err := net.Listen("tcp", ":0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	busy := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+
+	testCases := map[string]struct {
+		port      string
+		component string
+		err       string
+	}{
+		"invalid port": {
+			port:      "foo",
+			component: "sockeye",
+			err:       `invalid port "foo"`,
+		},
+		"port out of range": {
+			port:      "70000",
+			component: "sockeye",
+			err:       `invalid port "70000"`,
+		},
+		"assigned to other broker component": {
+			port:      taken,
+			component: "sockeye",
+			err:       "port " + taken + ` is already assigned to "bar/sockeye"`,
+		},
+		"used on the host": {
+			port:      busy,
+			component: "sockeye",
+			err:       "port " + busy + " is not available",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := ReserveHostPort(configHome, "foo", tc.component, tc.port)
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+
+	// releasing the busy port makes it available
+	listener.Close()
+	assert.NoError(t, ReserveHostPort(configHome, "foo", "sockeye", busy))
+	port, err := HostPort(configHome, "foo", "sockeye")
+	assert.NoError(t, err)
+	assert.Equal(t, busy, port)
+	// component keeps its own port
+	assert.NoError(t, ReserveHostPort(configHome, "foo", "sockeye", busy))
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+)
+
+const (
+	// ManagedRedisFile is the state of the Redis container managed by tmctl.
+	ManagedRedisFile = "redis.yaml"
+
+	redisPort     = "6379/tcp"
+	redisUsername = "default"
+	// redisPasswordEnv is read by redis-cli, the server gets
+	// the password from the same variable.
+	redisPasswordEnv = "REDISCLI_AUTH"
+
+	redisHealthInterval = time.Second
+	redisHealthRetries  = 5
+)
+
+// ManagedRedis is the local Redis container that persists the broker
+// events in the named Docker volume. Redis port is not published on
+// the host, it is reachable in the broker components network only.
+type ManagedRedis struct {
+	Broker   string `yaml:"-"`
+	Password string `yaml:"password"`
+
+	images config.Images
+}
+
+// NewManagedRedis creates the managed Redis state with the random
+// password and stores it in the broker directory.
+func NewManagedRedis(configHome, broker string) (*ManagedRedis, error) {
+	password := make([]byte, 16)
+	if _, err := rand.Read(password); err != nil {
+		return nil, fmt.Errorf("generating password: %w", err)
+	}
+	r := &ManagedRedis{
+		Broker:   broker,
+		Password: hex.EncodeToString(password),
+	}
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("marshal redis state: %w", err)
+	}
+	return r, os.WriteFile(filepath.Join(configHome, broker, ManagedRedisFile), data, 0600)
+}
+
+// LoadManagedRedis reads the managed Redis state from the broker directory.
+// Nil is returned if the broker does not use the managed Redis.
+func LoadManagedRedis(configHome, broker string) (*ManagedRedis, error) {
+	data, err := os.ReadFile(filepath.Join(configHome, broker, ManagedRedisFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read redis state: %w", err)
+	}
+	r := &ManagedRedis{Broker: broker}
+	if err := yaml.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("decode redis state: %w", err)
+	}
+	return r, nil
+}
+
+// ContainerName returns the name of the Redis container.
+func (r *ManagedRedis) ContainerName() string {
+	return r.Broker + "-redis"
+}
+
+// Volume returns the name of the Docker volume with the Redis data.
+func (r *ManagedRedis) Volume() string {
+	return r.Broker + "-redis-data"
+}
+
+// BrokerConfig returns the broker configuration pointing to the managed Redis.
+func (r *ManagedRedis) BrokerConfig(c config.BrokerConfig) config.BrokerConfig {
+	c.Memory = nil
+	c.Redis = &config.RedisBrokerConfig{
+		Address:  r.ContainerName() + ":6379",
+		Username: redisUsername,
+		Password: r.Password,
+	}
+	return c
+}
+
+// command starts the server with the password from the environment variable
+// so that it is not exposed in the container command. The image entrypoint
+// is called to run the server as the unprivileged user. Docker Compose
+// requires the "$" to be escaped to skip the variable interpolation.
+func (r *ManagedRedis) command(variable string) []string {
+	return []string{"sh", "-c", fmt.Sprintf("exec docker-entrypoint.sh redis-server --appendonly yes --requirepass \"%s\"", variable)}
+}
+
+func (r *ManagedRedis) env() []string {
+	return []string{redisPasswordEnv + "=" + r.Password}
+}
+
+func (r *ManagedRedis) image() string {
+	return r.images.Image("Redis", config.ManagedRedisImage)
+}
+
+func (r *ManagedRedis) asContainer() *docker.Container {
+	return &docker.Container{
+		Name:  r.ContainerName(),
+		Image: r.image(),
+		CreateContainerOptions: []docker.ContainerOption{
+			docker.WithImage(r.image()),
+			docker.WithCmd(r.command("$" + redisPasswordEnv)),
+			docker.WithEnv(r.env()),
+			docker.WithPort(redisPort),
+			docker.WithHealthCheck([]string{"redis-cli", "ping"},
+				redisHealthInterval, redisHealthInterval, redisHealthRetries),
+		},
+		CreateHostOptions: []docker.HostOption{
+			docker.WithVolumeBind(r.Volume() + ":/data"),
+			docker.WithNetwork(docker.NetworkName(r.Broker)),
+		},
+	}
+}
+
+// AsDockerComposeObject returns the Redis service, its port is not published.
+func (r *ManagedRedis) AsDockerComposeObject() *docker.ComposeService {
+	return &docker.ComposeService{
+		ContainerName: r.ContainerName(),
+		Image:         r.image(),
+		Command:       r.command("$$" + redisPasswordEnv),
+		Ports:         []string{},
+		Environment:   r.env(),
+		Volumes:       []string{r.Volume() + ":/data"},
+	}
+}
+
+// Start starts the Redis container if it is not running.
+func (r *ManagedRedis) Start(ctx context.Context, runtime docker.Runtime) (*docker.Container, error) {
+	return r.asContainer().Start(ctx, runtime, false)
+}
+
+// Stop removes the Redis container, data volume is kept.
+func (r *ManagedRedis) Stop(ctx context.Context, runtime docker.Runtime) error {
+	return docker.ForceStop(ctx, r.ContainerName(), runtime)
+}
+
+// Remove deletes the Redis container and its data volume.
+func (r *ManagedRedis) Remove(ctx context.Context, runtime docker.Runtime) error {
+	if err := r.Stop(ctx, runtime); err != nil {
+		return fmt.Errorf("removing container: %w", err)
+	}
+	if err := runtime.RemoveVolume(ctx, r.Volume()); err != nil {
+		return fmt.Errorf("removing volume: %w", err)
+	}
+	return nil
+}
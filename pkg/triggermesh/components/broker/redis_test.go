@@ -0,0 +1,130 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+)
+
+func TestManagedRedis(t *testing.T) {
+	configBase := t.TempDir()
+	_, err := CreateBrokerConfig(configBase, "foo")
+	assert.NoError(t, err)
+
+	redis, err := LoadManagedRedis(configBase, "foo")
+	assert.NoError(t, err)
+	assert.Nil(t, redis)
+
+	created, err := NewManagedRedis(configBase, "foo")
+	assert.NoError(t, err)
+	assert.Len(t, created.Password, 32)
+
+	redis, err = LoadManagedRedis(configBase, "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, created, redis)
+	assert.Equal(t, "foo-redis", redis.ContainerName())
+	assert.Equal(t, "foo-redis-data", redis.Volume())
+	assert.Equal(t, []string{"foo-redis-data:/data"}, redis.AsDockerComposeObject().Volumes)
+}
+
+func TestManagedRedisContainer(t *testing.T) {
+	redis := &ManagedRedis{Broker: "foo", Password: "secret"}
+	c := redis.asContainer()
+	cc, hc := &container.Config{}, &container.HostConfig{}
+	for _, option := range c.CreateContainerOptions {
+		option(cc)
+	}
+	for _, option := range c.CreateHostOptions {
+		option(hc)
+	}
+	// port is not published and the password is passed in the environment only
+	assert.Empty(t, hc.PortBindings)
+	assert.Contains(t, cc.Env, "REDISCLI_AUTH=secret")
+	assert.NotContains(t, strings.Join(cc.Cmd, " "), "secret")
+	assert.NotContains(t, strings.Join(cc.Healthcheck.Test, " "), "secret")
+
+	service := redis.AsDockerComposeObject()
+	assert.Empty(t, service.Ports)
+	assert.Contains(t, service.Environment, "REDISCLI_AUTH=secret")
+	assert.Contains(t, strings.Join(service.Command, " "), `"$$REDISCLI_AUTH"`)
+}
+
+func TestManagedRedisBroker(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	configHome := config.HomeAbsPath()
+	_, err := CreateBrokerConfig(configHome, "foo")
+	assert.NoError(t, err)
+	redis, err := NewManagedRedis(configHome, "foo")
+	assert.NoError(t, err)
+
+	brokerConfig := config.BrokerConfig{
+		Version: "v1.1.0",
+		Memory:  &config.InMemoryBrokerConfig{BufferSize: "100", ProduceTimeout: "1s"},
+	}
+	component, err := New(configHome, "foo", brokerConfig, config.Images{})
+	assert.NoError(t, err)
+	b := component.(*Broker)
+	assert.Equal(t, redis, b.ManagedRedis())
+	assert.Equal(t, config.RedisBrokerImage+":v1.1.0", b.image)
+	assert.Contains(t, b.entrypoint, "foo-redis:6379")
+	assert.NotContains(t, b.entrypoint, redis.Password)
+	assert.Equal(t, redis.Password, b.env["REDIS_PASSWORD"])
+
+	service, err := b.AsDockerComposeObject(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, service.(*docker.ComposeService).Entrypoint, "foo-redis:6379")
+	assert.NotContains(t, service.(*docker.ComposeService).Entrypoint, redis.Password)
+	assert.Contains(t, service.(*docker.ComposeService).Environment, "REDIS_PASSWORD="+redis.Password)
+
+	component, err = New(configHome, "bar", brokerConfig, config.Images{})
+	assert.NoError(t, err)
+	assert.Nil(t, component.(*Broker).ManagedRedis())
+	assert.Equal(t, config.MemoryBrokerImage+":v1.1.0", component.(*Broker).image)
+	assert.NoFileExists(t, filepath.Join(configHome, "bar", ManagedRedisFile))
+}
+
+func TestNewBrokerConfigHome(t *testing.T) {
+	// global config home is not used for the broker settings
+	t.Setenv("HOME", t.TempDir())
+	configHome := t.TempDir()
+	_, err := CreateBrokerConfig(configHome, "foo")
+	assert.NoError(t, err)
+	redis, err := NewManagedRedis(configHome, "foo")
+	assert.NoError(t, err)
+
+	component, err := New(configHome, "foo", config.BrokerConfig{Version: "v1.1.0", Memory: &config.InMemoryBrokerConfig{}}, config.Images{})
+	assert.NoError(t, err)
+	b := component.(*Broker)
+	assert.Equal(t, redis, b.ManagedRedis())
+
+	c, err := b.asContainer(nil)
+	assert.NoError(t, err)
+	hc := &container.HostConfig{}
+	for _, option := range c.CreateHostOptions {
+		option(hc)
+	}
+	assert.Equal(t, []string{filepath.Join(configHome, "foo") + ":/etc/triggermesh:ro"}, hc.Binds)
+}
@@ -17,7 +17,6 @@ limitations under the License.
 package broker
 
 import (
-	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
@@ -31,21 +30,23 @@ import (
 
 	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
 	eventingv1alpha1 "github.com/triggermesh/triggermesh-core/pkg/apis/eventing/v1alpha1"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 
 	"github.com/triggermesh/tmctl/pkg/kubernetes"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 )
 
-const (
-	dockerHost = "http://host.docker.internal"
-)
-
 var _ triggermesh.Component = (*Trigger)(nil)
 
 type Trigger struct {
 	Name       string
 	ConfigBase string
 	LocalURL   *apis.URL
+	// DeadLetterURL is the local address of the dead letter sink.
+	DeadLetterURL *apis.URL
+	// CESQL is the CloudEvents SQL filter expression
+	// combined with the Filters with the logical AND.
+	CESQL string
 
 	eventingv1alpha1.TriggerSpec `yaml:"spec,omitempty"`
 }
@@ -55,8 +56,11 @@ func (t *Trigger) AsK8sObject() (kubernetes.Object, error) {
 		"broker": t.Broker,
 		"target": t.Target,
 	}
-	if len(t.Filters) != 0 {
-		spec["filters"] = t.Filters
+	if filters := JoinFilters(t.Filters, t.CESQL); len(filters) != 0 {
+		spec["filters"] = filters
+	}
+	if t.Delivery != nil {
+		spec["delivery"] = t.Delivery
 	}
 	return kubernetes.Object{
 		APIVersion: APIVersion,
@@ -86,8 +90,10 @@ func (t *Trigger) GetAPIVersion() string {
 
 func (t *Trigger) GetSpec() map[string]interface{} {
 	return map[string]interface{}{
-		"filters": t.Filters,
-		"target":  t.Target,
+		"filters":  t.Filters,
+		"cesql":    t.CESQL,
+		"target":   t.Target,
+		"delivery": t.Delivery,
 	}
 }
 
@@ -95,12 +101,21 @@ func (t *Trigger) SetSpec(spec map[string]interface{}) {
 	if filters, ok := spec["filters"]; ok {
 		t.Filters = filters.([]eventingbroker.Filter)
 	}
+	if cesql, ok := spec["cesql"]; ok {
+		t.CESQL = cesql.(string)
+	}
 	if target, ok := spec["target"]; ok {
 		t.Target = target.(duckv1.Destination)
 	}
+	if delivery, ok := spec["delivery"]; ok {
+		t.Delivery = delivery.(*eventingduckv1.DeliverySpec)
+	}
 }
 
-func NewTrigger(name, broker, configBase string, target triggermesh.Component, filter *eventingbroker.Filter) (triggermesh.Component, error) {
+// NewTrigger returns the trigger with the filters. Multiple filters are combined
+// with the logical AND. Options are applied before the trigger name is generated
+// from the target, filters and CESQL expression if the name is empty.
+func NewTrigger(name, broker, configBase string, target triggermesh.Component, filters []eventingbroker.Filter, options ...func(*Trigger)) (triggermesh.Component, error) {
 	trigger := &Trigger{
 		Name:       name,
 		ConfigBase: configBase,
@@ -112,20 +127,32 @@ func NewTrigger(name, broker, configBase string, target triggermesh.Component, f
 			},
 		},
 	}
+	for _, option := range options {
+		option(trigger)
+	}
 
 	if name == "" {
-		filterStruct, _ := yaml.Marshal(filter)
+		// single filter is hashed as is to keep names of the existing triggers
+		var filterStruct []byte
+		switch len(filters) {
+		case 0:
+			filterStruct, _ = yaml.Marshal((*eventingbroker.Filter)(nil))
+		case 1:
+			filterStruct, _ = yaml.Marshal(&filters[0])
+		default:
+			filterStruct, _ = yaml.Marshal(filters)
+		}
+		if trigger.CESQL != "" {
+			filterStruct = append(filterStruct, trigger.CESQL...)
+		}
 		// in case of event types hash collision, replace with sha256
 		hash := md5.Sum([]byte(fmt.Sprintf("%s-%s", target.GetName(), string(filterStruct))))
 		trigger.Name = fmt.Sprintf("%s-trigger-%s", broker, hex.EncodeToString(hash[:4]))
 	}
 
 	if target != nil {
-		targetPort, err := target.(triggermesh.Consumer).GetPort(context.Background())
-		if err != nil {
-			return nil, fmt.Errorf("target local port: %w", err)
-		}
-		trigger.LocalURL, err = apis.ParseURL(fmt.Sprintf("%s:%s", dockerHost, targetPort))
+		var err error
+		trigger.LocalURL, err = componentURL(target.GetName())
 		if err != nil {
 			return nil, fmt.Errorf("target local URL: %w", err)
 		}
@@ -138,8 +165,8 @@ func NewTrigger(name, broker, configBase string, target triggermesh.Component, f
 		}
 	}
 
-	if filter != nil {
-		trigger.Filters = []eventingbroker.Filter{*filter}
+	if len(filters) != 0 {
+		trigger.Filters = filters
 	}
 	return trigger, nil
 }
@@ -152,18 +179,18 @@ func (t *Trigger) SetTarget(target triggermesh.Component) {
 			APIVersion: target.GetAPIVersion(),
 		},
 	}
-	if consumer, ok := target.(triggermesh.Consumer); ok {
-		port, err := consumer.GetPort(context.Background())
-		if err != nil {
-			return
-		}
-		t.LocalURL, err = apis.ParseURL(fmt.Sprintf("%s:%s", dockerHost, port))
-		if err != nil {
-			return
+	if _, ok := target.(triggermesh.Consumer); ok {
+		if url, err := componentURL(target.GetName()); err == nil {
+			t.LocalURL = url
 		}
 	}
 }
 
+// componentURL returns the component address in the broker components network.
+func componentURL(component string) (*apis.URL, error) {
+	return apis.ParseURL(fmt.Sprintf("http://%s:8080", component))
+}
+
 func (t *Trigger) LookupTarget() {
 	config, err := readBrokerConfig(filepath.Join(t.ConfigBase, t.Broker.Name, triggermesh.BrokerConfigFile))
 	if err != nil {
@@ -176,7 +203,8 @@ func (t *Trigger) LookupTarget() {
 	if url, _ := apis.ParseURL(localTrigger.Target.URL); url != nil {
 		t.LocalURL = url
 	}
-	t.Filters = localTrigger.Filters
+	t.Filters, t.CESQL = SplitFilters(localTrigger.Filters)
+	t.lookupDelivery(localTrigger.Target)
 	t.Target = duckv1.Destination{
 		Ref: &duckv1.KReference{
 			Name: localTrigger.Target.Component,
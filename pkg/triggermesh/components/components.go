@@ -18,12 +18,13 @@ package components
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"strings"
 
-	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
 	"gopkg.in/yaml.v3"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
 
 	"github.com/triggermesh/tmctl/pkg/config"
@@ -47,7 +48,7 @@ func GetObject(name string, config *config.Config, manifest *manifest.Manifest,
 		if !set {
 			return nil, fmt.Errorf("context label not set")
 		}
-		crd := crds[strings.ToLower(object.Kind)]
+		kindCRD := crds[strings.ToLower(object.Kind)]
 		switch object.APIVersion {
 		case "sources.triggermesh.io/v1alpha1":
 			status := make(map[string]interface{}, 0)
@@ -60,29 +61,37 @@ func GetObject(name string, config *config.Config, manifest *manifest.Manifest,
 					}
 				}
 			}
-			return source.New(object.Metadata.Name, object.Kind, broker, config.Triggermesh.ComponentsVersion, crd, object.Spec, status), nil
+			return source.New(object.Metadata.Name, object.Kind, broker, config.Triggermesh.ComponentsVersion, config.Images, kindCRD, object.Spec, status), nil
 		case "targets.triggermesh.io/v1alpha1":
-			return target.New(object.Metadata.Name, object.Kind, broker, config.Triggermesh.ComponentsVersion, crd, object.Spec), nil
+			return target.New(object.Metadata.Name, object.Kind, broker, config.Triggermesh.ComponentsVersion, config.Images, kindCRD, object.Spec), nil
 		case "flow.triggermesh.io/v1alpha1":
-			return transformation.New(object.Metadata.Name, object.Kind, broker, config.Triggermesh.ComponentsVersion, crd, object.Spec), nil
+			return transformation.New(object.Metadata.Name, object.Kind, broker, config.Triggermesh.ComponentsVersion, config.Images, kindCRD, object.Spec), nil
 		case "eventing.triggermesh.io/v1alpha1":
 			switch object.Kind {
 			case "RedisBroker":
-				return tmbroker.New(object.Metadata.Name, config.Triggermesh.Broker)
+				return tmbroker.New(config.ConfigHome, object.Metadata.Name, config.Triggermesh.Broker, config.Images)
 			case "Trigger":
 				brokerConfigPath := filepath.Dir(manifest.Path)
 				baseConfigPath := filepath.Dir(brokerConfigPath)
-				targetName, filter, err := parseTriggerSpec(object.Spec)
+				targetName, triggerFilters, delivery, err := parseTriggerSpec(object.Spec)
 				if err != nil {
 					return nil, fmt.Errorf("trigger spec: %w", err)
 				}
-				trigger, err := tmbroker.NewTrigger(object.Metadata.Name, broker, baseConfigPath, nil, filter)
+				filters, cesql := tmbroker.SplitFilters(triggerFilters)
+				trigger, err := tmbroker.NewTrigger(object.Metadata.Name, broker, baseConfigPath, nil, filters)
 				if err != nil {
 					return nil, fmt.Errorf("trigger object: %w", err)
 				}
+				trigger.(*tmbroker.Trigger).CESQL = cesql
 				if target, _ := GetObject(targetName, config, manifest, crds); target != nil {
 					trigger.(*tmbroker.Trigger).SetTarget(target)
 				}
+				trigger.(*tmbroker.Trigger).Delivery = delivery
+				if delivery != nil && delivery.DeadLetterSink != nil && delivery.DeadLetterSink.Ref != nil {
+					if sink, _ := GetObject(delivery.DeadLetterSink.Ref.Name, config, manifest, crds); sink != nil {
+						trigger.(*tmbroker.Trigger).SetDeadLetter(sink)
+					}
+				}
 				return trigger, nil
 			}
 		case "serving.knative.dev/v1":
@@ -117,6 +126,14 @@ func GetObject(name string, config *config.Config, manifest *manifest.Manifest,
 			if object.Kind == "Secret" {
 				return secret.New(object.Metadata.Name, broker, object.Data), nil
 			}
+		default:
+			// user-defined kinds from the custom CRD sources
+			switch {
+			case crd.IsSource(object.APIVersion, object.Kind):
+				return source.New(object.Metadata.Name, object.Kind, broker, config.Triggermesh.ComponentsVersion, config.Images, kindCRD, object.Spec, nil), nil
+			case crd.IsTarget(object.APIVersion, object.Kind):
+				return target.New(object.Metadata.Name, object.Kind, broker, config.Triggermesh.ComponentsVersion, config.Images, kindCRD, object.Spec), nil
+			}
 		}
 	}
 	return nil, nil
@@ -162,21 +179,28 @@ func decodeSecrets(secrets []triggermesh.Component) (map[string]string, error) {
 	return result, nil
 }
 
-func parseTriggerSpec(spec map[string]interface{}) (string, *eventingbroker.Filter, error) {
+func parseTriggerSpec(spec map[string]interface{}) (string, []tmbroker.TriggerFilter, *eventingduckv1.DeliverySpec, error) {
 	triggerSpec, err := yaml.Marshal(spec)
 	if err != nil {
-		return "", nil, err
+		return "", nil, nil, err
 	}
 	t := struct {
-		Filters []eventingbroker.Filter `yaml:"filters,omitempty"`
-		Target  duckv1.Destination      `yaml:"target"`
+		Filters []tmbroker.TriggerFilter `yaml:"filters,omitempty"`
+		Target  duckv1.Destination       `yaml:"target"`
 	}{}
 	if err := yaml.Unmarshal(triggerSpec, &t); err != nil {
-		return "", nil, err
+		return "", nil, nil, err
 	}
-	var filter *eventingbroker.Filter
-	if len(t.Filters) == 1 {
-		filter = &t.Filters[0]
+	// delivery spec fields are camel-cased, use JSON tags to decode them
+	var delivery *eventingduckv1.DeliverySpec
+	if d, set := spec["delivery"]; set && d != nil {
+		data, err := json.Marshal(d)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		if err := json.Unmarshal(data, &delivery); err != nil {
+			return "", nil, nil, fmt.Errorf("delivery spec: %w", err)
+		}
 	}
-	return t.Target.Ref.Name, filter, nil
+	return t.Target.Ref.Name, t.Filters, delivery, nil
 }
@@ -71,7 +71,7 @@ func TestProcessSecrets(t *testing.T) {
 
 	for name, spec := range specs {
 		t.Run(name, func(t *testing.T) {
-			s := source.New("foo-awss3source", "awss3source", "foo", version, test.CRD()["awss3source"], spec, nil)
+			s := source.New("foo-awss3source", "awss3source", "foo", version, config.Images{}, test.CRD()["awss3source"], spec, nil)
 			secrets, plainValues, err := ProcessSecrets(s.(triggermesh.Parent), m)
 			assert.NoError(t, err)
 
@@ -20,7 +20,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"strconv"
 	"strings"
 	"time"
 
@@ -34,6 +33,7 @@ import (
 	"github.com/triggermesh/tmctl/pkg/kubernetes"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/pkg"
 )
 
@@ -109,7 +109,7 @@ func (s *Service) AsDockerComposeObject(additionalEnvs map[string]string) (inter
 		ContainerName: s.Name,
 		Image:         s.Image,
 		Environment:   pkg.EnvsToString(envs),
-		Ports:         []string{strconv.Itoa(pkg.OpenPort()) + ":8080"},
+		Ports:         []string{tmbroker.ComposePort(s.Broker, s.Name)},
 	}, nil
 }
 
@@ -154,7 +154,7 @@ func (s *Service) asContainer(additionalEnvs map[string]string) (*docker.Contain
 	return &docker.Container{
 		Name:                   s.Name,
 		Image:                  s.Image,
-		CreateHostOptions:      ho,
+		CreateHostOptions:      append(ho, docker.WithNetwork(docker.NetworkName(s.Broker))),
 		CreateContainerOptions: co,
 	}, nil
 }
@@ -224,54 +224,59 @@ func (s *Service) SetEventAttributes(attributes map[string]string) error {
 }
 
 func (s *Service) Start(ctx context.Context, additionalEnvs map[string]string, restart bool) (*docker.Container, error) {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return nil, fmt.Errorf("docker client: %w", err)
+		return nil, fmt.Errorf("container runtime: %w", err)
 	}
 	container, err := s.asContainer(additionalEnvs)
 	if err != nil {
 		return nil, fmt.Errorf("container object: %w", err)
 	}
-	return container.Start(ctx, client, restart)
+	hostPort, err := tmbroker.WithHostPort(s.Broker, s.Name)
+	if err != nil {
+		return nil, err
+	}
+	container.CreateHostOptions = append(container.CreateHostOptions, hostPort)
+	return container.Start(ctx, runtime, restart)
 }
 
 func (s *Service) Stop(ctx context.Context) error {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return fmt.Errorf("docker client: %w", err)
+		return fmt.Errorf("container runtime: %w", err)
 	}
 	container, err := s.asContainer(nil)
 	if err != nil {
 		return fmt.Errorf("container object: %w", err)
 	}
-	return container.Remove(ctx, client)
+	return container.Remove(ctx, runtime)
 }
 
 func (s *Service) Info(ctx context.Context) (*docker.Container, error) {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return nil, fmt.Errorf("docker client: %w", err)
+		return nil, fmt.Errorf("container runtime: %w", err)
 	}
 	container, err := s.asContainer(nil)
 	if err != nil {
 		return nil, fmt.Errorf("container object: %w", err)
 	}
-	return container.LookupHostConfig(ctx, client)
+	return container.LookupHostConfig(ctx, runtime)
 }
 
 func (s *Service) Logs(ctx context.Context, since time.Time, follow bool) (io.ReadCloser, error) {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return nil, fmt.Errorf("docker client: %w", err)
+		return nil, fmt.Errorf("container runtime: %w", err)
 	}
 	container, err := s.asContainer(nil)
 	if err != nil {
 		return nil, fmt.Errorf("container object: %w", err)
 	}
-	if _, err := container.LookupHostConfig(ctx, client); err != nil {
+	if _, err := container.LookupHostConfig(ctx, runtime); err != nil {
 		return nil, fmt.Errorf("container config: %w", err)
 	}
-	return container.Logs(ctx, client, since, follow)
+	return container.Logs(ctx, runtime, since, follow)
 }
 
 func New(name, image, broker string, role Role, params map[string]string) triggermesh.Component {
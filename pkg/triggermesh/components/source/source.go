@@ -58,6 +58,7 @@ type Source struct {
 	Broker  string
 	Kind    string
 	Version string
+	Images  config.Images
 
 	spec   map[string]interface{}
 	status map[string]interface{}
@@ -87,7 +88,7 @@ func (s *Source) AsDockerComposeObject(additionalEnvs map[string]string) (interf
 	if err != nil {
 		return nil, fmt.Errorf("creating object: %w", err)
 	}
-	image := adapter.Image(o, s.Version)
+	image := adapter.Image(s.Images, o, s.Version)
 
 	adapterEnv, err := env.Build(o)
 	if err != nil {
@@ -150,18 +151,14 @@ func (s *Source) AsDigitalOceanObject(additionalEnvs map[string]string) (interfa
 	sinkURI := fmt.Sprintf("${%s.PRIVATE_URL}/%s", s.Broker, s.Broker)
 	envs = append(envs, &godo.AppVariableDefinition{Key: "K_SINK", Value: sinkURI, Scope: "RUN_AND_BUILD_TIME"})
 
-	// Get the image and tag
-	imageURI := strings.Split(adapter.Image(o, ""), "/")
-	adapterName := strings.TrimRight(imageURI[len(imageURI)-1], ":")
+	image, err := adapter.DigitalOceanImage(adapter.PublicImage(s.Images, o, s.Version))
+	if err != nil {
+		return nil, fmt.Errorf("adapter image: %w", err)
+	}
 
 	return godo.AppWorkerSpec{
-		Name: s.Name,
-		Image: &godo.ImageSourceSpec{
-			RegistryType: godo.ImageSourceSpecRegistryType_DockerHub,
-			Registry:     config.DockerRegistry,
-			Repository:   adapterName,
-			Tag:          s.Version,
-		},
+		Name:             s.Name,
+		Image:            image,
 		Envs:             envs,
 		InstanceCount:    1,
 		InstanceSizeSlug: "professional-xs",
@@ -192,7 +189,7 @@ func (s *Source) asContainer(additionalEnvs map[string]string) (*docker.Containe
 	if err != nil {
 		return nil, fmt.Errorf("creating object: %w", err)
 	}
-	image := adapter.Image(o, s.Version)
+	image := adapter.Image(s.Images, o, s.Version)
 	co, ho, err := adapter.RuntimeParams(o, image, additionalEnvs)
 	if err != nil {
 		return nil, fmt.Errorf("creating adapter params: %w", err)
@@ -200,7 +197,7 @@ func (s *Source) asContainer(additionalEnvs map[string]string) (*docker.Containe
 	return &docker.Container{
 		Name:                   s.GetName(),
 		Image:                  image,
-		CreateHostOptions:      ho,
+		CreateHostOptions:      append(ho, docker.WithNetwork(docker.NetworkName(s.Broker))),
 		CreateContainerOptions: co,
 	}, nil
 }
@@ -290,54 +287,63 @@ func (s *Source) SetEventAttributes(map[string]string) error {
 }
 
 func (s *Source) Start(ctx context.Context, additionalEnvs map[string]string, restart bool) (*docker.Container, error) {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return nil, fmt.Errorf("docker client: %w", err)
+		return nil, fmt.Errorf("container runtime: %w", err)
 	}
 	container, err := s.asContainer(additionalEnvs)
 	if err != nil {
 		return nil, fmt.Errorf("container object: %w", err)
 	}
-	return container.Start(ctx, client, restart)
+	hostPort, err := tmbroker.WithHostPort(s.Broker, s.Name)
+	if err != nil {
+		return nil, err
+	}
+	metricsHostPort, err := tmbroker.WithMetricsHostPort(s.Broker, s.Name)
+	if err != nil {
+		return nil, err
+	}
+	container.CreateHostOptions = append(container.CreateHostOptions, hostPort, metricsHostPort)
+	return container.Start(ctx, runtime, restart)
 }
 
 func (s *Source) Stop(ctx context.Context) error {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return fmt.Errorf("docker client: %w", err)
+		return fmt.Errorf("container runtime: %w", err)
 	}
 	container, err := s.asContainer(nil)
 	if err != nil {
 		return fmt.Errorf("container object: %w", err)
 	}
-	return container.Remove(ctx, client)
+	return container.Remove(ctx, runtime)
 }
 
 func (s *Source) Info(ctx context.Context) (*docker.Container, error) {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return nil, fmt.Errorf("docker client: %w", err)
+		return nil, fmt.Errorf("container runtime: %w", err)
 	}
 	container, err := s.asContainer(nil)
 	if err != nil {
 		return nil, fmt.Errorf("container object: %w", err)
 	}
-	return container.LookupHostConfig(ctx, client)
+	return container.LookupHostConfig(ctx, runtime)
 }
 
 func (s *Source) Logs(ctx context.Context, since time.Time, follow bool) (io.ReadCloser, error) {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return nil, fmt.Errorf("docker client: %w", err)
+		return nil, fmt.Errorf("container runtime: %w", err)
 	}
 	container, err := s.asContainer(nil)
 	if err != nil {
 		return nil, fmt.Errorf("container object: %w", err)
 	}
-	if _, err := container.LookupHostConfig(ctx, client); err != nil {
+	if _, err := container.LookupHostConfig(ctx, runtime); err != nil {
 		return nil, fmt.Errorf("container config: %w", err)
 	}
-	return container.Logs(ctx, client, since, follow)
+	return container.Logs(ctx, runtime, since, follow)
 }
 
 func (s *Source) Initialize(ctx context.Context, secrets map[string]string) (map[string]interface{}, error) {
@@ -364,7 +370,7 @@ func (s *Source) GetExternalResources() map[string]interface{} {
 	return s.status
 }
 
-func New(name, kind, broker, version string, crd crd.CRD, params interface{}, status map[string]interface{}) triggermesh.Component {
+func New(name, kind, broker, version string, images config.Images, crd crd.CRD, params interface{}, status map[string]interface{}) triggermesh.Component {
 	var spec map[string]interface{}
 	switch p := params.(type) {
 	case map[string]string:
@@ -391,6 +397,7 @@ func New(name, kind, broker, version string, crd crd.CRD, params interface{}, st
 		Broker:  broker,
 		Kind:    k,
 		Version: version,
+		Images:  images,
 		spec:    spec,
 		status:  status,
 	}
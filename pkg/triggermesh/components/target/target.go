@@ -20,7 +20,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"strconv"
 	"strings"
 	"time"
 
@@ -35,6 +34,7 @@ import (
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter/env"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components/secret"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/pkg"
@@ -55,6 +55,7 @@ type Target struct {
 
 	Broker  string
 	Version string
+	Images  config.Images
 	Kind    string
 
 	spec map[string]interface{}
@@ -83,7 +84,7 @@ func (t *Target) AsDockerComposeObject(additionalEnvs map[string]string) (interf
 	if err != nil {
 		return nil, fmt.Errorf("creating object: %w", err)
 	}
-	image := adapter.Image(o, t.Version)
+	image := adapter.Image(t.Images, o, t.Version)
 
 	adapterEnv, err := env.Build(o)
 	if err != nil {
@@ -110,7 +111,7 @@ func (t *Target) AsDockerComposeObject(additionalEnvs map[string]string) (interf
 		ContainerName: t.Name,
 		Image:         image,
 		Environment:   pkg.EnvsToString(envs),
-		Ports:         []string{strconv.Itoa(pkg.OpenPort()) + ":8080"},
+		Ports:         []string{tmbroker.ComposePort(t.Broker, t.Name)},
 	}, nil
 }
 
@@ -141,18 +142,14 @@ func (t *Target) AsDigitalOceanObject(additionalEnvs map[string]string) (interfa
 		envs = append(envs, &godo.AppVariableDefinition{Key: k, Value: v})
 	}
 
-	// Get the image and tag
-	imageURI := strings.Split(adapter.Image(o, ""), "/")
-	adapterName := strings.TrimRight(imageURI[len(imageURI)-1], ":")
+	image, err := adapter.DigitalOceanImage(adapter.PublicImage(t.Images, o, t.Version))
+	if err != nil {
+		return nil, fmt.Errorf("adapter image: %w", err)
+	}
 
 	return godo.AppServiceSpec{
-		Name: t.Name,
-		Image: &godo.ImageSourceSpec{
-			RegistryType: godo.ImageSourceSpecRegistryType_DockerHub,
-			Registry:     config.DockerRegistry,
-			Repository:   adapterName,
-			Tag:          t.Version,
-		},
+		Name:             t.Name,
+		Image:            image,
 		InternalPorts:    []int64{8080},
 		Envs:             envs,
 		InstanceCount:    1,
@@ -165,7 +162,7 @@ func (t *Target) asContainer(additionalEnvs map[string]string) (*docker.Containe
 	if err != nil {
 		return nil, fmt.Errorf("creating object: %w", err)
 	}
-	image := adapter.Image(o, t.Version)
+	image := adapter.Image(t.Images, o, t.Version)
 	co, ho, err := adapter.RuntimeParams(o, image, additionalEnvs)
 	if err != nil {
 		return nil, fmt.Errorf("creating adapter params: %w", err)
@@ -173,7 +170,7 @@ func (t *Target) asContainer(additionalEnvs map[string]string) (*docker.Containe
 	return &docker.Container{
 		Name:                   t.GetName(),
 		Image:                  image,
-		CreateHostOptions:      ho,
+		CreateHostOptions:      append(ho, docker.WithNetwork(docker.NetworkName(t.Broker))),
 		CreateContainerOptions: co,
 	}, nil
 }
@@ -234,57 +231,66 @@ func (t *Target) ConsumedEventTypes() ([]string, error) {
 }
 
 func (t *Target) Start(ctx context.Context, additionalEnvs map[string]string, restart bool) (*docker.Container, error) {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return nil, fmt.Errorf("docker client: %w", err)
+		return nil, fmt.Errorf("container runtime: %w", err)
 	}
 	container, err := t.asContainer(additionalEnvs)
 	if err != nil {
 		return nil, fmt.Errorf("container object: %w", err)
 	}
-	return container.Start(ctx, client, restart)
+	hostPort, err := tmbroker.WithHostPort(t.Broker, t.Name)
+	if err != nil {
+		return nil, err
+	}
+	metricsHostPort, err := tmbroker.WithMetricsHostPort(t.Broker, t.Name)
+	if err != nil {
+		return nil, err
+	}
+	container.CreateHostOptions = append(container.CreateHostOptions, hostPort, metricsHostPort)
+	return container.Start(ctx, runtime, restart)
 }
 
 func (t *Target) Stop(ctx context.Context) error {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return fmt.Errorf("docker client: %w", err)
+		return fmt.Errorf("container runtime: %w", err)
 	}
 	container, err := t.asContainer(nil)
 	if err != nil {
 		return fmt.Errorf("container object: %w", err)
 	}
-	return container.Remove(ctx, client)
+	return container.Remove(ctx, runtime)
 }
 
 func (t *Target) Info(ctx context.Context) (*docker.Container, error) {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return nil, fmt.Errorf("docker client: %w", err)
+		return nil, fmt.Errorf("container runtime: %w", err)
 	}
 	container, err := t.asContainer(nil)
 	if err != nil {
 		return nil, fmt.Errorf("container object: %w", err)
 	}
-	return container.LookupHostConfig(ctx, client)
+	return container.LookupHostConfig(ctx, runtime)
 }
 
 func (t *Target) Logs(ctx context.Context, since time.Time, follow bool) (io.ReadCloser, error) {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return nil, fmt.Errorf("docker client: %w", err)
+		return nil, fmt.Errorf("container runtime: %w", err)
 	}
 	container, err := t.asContainer(nil)
 	if err != nil {
 		return nil, fmt.Errorf("container object: %w", err)
 	}
-	if _, err := container.LookupHostConfig(ctx, client); err != nil {
+	if _, err := container.LookupHostConfig(ctx, runtime); err != nil {
 		return nil, fmt.Errorf("container config: %w", err)
 	}
-	return container.Logs(ctx, client, since, follow)
+	return container.Logs(ctx, runtime, since, follow)
 }
 
-func New(name, kind, broker, version string, crd crd.CRD, params interface{}) triggermesh.Component {
+func New(name, kind, broker, version string, images config.Images, crd crd.CRD, params interface{}) triggermesh.Component {
 	var spec map[string]interface{}
 	switch p := params.(type) {
 	case map[string]string:
@@ -312,6 +318,7 @@ func New(name, kind, broker, version string, crd crd.CRD, params interface{}) tr
 		Broker:  broker,
 		Kind:    k,
 		Version: version,
+		Images:  images,
 		spec:    spec,
 	}
 }
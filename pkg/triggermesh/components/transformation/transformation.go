@@ -20,8 +20,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"strconv"
-	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -35,6 +33,7 @@ import (
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter/env"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/pkg"
 )
@@ -52,6 +51,7 @@ type Transformation struct {
 	CRD     crd.CRD
 	Broker  string
 	Version string
+	Images  config.Images
 
 	spec map[string]interface{}
 }
@@ -79,7 +79,7 @@ func (t *Transformation) AsDockerComposeObject(additionalEnvs map[string]string)
 	if err != nil {
 		return nil, fmt.Errorf("creating object: %w", err)
 	}
-	image := adapter.Image(o, t.Version)
+	image := adapter.Image(t.Images, o, t.Version)
 
 	adapterEnv, err := env.Build(o)
 	if err != nil {
@@ -106,7 +106,7 @@ func (t *Transformation) AsDockerComposeObject(additionalEnvs map[string]string)
 		ContainerName: t.Name,
 		Image:         image,
 		Environment:   pkg.EnvsToString(envs),
-		Ports:         []string{strconv.Itoa(pkg.OpenPort()) + ":8080"},
+		Ports:         []string{tmbroker.ComposePort(t.Broker, t.Name)},
 	}, nil
 }
 
@@ -137,18 +137,14 @@ func (t *Transformation) AsDigitalOceanObject(additionalEnvs map[string]string)
 		envs = append(envs, &godo.AppVariableDefinition{Key: k, Value: v})
 	}
 
-	// Get the image and tag
-	imageURI := strings.Split(adapter.Image(o, ""), "/")
-	adapterName := strings.TrimRight(imageURI[len(imageURI)-1], ":")
+	image, err := adapter.DigitalOceanImage(adapter.PublicImage(t.Images, o, t.Version))
+	if err != nil {
+		return nil, fmt.Errorf("adapter image: %w", err)
+	}
 
 	return godo.AppServiceSpec{
-		Name: t.Name,
-		Image: &godo.ImageSourceSpec{
-			RegistryType: godo.ImageSourceSpecRegistryType_DockerHub,
-			Registry:     config.DockerRegistry,
-			Repository:   adapterName,
-			Tag:          t.Version,
-		},
+		Name:             t.Name,
+		Image:            image,
 		InternalPorts:    []int64{8080},
 		Envs:             envs,
 		InstanceCount:    1,
@@ -161,7 +157,7 @@ func (t *Transformation) asContainer(additionalEnvs map[string]string) (*docker.
 	if err != nil {
 		return nil, fmt.Errorf("creating object: %w", err)
 	}
-	image := adapter.Image(o, t.Version)
+	image := adapter.Image(t.Images, o, t.Version)
 	co, ho, err := adapter.RuntimeParams(o, image, additionalEnvs)
 	if err != nil {
 		return nil, fmt.Errorf("creating adapter params: %w", err)
@@ -169,7 +165,7 @@ func (t *Transformation) asContainer(additionalEnvs map[string]string) (*docker.
 	return &docker.Container{
 		Name:                   t.GetName(),
 		Image:                  image,
-		CreateHostOptions:      ho,
+		CreateHostOptions:      append(ho, docker.WithNetwork(docker.NetworkName(t.Broker))),
 		CreateContainerOptions: co,
 	}, nil
 }
@@ -248,57 +244,66 @@ func (t *Transformation) GetPort(ctx context.Context) (string, error) {
 }
 
 func (t *Transformation) Start(ctx context.Context, additionalEnvs map[string]string, restart bool) (*docker.Container, error) {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return nil, fmt.Errorf("docker client: %w", err)
+		return nil, fmt.Errorf("container runtime: %w", err)
 	}
 	container, err := t.asContainer(additionalEnvs)
 	if err != nil {
 		return nil, fmt.Errorf("container object: %w", err)
 	}
-	return container.Start(ctx, client, restart)
+	hostPort, err := tmbroker.WithHostPort(t.Broker, t.Name)
+	if err != nil {
+		return nil, err
+	}
+	metricsHostPort, err := tmbroker.WithMetricsHostPort(t.Broker, t.Name)
+	if err != nil {
+		return nil, err
+	}
+	container.CreateHostOptions = append(container.CreateHostOptions, hostPort, metricsHostPort)
+	return container.Start(ctx, runtime, restart)
 }
 
 func (t *Transformation) Stop(ctx context.Context) error {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return fmt.Errorf("docker client: %w", err)
+		return fmt.Errorf("container runtime: %w", err)
 	}
 	container, err := t.asContainer(nil)
 	if err != nil {
 		return fmt.Errorf("container object: %w", err)
 	}
-	return container.Remove(ctx, client)
+	return container.Remove(ctx, runtime)
 }
 
 func (t *Transformation) Info(ctx context.Context) (*docker.Container, error) {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return nil, fmt.Errorf("docker client: %w", err)
+		return nil, fmt.Errorf("container runtime: %w", err)
 	}
 	container, err := t.asContainer(nil)
 	if err != nil {
 		return nil, fmt.Errorf("container object: %w", err)
 	}
-	return container.LookupHostConfig(ctx, client)
+	return container.LookupHostConfig(ctx, runtime)
 }
 
 func (t *Transformation) Logs(ctx context.Context, since time.Time, follow bool) (io.ReadCloser, error) {
-	client, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
-		return nil, fmt.Errorf("docker client: %w", err)
+		return nil, fmt.Errorf("container runtime: %w", err)
 	}
 	container, err := t.asContainer(nil)
 	if err != nil {
 		return nil, fmt.Errorf("container object: %w", err)
 	}
-	if _, err := container.LookupHostConfig(ctx, client); err != nil {
+	if _, err := container.LookupHostConfig(ctx, runtime); err != nil {
 		return nil, fmt.Errorf("container config: %w", err)
 	}
-	return container.Logs(ctx, client, since, follow)
+	return container.Logs(ctx, runtime, since, follow)
 }
 
-func New(name, kind, broker, version string, crd crd.CRD, spec map[string]interface{}) triggermesh.Component {
+func New(name, kind, broker, version string, images config.Images, crd crd.CRD, spec map[string]interface{}) triggermesh.Component {
 	if name == "" {
 		name = fmt.Sprintf("%s-transformation", broker)
 	}
@@ -307,6 +312,7 @@ func New(name, kind, broker, version string, crd crd.CRD, spec map[string]interf
 		CRD:     crd,
 		Broker:  broker,
 		Version: version,
+		Images:  images,
 
 		spec: spec,
 	}
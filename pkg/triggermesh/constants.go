@@ -21,6 +21,8 @@ const (
 	Namespace        = "local"
 	ManifestFile     = "manifest.yaml"
 	BrokerConfigFile = "broker.conf"
+	// ObservabilityConfigFile is the broker observability configuration.
+	ObservabilityConfigFile = "observability.yaml"
 
 	UserInputTag = "<user_input>"
 
@@ -17,6 +17,7 @@ limitations under the License.
 package crd
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"net/http"
@@ -27,11 +28,14 @@ import (
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter/custom"
 )
 
 const (
-	crdsURL = "https://github.com/triggermesh/triggermesh/releases/download/$VERSION/triggermesh-crds.yaml"
+	crdsURL     = "https://github.com/triggermesh/triggermesh/releases/download/$VERSION/triggermesh-crds.yaml"
+	crdFileName = "crd.yaml"
 )
 
 type CRD struct {
@@ -74,10 +78,11 @@ type EventTypes []struct {
 	Type string `json:"type"`
 }
 
-// Fetch downloads the release version of TriggerMesh CRDs for specified version.
-func Fetch(configDir, version string) (map[string]CRD, error) {
-	crdDir := filepath.Join(configDir, "crd", version)
-	crdFile := filepath.Join(crdDir, "crd.yaml")
+// Fetch returns the TriggerMesh CRDs of the specified version. CRDs are read from
+// the local cache or from the bundle. Other versions are downloaded from the release
+// and cached, unless the offline mode is enabled.
+func Fetch(configDir, version string, offline bool) (map[string]CRD, error) {
+	crdFile := filepath.Join(configDir, "crd", version, crdFileName)
 	if _, err := os.Stat(crdFile); err == nil {
 		f, err := os.Open(crdFile)
 		if err != nil {
@@ -86,16 +91,13 @@ func Fetch(configDir, version string) (map[string]CRD, error) {
 		defer f.Close()
 		return Parse(f)
 	}
-	if err := os.MkdirAll(crdDir, os.ModePerm); err != nil {
-		return nil, err
+	if version == BundledVersion {
+		return Parse(io.NopCloser(bytes.NewReader(bundle)))
 	}
-	log.Printf("Fetching %s CRD", version)
-	out, err := os.Create(crdFile)
-	if err != nil {
-		return nil, err
+	if offline {
+		return nil, fmt.Errorf("%s CRD is not cached and the offline mode is enabled, use \"tmctl crd import\" to add it", version)
 	}
-	defer out.Close()
-
+	log.Printf("Fetching %s CRD", version)
 	resp, err := http.Get(strings.ReplaceAll(crdsURL, "$VERSION", version))
 	if err != nil {
 		return nil, err
@@ -104,16 +106,88 @@ func Fetch(configDir, version string) (map[string]CRD, error) {
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("CRD request failed: %s", resp.Status)
 	}
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
+	if err := Import(configDir, version, resp.Body); err != nil {
 		return nil, err
 	}
-	f, err := os.Open(crdFile)
+	return Fetch(configDir, version, true)
+}
+
+// Load returns the TriggerMesh CRDs of the configured version
+// along with the CRDs of the user-defined component kinds.
+func Load(c *config.Config) (map[string]CRD, error) {
+	crds, err := Fetch(c.ConfigHome, c.Triggermesh.ComponentsVersion, c.IsOffline())
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-	return Parse(f)
+	for _, path := range c.Triggermesh.CRDSources {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(c.ConfigHome, path)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("custom CRD: %w", err)
+		}
+		custom, err := Parse(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		for kind, crd := range custom {
+			if crd.Kind != "CustomResourceDefinition" {
+				continue
+			}
+			if _, exists := crds[kind]; exists {
+				return nil, fmt.Errorf("%s: kind %q is already defined", path, crd.Spec.Names.Kind)
+			}
+			if !IsSource(crd.Spec.Group, crd.Spec.Names.Kind) && !IsTarget(crd.Spec.Group, crd.Spec.Names.Kind) {
+				return nil, fmt.Errorf("%s: kind %q of the API group %q must have the %q or %q role in the triggermesh.kinds config",
+					path, crd.Spec.Names.Kind, crd.Spec.Group, config.SourceRole, config.TargetRole)
+			}
+			crds[kind] = crd
+		}
+	}
+	return crds, nil
+}
+
+// Import validates the CRDs from the reader and writes them
+// to the local cache as the specified version.
+func Import(configDir, version string, reader io.Reader) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("reading CRD: %w", err)
+	}
+	crds, err := Parse(io.NopCloser(bytes.NewReader(data)))
+	if err != nil {
+		return fmt.Errorf("parsing CRD: %w", err)
+	}
+	var found bool
+	for _, crd := range crds {
+		if crd.Kind == "CustomResourceDefinition" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("no CustomResourceDefinition objects found")
+	}
+	crdDir := filepath.Join(configDir, "crd", version)
+	if err := os.MkdirAll(crdDir, os.ModePerm); err != nil {
+		return err
+	}
+	// partially written file must not be taken as the cached CRD
+	tmp, err := os.CreateTemp(crdDir, crdFileName)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(crdDir, crdFileName))
 }
 
 func Parse(reader io.ReadCloser) (map[string]CRD, error) {
@@ -140,7 +214,7 @@ func Parse(reader io.ReadCloser) (map[string]CRD, error) {
 	return result, nil
 }
 
-// ListSources returns the list of resources of the "source" API group from CRD.
+// ListSources returns the list of resources of the "sources" API groups from CRD.
 func ListSources(crds map[string]CRD) ([]string, error) {
 	// crds, err := parse(crdReader)
 	// if err != nil {
@@ -148,7 +222,7 @@ func ListSources(crds map[string]CRD) ([]string, error) {
 	// }
 	var result []string
 	for k, crd := range crds {
-		if crd.Spec.Group == "sources.triggermesh.io" {
+		if IsSource(crd.Spec.Group, crd.Spec.Names.Kind) {
 			result = append(result, strings.TrimSuffix(k, "source"))
 		}
 	}
@@ -156,7 +230,7 @@ func ListSources(crds map[string]CRD) ([]string, error) {
 	return result, nil
 }
 
-// ListTargets returns the list of resources of the "target" API group from CRD.
+// ListTargets returns the list of resources of the "targets" API groups from CRD.
 func ListTargets(crds map[string]CRD) ([]string, error) {
 	// crds, err := parse(crdReader)
 	// if err != nil {
@@ -164,10 +238,28 @@ func ListTargets(crds map[string]CRD) ([]string, error) {
 	// }
 	var result []string
 	for k, crd := range crds {
-		if crd.Spec.Group == "targets.triggermesh.io" {
+		if IsTarget(crd.Spec.Group, crd.Spec.Names.Kind) {
 			result = append(result, strings.TrimSuffix(k, "target"))
 		}
 	}
 	sort.Strings(result)
 	return result, nil
 }
+
+// IsSource checks if the kind of the API group or version is the event source.
+// User-defined kinds may set their role in the config, other kinds are sources
+// if their group starts with "sources.", e.g. "sources.triggermesh.io".
+func IsSource(group, kind string) bool {
+	if adapter, exists := custom.Lookup(kind); exists && adapter.Role != "" {
+		return adapter.Role == config.SourceRole
+	}
+	return strings.HasPrefix(group, "sources.")
+}
+
+// IsTarget checks if the kind of the API group or version is the event target.
+func IsTarget(group, kind string) bool {
+	if adapter, exists := custom.Lookup(kind); exists && adapter.Role != "" {
+		return adapter.Role == config.TargetRole
+	}
+	return strings.HasPrefix(group, "targets.")
+}
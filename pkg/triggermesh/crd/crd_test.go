@@ -0,0 +1,161 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter/custom"
+)
+
+func TestFetchBundled(t *testing.T) {
+	configDir := t.TempDir()
+	crds, err := Fetch(configDir, BundledVersion, true)
+	assert.NoError(t, err)
+	assert.Contains(t, crds, "awss3source")
+	assert.Contains(t, crds, "transformation")
+	assert.Contains(t, crds, "cloudeventstarget")
+
+	// bundle is not written to the cache
+	_, err = os.Stat(filepath.Join(configDir, "crd", BundledVersion))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFetchOffline(t *testing.T) {
+	configDir := t.TempDir()
+	_, err := Fetch(configDir, "v0.0.1", true)
+	assert.ErrorContains(t, err, "offline mode is enabled")
+
+	bundled, err := Fetch(configDir, BundledVersion, true)
+	assert.NoError(t, err)
+	// imported version takes precedence over the bundle
+	assert.NoError(t, Import(configDir, BundledVersion, strings.NewReader(bundledCRD(t, "httptarget"))))
+	assert.NoError(t, Import(configDir, "v0.0.1", bytes.NewReader(bundle)))
+
+	crds, err := Fetch(configDir, "v0.0.1", true)
+	assert.NoError(t, err)
+	assert.Equal(t, bundled, crds)
+
+	crds, err = Fetch(configDir, BundledVersion, true)
+	assert.NoError(t, err)
+	assert.Len(t, crds, 1)
+	assert.Contains(t, crds, "httptarget")
+}
+
+func TestImport(t *testing.T) {
+	configDir := t.TempDir()
+	cases := map[string]struct {
+		data string
+		err  string
+	}{
+		"valid CRD": {
+			data: bundledCRD(t, "httptarget"),
+		},
+		"not a CRD": {
+			data: "apiVersion: v1\nkind: Secret\nmetadata:\n  name: foo\n",
+			err:  "no CustomResourceDefinition objects found",
+		},
+		"invalid yaml": {
+			data: "kind: [",
+			err:  "parsing CRD",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := Import(configDir, "v1.0.0", strings.NewReader(tc.data))
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			files, err := os.ReadDir(filepath.Join(configDir, "crd", "v1.0.0"))
+			assert.NoError(t, err)
+			assert.Len(t, files, 1)
+			assert.Equal(t, "crd.yaml", files[0].Name())
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	customCRD, err := os.ReadFile(filepath.Join("..", "..", "..", "test", "fixtures", "custom-crd.yaml"))
+	assert.NoError(t, err)
+	c := &config.Config{
+		ConfigHome: t.TempDir(),
+		Offline:    true,
+		Triggermesh: config.TmConfig{
+			ComponentsVersion: BundledVersion,
+			CRDSources:        []string{"inventory.yaml"},
+		},
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(c.ConfigHome, "inventory.yaml"), customCRD, 0644))
+
+	crds, err := Load(c)
+	assert.NoError(t, err)
+	assert.Contains(t, crds, "awss3source")
+	assert.Contains(t, crds, "inventorysource")
+
+	sources, err := ListSources(crds)
+	assert.NoError(t, err)
+	assert.Contains(t, sources, "inventory")
+
+	// built-in kinds cannot be redefined
+	conflict := filepath.Join(t.TempDir(), "conflict.yaml")
+	assert.NoError(t, os.WriteFile(conflict, []byte(bundledCRD(t, "httptarget")), 0644))
+	c.Triggermesh.CRDSources = []string{conflict}
+	_, err = Load(c)
+	assert.ErrorContains(t, err, `kind "HTTPTarget" is already defined`)
+
+	c.Triggermesh.CRDSources = []string{"missing.yaml"}
+	_, err = Load(c)
+	assert.Error(t, err)
+
+	// role of the kinds of other API groups must be set in the config
+	assert.NoError(t, os.WriteFile(filepath.Join(c.ConfigHome, "inventory.yaml"),
+		bytes.ReplaceAll(customCRD, []byte("sources.example.com"), []byte("inventory.example.com")), 0644))
+	c.Triggermesh.CRDSources = []string{"inventory.yaml"}
+	_, err = Load(c)
+	assert.EqualError(t, err, filepath.Join(c.ConfigHome, "inventory.yaml")+`: kind "InventorySource" of the API group "inventory.example.com" must have the "source" or "target" role in the triggermesh.kinds config`)
+
+	custom.Register(map[string]config.CustomKind{"InventorySource": {Role: config.SourceRole}})
+	defer custom.Register(nil)
+	crds, err = Load(c)
+	assert.NoError(t, err)
+	sources, err = ListSources(crds)
+	assert.NoError(t, err)
+	assert.Contains(t, sources, "inventory")
+	targets, err := ListTargets(crds)
+	assert.NoError(t, err)
+	assert.NotContains(t, targets, "inventory")
+}
+
+// bundledCRD returns the single CRD document of the kind from the bundle.
+func bundledCRD(t *testing.T, kind string) string {
+	for _, doc := range strings.Split(string(bundle), "---\n") {
+		if strings.Contains(doc, "name: "+kind+"s.") {
+			return doc
+		}
+	}
+	t.Fatalf("%s CRD is not bundled", kind)
+	return ""
+}
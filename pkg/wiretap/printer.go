@@ -0,0 +1,102 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wiretap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/template"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+const (
+	OutputJSON     = "json"
+	OutputPretty   = "pretty"
+	OutputTemplate = "template"
+)
+
+// Printer writes received events in the selected output format.
+type Printer struct {
+	format   string
+	template *template.Template
+}
+
+// NewPrinter returns the printer for the output format. Template is
+// required for the "template" format and is executed on the event
+// attributes map with "data" and "extensions" keys, e.g. "{{.type}}: {{.data.id}}".
+func NewPrinter(format, text string) (*Printer, error) {
+	p := &Printer{format: format}
+	switch format {
+	case OutputJSON, OutputPretty:
+	case OutputTemplate:
+		if text == "" {
+			return nil, fmt.Errorf("template is not set")
+		}
+		t, err := template.New("event").Option("missingkey=zero").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("template: %w", err)
+		}
+		p.template = t
+	default:
+		return nil, fmt.Errorf("output format %q is not supported", format)
+	}
+	return p, nil
+}
+
+// Print writes the event to the output.
+func (p *Printer) Print(w io.Writer, event cloudevents.Event) error {
+	switch p.format {
+	case OutputJSON:
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case OutputTemplate:
+		if err := p.template.Execute(w, templateData(event)); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(w)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "☁️  cloudevents.Event\n%s", event.String())
+	return err
+}
+
+func templateData(event cloudevents.Event) map[string]interface{} {
+	result := map[string]interface{}{
+		"specversion":     event.SpecVersion(),
+		"id":              event.ID(),
+		"type":            event.Type(),
+		"source":          event.Source(),
+		"subject":         event.Subject(),
+		"time":            event.Time(),
+		"dataschema":      event.DataSchema(),
+		"datacontenttype": event.DataContentType(),
+		"extensions":      event.Extensions(),
+	}
+	var data interface{}
+	if err := json.Unmarshal(event.Data(), &data); err == nil {
+		result["data"] = data
+	} else {
+		result["data"] = string(event.Data())
+	}
+	return result
+}
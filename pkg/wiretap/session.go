@@ -0,0 +1,206 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wiretap
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "knative.dev/pkg/apis/duck/v1"
+
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/triggermesh-core/pkg/apis/eventing/v1alpha1"
+)
+
+const (
+	// SessionsFile is the registry of the active watch sessions in the broker directory.
+	SessionsFile = "wiretap-sessions.json"
+
+	triggerPrefix = "wiretap"
+	probeTimeout  = 500 * time.Millisecond
+)
+
+// registryMutex serializes registry updates within the process,
+// concurrent processes are serialized with the registry file lock.
+var registryMutex sync.Mutex
+
+// Session is the single watch process attached to the broker.
+type Session struct {
+	ID        string    `json:"id"`
+	Trigger   string    `json:"trigger"`
+	Port      int       `json:"port"`
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+func newSessionID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Sessions returns the watch sessions registered for the broker.
+func Sessions(broker, configBase string) ([]Session, error) {
+	data, err := os.ReadFile(filepath.Join(configBase, broker, SessionsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sessions []Session
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		return nil, fmt.Errorf("sessions registry: %w", err)
+	}
+	return sessions, nil
+}
+
+// updateSessions applies the change to the sessions registry. Registry is locked
+// for the time of the update so that concurrent processes do not lose the changes,
+// the registry is not changed if the update returns an error.
+func updateSessions(broker, configBase string, update func([]Session) ([]Session, error)) error {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	path := filepath.Join(configBase, broker, SessionsFile)
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("registry lock: %w", err)
+	}
+	defer lock.Close()
+	if err := lockFile(lock); err != nil {
+		return fmt.Errorf("registry lock: %w", err)
+	}
+	defer unlockFile(lock)
+
+	sessions, err := Sessions(broker, configBase)
+	if err != nil {
+		return err
+	}
+	if sessions, err = update(sessions); err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := fmt.Sprintf("%s.%d", path, os.Getpid())
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func register(broker, configBase string, session Session) error {
+	return updateSessions(broker, configBase, func(sessions []Session) ([]Session, error) {
+		return append(sessions, session), nil
+	})
+}
+
+func unregister(broker, configBase, id string) error {
+	return updateSessions(broker, configBase, func(sessions []Session) ([]Session, error) {
+		result := make([]Session, 0, len(sessions))
+		for _, s := range sessions {
+			if s.ID != id {
+				result = append(result, s)
+			}
+		}
+		return result, nil
+	})
+}
+
+// alive returns true if the session process is running
+// and its receiver still accepts connections.
+func (s Session) alive() bool {
+	if s.PID != 0 && !processAlive(s.PID) {
+		return false
+	}
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("localhost:%d", s.Port), probeTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// RemoveStaleSessions unregisters the sessions which processes are gone
+// without cleanup and removes their triggers from the broker configuration,
+// along with the wiretap triggers that do not belong to any session.
+// Registry is locked until the triggers are removed, sessions register
+// before they create the triggers. Names of the removed triggers are returned.
+func RemoveStaleSessions(broker, configBase string) ([]string, error) {
+	var removed []string
+	err := updateSessions(broker, configBase, func(sessions []Session) ([]Session, error) {
+		live := make(map[string]bool, len(sessions))
+		result := make([]Session, 0, len(sessions))
+		for _, s := range sessions {
+			if s.alive() {
+				live[s.Trigger] = true
+				result = append(result, s)
+			}
+		}
+		triggers, err := tmbroker.LocalTriggers(broker, configBase)
+		if err != nil {
+			return nil, err
+		}
+		for name, trigger := range triggers {
+			// session triggers point to themselves
+			if trigger.Target.Component != name || !strings.HasPrefix(name, triggerPrefix) || live[name] {
+				continue
+			}
+			if err := sessionTrigger(name, broker, configBase).RemoveFromLocalConfig(); err != nil {
+				return nil, fmt.Errorf("removing trigger %q: %w", name, err)
+			}
+			removed = append(removed, name)
+		}
+		return result, nil
+	})
+	return removed, err
+}
+
+func sessionTrigger(name, broker, configBase string) *tmbroker.Trigger {
+	return &tmbroker.Trigger{
+		Name:       name,
+		ConfigBase: configBase,
+		TriggerSpec: v1alpha1.TriggerSpec{
+			Target: v1.Destination{
+				Ref: &v1.KReference{
+					Name: name,
+				},
+			},
+			Broker: v1.KReference{
+				Name: broker,
+			},
+		},
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wiretap
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"knative.dev/pkg/apis"
+
+	"github.com/triggermesh/tmctl/pkg/docker/fake"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+)
+
+func TestSessions(t *testing.T) {
+	_, c := fake.Setup(t)
+	configBase := c.ConfigHome
+	_, err := tmbroker.CreateBrokerConfig(configBase, "foo")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wiretaps []*Wiretap
+	for i := 0; i < 2; i++ {
+		w, err := New("foo", configBase)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Listen(ctx, func(cloudevents.Event) {}))
+		assert.NoError(t, w.CreateTrigger(nil))
+		wiretaps = append(wiretaps, w)
+	}
+	assert.NotEqual(t, wiretaps[0].Session.Trigger, wiretaps[1].Session.Trigger)
+
+	sessions, err := Sessions("foo", configBase)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	// session that was not cleaned up
+	stale := sessionTrigger("wiretap-stale", "foo", configBase)
+	stale.LocalURL, err = apis.ParseURL("http://host.docker.internal:1")
+	assert.NoError(t, err)
+	assert.NoError(t, stale.WriteLocalConfig())
+	assert.NoError(t, register("foo", configBase, Session{ID: "stale", Trigger: "wiretap-stale", Port: 1}))
+
+	removed, err := RemoveStaleSessions("foo", configBase)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"wiretap-stale"}, removed)
+
+	assert.NoError(t, wiretaps[0].Cleanup(ctx))
+	triggers, err := tmbroker.LocalTriggers("foo", configBase)
+	assert.NoError(t, err)
+	assert.Len(t, triggers, 1)
+	assert.Contains(t, triggers, wiretaps[1].Session.Trigger)
+
+	assert.NoError(t, wiretaps[1].Cleanup(ctx))
+	_, err = os.Stat(filepath.Join(configBase, "foo", SessionsFile))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestStaleSessionPID(t *testing.T) {
+	configBase := t.TempDir()
+	_, err := tmbroker.CreateBrokerConfig(configBase, "foo")
+	assert.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	// process is gone, its port is reused by another one
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	assert.NoError(t, cmd.Run())
+	assert.NoError(t, register("foo", configBase, Session{ID: "exited", Trigger: "wiretap-exited", Port: port, PID: cmd.Process.Pid}))
+	assert.NoError(t, register("foo", configBase, Session{ID: "running", Trigger: "wiretap-running", Port: port, PID: os.Getpid()}))
+
+	_, err = RemoveStaleSessions("foo", configBase)
+	assert.NoError(t, err)
+	sessions, err := Sessions("foo", configBase)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, "running", sessions[0].ID)
+}
+
+func TestConcurrentRegister(t *testing.T) {
+	configBase := t.TempDir()
+	_, err := tmbroker.CreateBrokerConfig(configBase, "foo")
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, register("foo", configBase, Session{ID: fmt.Sprint(i)}))
+		}(i)
+	}
+	wg.Wait()
+	sessions, err := Sessions("foo", configBase)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 10)
+}
@@ -20,96 +20,122 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
+	"os"
 	"time"
 
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
 	"knative.dev/pkg/apis"
-	v1 "knative.dev/pkg/apis/duck/v1"
 
-	"github.com/docker/docker/client"
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
 	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/log"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
-	"github.com/triggermesh/triggermesh-core/pkg/apis/eventing/v1alpha1"
 )
 
 type Wiretap struct {
 	Broker      string
 	ConfigBase  string
 	Destination string
+	Session     Session
 
-	client *client.Client
+	runtime docker.Runtime
 }
 
-const (
-	image = "gcr.io/knative-releases/knative.dev/eventing/cmd/event_display"
-	port  = "8080/tcp"
-)
-
+// New returns the wiretap with the unique session so that
+// multiple wiretaps can be attached to the same broker.
 func New(broker, configBase string) (*Wiretap, error) {
-	dockerClient, err := docker.NewClient()
+	runtime, err := docker.NewRuntime()
 	if err != nil {
 		return nil, err
 	}
+	id := newSessionID()
 	return &Wiretap{
 		Broker:     broker,
 		ConfigBase: configBase,
-		client:     dockerClient,
+		Session: Session{
+			ID:      id,
+			Trigger: fmt.Sprintf("%s-%s", triggerPrefix, id),
+			PID:     os.Getpid(),
+		},
+		runtime: runtime,
 	}, nil
 }
 
-func (w *Wiretap) CreateAdapter(ctx context.Context) (io.ReadCloser, error) {
-	co := []docker.ContainerOption{
-		docker.WithImage(image),
-		docker.WithPort(port),
-		docker.WithEnv([]string{"K_CONFIG_TRACING={}"}),
+// Listen starts the CloudEvents receiver on a free host port and calls
+// the handler for every event delivered by the broker. Receiver is stopped
+// when the context is cancelled.
+func (w *Wiretap) Listen(ctx context.Context, handler func(cloudevents.Event)) error {
+	listener, err := w.listen(ctx)
+	if err != nil {
+		return fmt.Errorf("listener: %w", err)
 	}
-	ho := []docker.HostOption{
-		docker.WithHostPortBinding(port),
-		docker.WithExtraHost(),
+	p, err := cloudevents.NewHTTP(cehttp.WithListener(listener))
+	if err != nil {
+		listener.Close()
+		return fmt.Errorf("cloudevents protocol: %w", err)
+	}
+	c, err := cloudevents.NewClient(p)
+	if err != nil {
+		listener.Close()
+		return fmt.Errorf("cloudevents client: %w", err)
 	}
-	container := &docker.Container{
-		Name:                   fmt.Sprintf("%s-wiretap", w.Broker),
-		Image:                  image,
-		CreateHostOptions:      ho,
-		CreateContainerOptions: co,
+	w.Session.Port = listener.Addr().(*net.TCPAddr).Port
+	w.Destination = fmt.Sprintf("http://host.docker.internal:%d", w.Session.Port)
+	go func() {
+		if err := c.StartReceiver(ctx, handler); err != nil {
+			log.Printf("Wiretap receiver: %v", err)
+		}
+	}()
+	return nil
+}
+
+// listen binds the receiver to the address that the containers reach
+// the host through, so that the receiver is not exposed to the network.
+// Loopback is used if the address is unknown or does not belong to the host,
+// e.g. when the runtime is remote.
+func (w *Wiretap) listen(ctx context.Context) (net.Listener, error) {
+	gateway, err := w.runtime.HostGateway(ctx)
+	if err != nil {
+		log.Printf("Host gateway address: %v", err)
+		return net.Listen("tcp", "127.0.0.1:0")
 	}
-	c, err := container.Start(ctx, w.client, true)
+	listener, err := net.Listen("tcp", net.JoinHostPort(gateway, "0"))
 	if err != nil {
-		return nil, fmt.Errorf("starting container: %w", err)
+		log.Printf("Listening on the host gateway: %v", err)
+		return net.Listen("tcp", "127.0.0.1:0")
 	}
-	w.Destination = fmt.Sprintf("http://host.docker.internal:%s", c.HostPort())
-	return c.Logs(ctx, w.client, time.Now().Add(2*time.Second), true)
+	return listener, nil
 }
 
-func (w *Wiretap) CreateTrigger(eventTypes []string) error {
+// CreateTrigger adds the session trigger with the filters to the broker
+// configuration to forward the matching events to the wiretap receiver.
+func (w *Wiretap) CreateTrigger(filters []eventingbroker.Filter) error {
 	url, err := apis.ParseURL(w.Destination)
 	if err != nil {
 		return fmt.Errorf("wiretap URL: %w", err)
 	}
-	trigger := &tmbroker.Trigger{
-		Name:       "wiretap",
-		ConfigBase: w.ConfigBase,
-		LocalURL:   url,
-		TriggerSpec: v1alpha1.TriggerSpec{
-			Target: v1.Destination{
-				Ref: &v1.KReference{
-					Name: "wiretap",
-				},
-			},
-			Broker: v1.KReference{
-				Name: w.Broker,
-			},
-		},
+	trigger := sessionTrigger(w.Session.Trigger, w.Broker, w.ConfigBase)
+	trigger.LocalURL = url
+	trigger.Filters = filters
+	// session is registered first so that the trigger
+	// is not removed as stale by the concurrent sessions
+	w.Session.StartedAt = time.Now()
+	if err := register(w.Broker, w.ConfigBase, w.Session); err != nil {
+		return fmt.Errorf("registering session: %w", err)
 	}
 	if err := trigger.WriteLocalConfig(); err != nil {
+		_ = unregister(w.Broker, w.ConfigBase, w.Session.ID)
 		return err
 	}
 	return nil
 }
 
-func (w *Wiretap) BrokerLogs(ctx context.Context, c config.BrokerConfig) (io.ReadCloser, error) {
-	bro, err := tmbroker.New(w.Broker, c)
+func (w *Wiretap) BrokerLogs(ctx context.Context, c config.BrokerConfig, images config.Images) (io.ReadCloser, error) {
+	bro, err := tmbroker.New(w.ConfigBase, w.Broker, c, images)
 	if err != nil {
 		return nil, err
 	}
@@ -117,21 +143,16 @@ func (w *Wiretap) BrokerLogs(ctx context.Context, c config.BrokerConfig) (io.Rea
 	if err != nil {
 		return nil, err
 	}
-	return broc.Logs(ctx, w.client, time.Now().Add(2*time.Second), true)
+	return broc.Logs(ctx, w.runtime, time.Now().Add(2*time.Second), true)
 }
 
+// Cleanup removes the session trigger and unregisters the session.
 func (w *Wiretap) Cleanup(ctx context.Context) error {
-	trigger := &tmbroker.Trigger{
-		Name:       "wiretap",
-		ConfigBase: w.ConfigBase,
-		TriggerSpec: v1alpha1.TriggerSpec{
-			Broker: v1.KReference{
-				Name: w.Broker,
-			},
-		},
-	}
-	if err := trigger.RemoveFromLocalConfig(); err != nil {
+	if err := sessionTrigger(w.Session.Trigger, w.Broker, w.ConfigBase).RemoveFromLocalConfig(); err != nil {
 		return fmt.Errorf("removing trigger: %v", err)
 	}
-	return docker.ForceStop(ctx, fmt.Sprintf("%s-wiretap", w.Broker), w.client)
+	if err := unregister(w.Broker, w.ConfigBase, w.Session.ID); err != nil {
+		return fmt.Errorf("unregistering session: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,178 @@
+/*
+Copyright 2023 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wiretap
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/docker/fake"
+)
+
+func newEvent(t *testing.T) cloudevents.Event {
+	event := cloudevents.NewEvent()
+	event.SetID("1")
+	event.SetType("foo.type")
+	event.SetSource("foo-source")
+	event.SetExtension("tenant", "bar")
+	assert.NoError(t, event.SetData(cloudevents.ApplicationJSON, map[string]string{"hello": "world"}))
+	return event
+}
+
+func TestListen(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, c := fake.Setup(t)
+	w, err := New("foo", c.ConfigHome)
+	assert.NoError(t, err)
+
+	received := make(chan cloudevents.Event, 1)
+	assert.NoError(t, w.Listen(ctx, func(event cloudevents.Event) {
+		received <- event
+	}))
+	assert.True(t, strings.HasPrefix(w.Destination, "http://host.docker.internal:"))
+
+	// receiver is bound to the host gateway only
+	listener, err := w.listen(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", listener.Addr().(*net.TCPAddr).IP.String())
+	listener.Close()
+
+	target := strings.Replace(w.Destination, "host.docker.internal", "127.0.0.1", 1)
+	client, err := cloudevents.NewClientHTTP()
+	assert.NoError(t, err)
+
+	event := newEvent(t)
+	var result error
+	// receiver is started asynchronously
+	for i := 0; i < 10; i++ {
+		if result = client.Send(cloudevents.ContextWithTarget(ctx, target), event); cloudevents.IsACK(result) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	assert.True(t, cloudevents.IsACK(result))
+
+	select {
+	case e := <-received:
+		assert.Equal(t, event.ID(), e.ID())
+		assert.Equal(t, event.Extensions(), e.Extensions())
+		assert.JSONEq(t, string(event.Data()), string(e.Data()))
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not received")
+	}
+}
+
+func TestBrokerLogs(t *testing.T) {
+	ctx := context.Background()
+	runtime, c := fake.Setup(t)
+	_, err := runtime.CreateContainer(ctx, "foo-broker", &container.Config{}, &container.HostConfig{})
+	assert.NoError(t, err)
+	runtime.Logs["foo-broker"] = []string{`{"level":"info","msg":"Event delivered"}`}
+
+	w, err := New("foo", c.ConfigHome)
+	assert.NoError(t, err)
+	logs, err := w.BrokerLogs(ctx, c.Triggermesh.Broker, c.Images)
+	assert.NoError(t, err)
+	defer logs.Close()
+
+	var stdout, stderr bytes.Buffer
+	_, err = stdcopy.StdCopy(&stdout, &stderr, logs)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"level":"info","msg":"Event delivered"}`+"\n", stdout.String())
+
+	w, err = New("bar", c.ConfigHome)
+	assert.NoError(t, err)
+	_, err = w.BrokerLogs(ctx, c.Triggermesh.Broker, c.Images)
+	assert.Error(t, err)
+}
+
+func TestPrinter(t *testing.T) {
+	event := newEvent(t)
+
+	cases := map[string]struct {
+		format   string
+		template string
+		expected string
+		err      bool
+	}{
+		"json": {
+			format:   OutputJSON,
+			expected: `{"specversion":"1.0","id":"1","source":"foo-source","type":"foo.type","datacontenttype":"application/json","data":{"hello":"world"},"tenant":"bar"}` + "\n",
+		},
+		"pretty": {
+			format:   OutputPretty,
+			expected: "☁️  cloudevents.Event\n" + event.String(),
+		},
+		"template": {
+			format:   OutputTemplate,
+			template: "{{.type}} from {{.source}} ({{.extensions.tenant}}): {{.data.hello}}",
+			expected: "foo.type from foo-source (bar): world\n",
+		},
+		"missing template": {
+			format: OutputTemplate,
+			err:    true,
+		},
+		"unknown format": {
+			format: "yaml",
+			err:    true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := NewPrinter(tc.format, tc.template)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			var out bytes.Buffer
+			assert.NoError(t, p.Print(&out, event))
+			assert.Equal(t, tc.expected, out.String())
+		})
+	}
+}
+
+func TestRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	recorder, err := NewRecorder(path)
+	assert.NoError(t, err)
+
+	event := newEvent(t)
+	receivedAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.NoError(t, recorder.Write(event, receivedAt))
+	assert.NoError(t, recorder.Write(event, receivedAt.Add(time.Second)))
+	assert.NoError(t, recorder.Close())
+
+	records, err := ReadRecords(path)
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, event.String(), records[0].Event.String())
+	assert.Equal(t, receivedAt, records[0].ReceivedAt)
+	assert.Equal(t, time.Second, records[1].ReceivedAt.Sub(records[0].ReceivedAt))
+}
@@ -42,6 +42,12 @@ func CRD() map[string]crd.CRD {
 	return crds
 }
 
+// CustomCRD returns the path to the CRD of the user-defined "InventorySource" kind.
+func CustomCRD() string {
+	_, filename, _, _ := runtime.Caller(0)
+	return path.Dir(filename) + "/fixtures/custom-crd.yaml"
+}
+
 func ConfigBase() string {
 	_, filename, _, _ := runtime.Caller(0)
 	return path.Dir(filename) + "/fixtures"